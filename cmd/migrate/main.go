@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	_ "github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/config"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/migrations"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  up        apply all pending migrations
+  down      revert the most recently applied migration
+  status    list migrations and whether they are applied
+  to N      migrate up or down to version N (0 reverts everything)
+`
+
+func main() {
+	config.LoadEnvs()
+	config.SetupLogger()
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	db, err := sql.Open("postgres", config.PostgresConnString())
+	if err != nil {
+		logger.LogFatal("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		logger.LogFatal("Failed to load migrations: %v", err)
+	}
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx)
+	case "status":
+		err = printStatus(ctx, migrator)
+	case "to":
+		if len(os.Args) < 3 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		version, convErr := strconv.Atoi(os.Args[2])
+		if convErr != nil || version < 0 {
+			fmt.Fprintf(os.Stderr, "Invalid version %q\n", os.Args[2])
+			os.Exit(2)
+		}
+		err = migrator.To(ctx, version)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		logger.LogFatal("Migration failed: %v", err)
+	}
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, status := range statuses {
+		state, appliedAt := "pending", "-"
+		if status.Applied {
+			state = "applied"
+			appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+// defaultAnalyticsRange is how far back analytics go when no start of
+// the range is given.
+const defaultAnalyticsRange = 3 * 30 * 24 * time.Hour
+
+// parseTimeParam parses the named query parameter as a date or an
+// RFC 3339 time. Dates are read in UTC; with end set, a date stands
+// for the end of that day.
+func parseTimeParam(c *gin.Context, param string, end bool) (time.Time, bool) {
+	value := c.Query(param)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + ", expected a date or an RFC 3339 time"})
+		return time.Time{}, false
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// analyticsRange holds the query shared by the analytics endpoints.
+type analyticsRange struct {
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Bucket training.Bucket `json:"bucket"`
+}
+
+// loadPerformedSets checks that the user may see the analytics of the
+// user in the path, parses the range and bucket of the query and
+// loads the sets performed over that range. If anything fails, it
+// writes the error response and returns false.
+func loadPerformedSets(c *gin.Context) ([]store.PerformedSet, analyticsRange, bool) {
+	var query analyticsRange
+
+	id, ok := parseIDParam(c, "id", "User")
+	if !ok {
+		return nil, query, false
+	}
+
+	// Analytics are only visible to the user they describe
+	if id != currentUser(c).ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, query, false
+	}
+
+	query.To = time.Now().UTC()
+	if c.Query("to") != "" {
+		if query.To, ok = parseTimeParam(c, "to", true); !ok {
+			return nil, query, false
+		}
+	}
+
+	query.From = query.To.Add(-defaultAnalyticsRange)
+	if c.Query("from") != "" {
+		if query.From, ok = parseTimeParam(c, "from", false); !ok {
+			return nil, query, false
+		}
+	}
+
+	if !query.From.Before(query.To) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return nil, query, false
+	}
+
+	query.Bucket = training.Bucket(c.DefaultQuery("bucket", string(training.Week)))
+	if !query.Bucket.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bucket must be one of day, week or month"})
+		return nil, query, false
+	}
+
+	sets, err := workoutStore.ListPerformedSets(c.Request.Context(), store.PerformedSetFilter{
+		UserID: id,
+		From:   query.From,
+		To:     query.To,
+	})
+	if err != nil {
+		logger.LogError("Failed to list performed sets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute analytics"})
+		return nil, query, false
+	}
+
+	// Bucket in the same location as the range
+	for i := range sets {
+		sets[i].PerformedAt = sets[i].PerformedAt.In(query.To.Location())
+	}
+	return sets, query, true
+}
+
+// -------------------- Analytics Handlers --------------------
+
+func getOneRepMaxAnalytics(c *gin.Context) {
+	formula := training.Formula(c.DefaultQuery("formula", string(training.Epley)))
+	if formula != training.Epley && formula != training.Brzycki {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Formula must be one of epley or brzycki"})
+		return
+	}
+
+	sets, query, ok := loadPerformedSets(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"range":   query,
+		"formula": formula,
+		"data":    training.OneRepMaxTrends(sets, query.Bucket, formula),
+	})
+}
+
+func getVolumeAnalytics(c *gin.Context) {
+	sets, query, ok := loadPerformedSets(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"range": query,
+		"data":  training.Volume(sets, query.Bucket),
+	})
+}
+
+func getMuscleAnalytics(c *gin.Context) {
+	sets, query, ok := loadPerformedSets(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"range": query,
+		"data":  training.Muscles(sets, query.Bucket),
+	})
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/auth"
+	"github.com/soa-rs/fit/internal/config"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// userContextKey is the gin context key under which requireAuth stores
+// the authenticated User.
+const userContextKey = "user"
+
+// Token signer
+var tokens *auth.Signer
+
+// Emails of the users who are admins regardless of the database
+var adminEmails = map[string]bool{}
+
+// Init token signer
+func initAuth() {
+	secret := []byte(config.GetEnvOrDefault(config.EnvBackendAuthSecret))
+	if len(secret) == 0 {
+		if config.GetEnvOrDefault(config.EnvBackendProfile) == config.DefaultProfile {
+			logger.LogFatal("%s must be set in production", config.EnvBackendAuthSecret)
+		}
+
+		var err error
+		secret, err = auth.RandomSecret()
+		if err != nil {
+			logger.LogFatal("Failed to generate auth secret: %v", err)
+		}
+		logger.LogWarn("%s not set, using an ephemeral secret; tokens will not survive a restart", config.EnvBackendAuthSecret)
+	}
+
+	accessTTL, err := time.ParseDuration(config.GetEnvOrDefault(config.EnvBackendAccessTokenTTL))
+	if err != nil {
+		logger.LogFatal("Failed to parse access token TTL: %v", err)
+	}
+
+	refreshTTL, err := time.ParseDuration(config.GetEnvOrDefault(config.EnvBackendRefreshTokenTTL))
+	if err != nil {
+		logger.LogFatal("Failed to parse refresh token TTL: %v", err)
+	}
+
+	tokens = auth.NewSigner(secret, accessTTL, refreshTTL)
+
+	for _, email := range strings.Split(config.GetEnvOrDefault(config.EnvBackendAdminEmails), ",") {
+		if email = normalizeEmail(email); email != "" {
+			adminEmails[email] = true
+		}
+	}
+}
+
+// requireAuth authenticates the request with the bearer access token
+// and stores the corresponding User on the gin context.
+func requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		scheme, token, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		claims, err := tokens.Verify(token, auth.AccessToken)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired access token"})
+			return
+		}
+
+		user, err := userStore.GetUser(c.Request.Context(), claims.Subject)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired access token"})
+				return
+			}
+
+			logger.LogError("Failed to get authenticated user: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if adminEmails[user.Email] {
+			user.IsAdmin = true
+		}
+
+		c.Set(userContextKey, user)
+		c.Next()
+	}
+}
+
+// currentUser returns the User stored by requireAuth.
+func currentUser(c *gin.Context) store.User {
+	return c.MustGet(userContextKey).(store.User)
+}
+
+// issueTokens issues a new access/refresh token pair for the user and
+// records the refresh token so that it can later be rotated or revoked.
+func issueTokens(ctx context.Context, user store.User) (gin.H, error) {
+	accessToken, accessClaims, err := tokens.Issue(user.ID, auth.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, refreshClaims, err := tokens.Issue(user.ID, auth.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = userStore.CreateRefreshToken(ctx, user.ID, refreshClaims.ID, refreshClaims.Expiry())
+	if err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    int(time.Until(accessClaims.Expiry()).Seconds()),
+		"user":          user,
+	}, nil
+}
+
+// -------------------- Auth Handlers --------------------
+
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func registerUser(c *gin.Context) {
+	var creds credentials
+	if err := c.ShouldBindJSON(&creds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validation
+	creds.Email = normalizeEmail(creds.Email)
+	if creds.Email == "" || !strings.Contains(creds.Email, "@") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email is required"})
+		return
+	}
+
+	if err := auth.ValidatePassword(creds.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hash, err := auth.HashPassword(creds.Password)
+	if err != nil {
+		logger.LogError("Failed to hash password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		return
+	}
+
+	// The unique email constraint is the only check, so that of two
+	// concurrent registrations the loser gets a conflict rather than an
+	// error
+	user := store.User{Email: creds.Email, PasswordHash: hash}
+	if err := userStore.CreateUser(c.Request.Context(), &user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+			return
+		}
+
+		logger.LogError("Failed to create user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		return
+	}
+
+	response, err := issueTokens(c.Request.Context(), user)
+	if err != nil {
+		logger.LogError("Failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+func loginUser(c *gin.Context) {
+	var creds credentials
+	if err := c.ShouldBindJSON(&creds); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := userStore.GetUserByEmail(c.Request.Context(), normalizeEmail(creds.Email))
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logger.LogError("Failed to get user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Unknown emails and wrong passwords get the same response so that
+	// the endpoint cannot be used to probe for registered accounts.
+	if err != nil || !auth.CheckPassword(user.PasswordHash, creds.Password) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	response, err := issueTokens(c.Request.Context(), user)
+	if err != nil {
+		logger.LogError("Failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func refreshSession(c *gin.Context) {
+	var request refreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	claims, err := tokens.Verify(request.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		return
+	}
+
+	// Refresh tokens are single use
+	err = userStore.ConsumeRefreshToken(c.Request.Context(), claims.Subject, claims.ID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+
+		logger.LogError("Failed to rotate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	user, err := userStore.GetUser(c.Request.Context(), claims.Subject)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+			return
+		}
+
+		logger.LogError("Failed to get user: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	response, err := issueTokens(c.Request.Context(), user)
+	if err != nil {
+		logger.LogError("Failed to issue tokens: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func logoutUser(c *gin.Context) {
+	var request refreshRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := currentUser(c)
+	claims, err := tokens.Verify(request.RefreshToken, auth.RefreshToken)
+	if err != nil || claims.Subject != user.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refresh token"})
+		return
+	}
+
+	// Access tokens are stateless and simply run out; revoking the
+	// refresh token is what ends the session.
+	if err := userStore.RevokeRefreshToken(c.Request.Context(), user.ID, claims.ID); err != nil {
+		logger.LogError("Failed to revoke refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+}
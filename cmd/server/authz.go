@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// access is what a handler intends to do with a resource.
+type access int
+
+const (
+	// readAccess is granted to the owner and, for public programs and
+	// their routines and for catalogue exercises, to everyone.
+	readAccess access = iota
+	// useAccess is granted like readAccess, but only while the resource
+	// has not been deleted, to build on it: forking or following a
+	// program, or logging a workout of a routine.
+	useAccess
+	// writeAccess is only ever granted to the owner, and only while the
+	// resource has not been deleted.
+	writeAccess
+	// ownerAccess is granted to the owner whether or not the resource
+	// has been deleted, to restore it or delete it for good.
+	ownerAccess
+)
+
+// authorizeProgram checks that the authenticated user may access the
+// program. If not, it writes the error response and returns false.
+func authorizeProgram(c *gin.Context, programID int, mode access) bool {
+	owner, err := programStore.ProgramOwner(c.Request.Context(), programID)
+	return authorize(c, "Program", owner, err, mode)
+}
+
+// authorizeRoutine checks that the authenticated user may access the
+// routine, which inherits its owner and visibility from its program.
+// If not, it writes the error response and returns false.
+func authorizeRoutine(c *gin.Context, routineID int, mode access) bool {
+	owner, err := routineStore.RoutineOwner(c.Request.Context(), routineID)
+	return authorize(c, "Routine", owner, err, mode)
+}
+
+// authorizeWorkout checks that the authenticated user may access the
+// workout. Workouts are always private. If the user may not access it,
+// it writes the error response and returns false.
+func authorizeWorkout(c *gin.Context, workoutID int, mode access) bool {
+	owner, err := workoutStore.WorkoutOwner(c.Request.Context(), workoutID)
+	return authorize(c, "Workout", owner, err, mode)
+}
+
+// authorizeExercise checks that the authenticated user may access the
+// exercise. Everyone may read catalogue exercises but only admins may
+// modify them, while custom exercises are private to their owner. If
+// the user may not access it, it writes the error response and returns
+// false.
+func authorizeExercise(c *gin.Context, exerciseID int, mode access) bool {
+	owner, err := exerciseStore.ExerciseOwner(c.Request.Context(), exerciseID)
+	if err == nil && owner.Public && currentUser(c).IsAdmin {
+		// Admins own the catalogue
+		owner.UserID = currentUser(c).ID
+	}
+	return authorize(c, "Exercise", owner, err, mode)
+}
+
+// authorize compares the owner of a resource with the authenticated
+// user. Resources the user cannot even read are reported as missing,
+// so that their existence is not leaked; resources the user can read
+// but not modify get a 403. Deleted resources can still be read, but
+// are reported as missing to anyone wanting to modify or use them.
+func authorize(c *gin.Context, resource string, owner store.Owner, err error, mode access) bool {
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+			return false
+		}
+
+		logger.LogError("Failed to resolve owner of %s: %v", strings.ToLower(resource), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return false
+	}
+
+	if owner.Deleted && (mode == writeAccess || mode == useAccess) {
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+		return false
+	}
+
+	if owner.UserID == currentUser(c).ID {
+		return true
+	}
+
+	if !owner.Public {
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+		return false
+	}
+
+	if mode == readAccess || mode == useAccess {
+		return true
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": fmt.Sprintf("You do not have permission to modify this %s", strings.ToLower(resource)),
+	})
+	return false
+}
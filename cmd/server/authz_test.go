@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestAuthorization(t *testing.T) {
+	router := newTestRouter()
+	owner := register(t, router, "owner@example.com")
+	other := register(t, router, "other@example.com")
+
+	private := createTestProgram(t, router, owner, store.Program{Name: "Private"})
+	public := createTestProgram(t, router, owner, store.Program{Name: "Public", IsPublic: true})
+	deleted := createTestProgram(t, router, owner, store.Program{Name: "Deleted", IsPublic: true})
+	if w := do(router, http.MethodDelete, fmt.Sprintf("/api/programs/%d", deleted), owner, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete program: %d %s", w.Code, w.Body.String())
+	}
+
+	update := store.Program{Name: "Renamed", CycleDays: 7}
+	tests := []struct {
+		name   string
+		token  string
+		method string
+		path   string
+		id     int
+		body   any
+		want   int
+	}{
+		{"anonymous", "", http.MethodGet, "/api/programs/%d", private, nil, http.StatusUnauthorized},
+		{"invalid token", "not a token", http.MethodGet, "/api/programs/%d", private, nil, http.StatusUnauthorized},
+
+		{"owner reads private", owner, http.MethodGet, "/api/programs/%d", private, nil, http.StatusOK},
+		{"owner updates private", owner, http.MethodPut, "/api/programs/%d", private, update, http.StatusOK},
+		{"other reads private", other, http.MethodGet, "/api/programs/%d", private, nil, http.StatusNotFound},
+		{"other updates private", other, http.MethodPut, "/api/programs/%d", private, update, http.StatusNotFound},
+		{"other forks private", other, http.MethodPost, "/api/programs/%d/fork", private, nil, http.StatusNotFound},
+
+		{"other reads public", other, http.MethodGet, "/api/programs/%d", public, nil, http.StatusOK},
+		{"other updates public", other, http.MethodPut, "/api/programs/%d", public, update, http.StatusForbidden},
+		{"other deletes public", other, http.MethodDelete, "/api/programs/%d", public, nil, http.StatusForbidden},
+		{"other forks public", other, http.MethodPost, "/api/programs/%d/fork", public, nil, http.StatusCreated},
+		{"other enrolls in public", other, http.MethodPost, "/api/programs/%d/enroll", public, nil, http.StatusCreated},
+
+		{"owner updates deleted", owner, http.MethodPut, "/api/programs/%d", deleted, update, http.StatusNotFound},
+		{"owner forks deleted", owner, http.MethodPost, "/api/programs/%d/fork", deleted, nil, http.StatusNotFound},
+		{"other forks deleted", other, http.MethodPost, "/api/programs/%d/fork", deleted, nil, http.StatusNotFound},
+		{"other enrolls in deleted", other, http.MethodPost, "/api/programs/%d/enroll", deleted, nil, http.StatusNotFound},
+		{"other restores deleted", other, http.MethodPost, "/api/programs/%d/restore", deleted, nil, http.StatusForbidden},
+
+		{"missing", owner, http.MethodGet, "/api/programs/%d", deleted + 100, nil, http.StatusNotFound},
+		{"invalid ID", owner, http.MethodGet, "/api/programs/%d", 0, nil, http.StatusNotFound},
+
+		// Last, as it brings the program back
+		{"owner restores deleted", owner, http.MethodPost, "/api/programs/%d/restore", deleted, nil, http.StatusOK},
+		{"other forks restored", other, http.MethodPost, "/api/programs/%d/fork", deleted, nil, http.StatusCreated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := fmt.Sprintf(tt.path, tt.id)
+			if w := do(router, tt.method, path, tt.token, tt.body); w.Code != tt.want {
+				t.Errorf("%s %s: %d %s, want %d", tt.method, path, w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Soft Deletion --------------------
+
+// hardDelete reports whether a delete request asks to delete the
+// resource for good. Resources are soft deleted by default, so that
+// the history referencing them stays intact and they can be restored.
+func hardDelete(c *gin.Context) bool {
+	return c.Query("hard") == "true"
+}
+
+// deleteAccess is the access a delete request needs: the owner may
+// delete a resource for good even after soft deleting it.
+func deleteAccess(c *gin.Context) access {
+	if hardDelete(c) {
+		return ownerAccess
+	}
+	return writeAccess
+}
+
+// setDeleted soft deletes or restores a resource with the store method
+// and writes the response.
+func setDeleted(c *gin.Context, resource string, id int, deleted bool, set func(context.Context, int, bool) error) {
+	verb := "delete"
+	if !deleted {
+		verb = "restore"
+	}
+
+	err := set(c.Request.Context(), id, deleted)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"message": resource + " " + verb + "d successfully"})
+	case errors.Is(err, store.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+	case errors.Is(err, store.ErrPrecondition) && deleted:
+		c.JSON(http.StatusNotFound, gin.H{"error": resource + " not found"})
+	case errors.Is(err, store.ErrPrecondition):
+		c.JSON(http.StatusConflict, gin.H{"error": resource + " is not deleted"})
+	case errors.Is(err, store.ErrConflict):
+		// Only workouts conflict, when restoring one would leave the user
+		// with two sessions in progress
+		c.JSON(http.StatusConflict, gin.H{"error": "Another workout is already in progress"})
+	default:
+		logger.LogError("Failed to %s %s: %v", verb, strings.ToLower(resource), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + verb + " " + strings.ToLower(resource)})
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Exercise Handlers (Milestone 2) --------------------
+
+// normalizeAliases trims the aliases of an exercise and drops empty ones
+// and those repeating its name or another alias, ignoring case.
+func normalizeAliases(exercise *store.Exercise) {
+	seen := map[string]bool{strings.ToLower(exercise.Name): true}
+	aliases := []string{}
+	for _, alias := range exercise.Aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" || seen[strings.ToLower(alias)] {
+			continue
+		}
+		seen[strings.ToLower(alias)] = true
+		aliases = append(aliases, alias)
+	}
+	exercise.Aliases = aliases
+}
+
+// getVisibleExercise gets an exercise the user may use: a catalogue
+// exercise or one of their custom exercises. Deleted exercises and the
+// custom exercises of other users are reported as missing.
+func getVisibleExercise(ctx context.Context, user store.User, id int) (store.Exercise, error) {
+	exercise, err := exerciseStore.GetExercise(ctx, id)
+	if err == nil && (exercise.DeletedAt != nil || exercise.OwnerID != nil && *exercise.OwnerID != user.ID) {
+		return store.Exercise{}, store.ErrNotFound
+	}
+	return exercise, err
+}
+
+func createExercise(c *gin.Context) {
+	var exercise store.Exercise
+	if err := c.ShouldBindJSON(&exercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Exercises are custom exercises of the authenticated user, unless
+	// an admin adds them to the catalogue
+	user := currentUser(c)
+	exercise.OwnerID = &user.ID
+	if c.Query("catalogue") == "true" {
+		if !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins may add exercises to the catalogue"})
+			return
+		}
+		exercise.OwnerID = nil
+	}
+
+	// Validation
+	if exercise.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if exercise.ExerciseType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise type is required"})
+		return
+	}
+	normalizeAliases(&exercise)
+
+	// Muscles, equipment and type must come from the taxonomy
+	if !checkTaxonomy(c, &exercise, "Failed to create exercise") {
+		return
+	}
+
+	if err := exerciseStore.CreateExercise(c.Request.Context(), &exercise); err != nil {
+		logger.LogError("Failed to create exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create exercise"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, exercise)
+}
+
+func getExerciseByID(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	// Custom exercises are only visible to their owner
+	if !authorizeExercise(c, id, readAccess) {
+		return
+	}
+
+	exercise, err := exerciseStore.GetExercise(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to get exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercise)
+}
+
+func listExercises(c *gin.Context) {
+	page, ok := getListPage(c, store.ExerciseSortFields, store.DefaultExerciseSort)
+	if !ok {
+		return
+	}
+
+	// Only the catalogue and the user's custom exercises are listed
+	filter := store.ExerciseFilter{
+		Page:      page,
+		VisibleTo: currentUser(c).ID,
+		// Optional filtering by type
+		Type: c.Query("type"),
+	}
+
+	exercises, info, err := exerciseStore.ListExercises(c.Request.Context(), filter)
+	if err != nil {
+		// Cursors are opaque, but may still be forged
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+
+		logger.LogError("Failed to list exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list exercises"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(exercises, info, filter.Page))
+}
+
+// queryList returns the values of a query parameter that may be
+// repeated or hold a comma-separated list, as taxonomy slugs.
+func queryList(c *gin.Context, param string) []string {
+	values := []string{}
+	for _, value := range c.QueryArray(param) {
+		for _, item := range strings.Split(value, ",") {
+			if item = slug(item); item != "" {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func searchExercises(c *gin.Context) {
+	search := store.ExerciseSearch{
+		Page:      getPaginationParams(c),
+		VisibleTo: currentUser(c).ID,
+		Query:     strings.TrimSpace(c.Query("q")),
+		Type:      slug(c.Query("type")),
+		Equipment: queryList(c, "equipment"),
+		Muscles:   queryList(c, "muscle"),
+	}
+
+	// Exercises match any of the equipment and muscles given by default
+	switch c.DefaultQuery("match", "any") {
+	case "any":
+	case "all":
+		search.MatchAll = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Match must be any or all"})
+		return
+	}
+
+	exercises, total, facets, err := exerciseStore.SearchExercises(c.Request.Context(), search)
+	if err != nil {
+		logger.LogError("Failed to search exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search exercises"})
+		return
+	}
+
+	response := paginatedResponse(exercises, total, search.Page)
+	response["facets"] = facets
+	c.JSON(http.StatusOK, response)
+}
+
+func updateExercise(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	// Only admins may edit the catalogue
+	if !authorizeExercise(c, id, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var exercise store.Exercise
+	if err := c.ShouldBindJSON(&exercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	exercise.ID = id
+
+	// Validation
+	if exercise.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if exercise.ExerciseType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise type is required"})
+		return
+	}
+	normalizeAliases(&exercise)
+
+	// Muscles, equipment and type must come from the taxonomy
+	if !checkTaxonomy(c, &exercise, "Failed to update exercise") {
+		return
+	}
+
+	if err := exerciseStore.UpdateExercise(c.Request.Context(), &exercise); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to update exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercise)
+}
+
+func deleteExercise(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	// Only admins may edit the catalogue
+	if !authorizeExercise(c, id, deleteAccess(c)) {
+		return
+	}
+
+	// Sets of a soft deleted exercise keep resolving it
+	if !hardDelete(c) {
+		setDeleted(c, "Exercise", id, true, exerciseStore.SetExerciseDeleted)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := exerciseStore.DeleteExercise(ctx, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		// Exercises still in routines or workouts can only be soft deleted
+		if errors.Is(err, store.ErrConflict) {
+			references, err := exerciseStore.ExerciseReferences(ctx, id)
+			if err != nil {
+				logger.LogError("Failed to count exercise references: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise"})
+				return
+			}
+
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "Exercise is still used by routines or workouts",
+				"references": references,
+			})
+			return
+		}
+
+		logger.LogError("Failed to delete exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
+}
+
+func restoreExercise(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	if !authorizeExercise(c, id, ownerAccess) {
+		return
+	}
+
+	setDeleted(c, "Exercise", id, false, exerciseStore.SetExerciseDeleted)
+}
+
+// mergeRequest names the catalogue exercise to merge a custom exercise
+// into.
+type mergeRequest struct {
+	IntoExerciseID int `json:"into_exercise_id"`
+}
+
+func mergeExercise(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	if !authorizeExercise(c, id, writeAccess) {
+		return
+	}
+
+	var request mergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	exercise, err := exerciseStore.GetExercise(ctx, id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to get exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge exercise"})
+		return
+	}
+
+	// Validation
+	if exercise.OwnerID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only custom exercises can be merged"})
+		return
+	}
+
+	if request.IntoExerciseID <= 0 || request.IntoExerciseID == id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise to merge into is required"})
+		return
+	}
+
+	into, err := exerciseStore.GetExercise(ctx, request.IntoExerciseID)
+	if err == nil && into.DeletedAt != nil {
+		err = store.ErrNotFound
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise to merge into not found"})
+			return
+		}
+
+		logger.LogError("Failed to get exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge exercise"})
+		return
+	}
+
+	if into.OwnerID != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercises can only be merged into catalogue exercises"})
+		return
+	}
+
+	// Sets are validated and earn records according to their type
+	if into.ExerciseType != exercise.ExerciseType {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercises of different types cannot be merged"})
+		return
+	}
+
+	// Re-points the routine exercises, sets and records of the exercise,
+	// then deletes it
+	if err := exerciseStore.MergeExercise(ctx, id, into.ID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to merge exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to merge exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, into)
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/history"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// maxHistoryImportSize bounds the size of imported history files.
+const maxHistoryImportSize = 10 << 20
+
+// historyFlushRows is how many rows of an export are written between
+// flushes.
+const historyFlushRows = 500
+
+// authorizeHistory checks that the user in the path is the current
+// user, whose history is the only one they may see.
+func authorizeHistory(c *gin.Context) bool {
+	id, ok := parseIDParam(c, "id", "User")
+	if !ok {
+		return false
+	}
+
+	if id != currentUser(c).ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return false
+	}
+	return true
+}
+
+// -------------------- Workout History Handlers --------------------
+
+func exportWorkoutHistory(c *gin.Context) {
+	if !authorizeHistory(c) {
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="workouts.csv"`)
+	c.Status(http.StatusOK)
+
+	// Rows are written as they are read, so errors past this point can
+	// only cut the file short
+	writer := csv.NewWriter(c.Writer)
+	written := 0
+	err := writer.Write(history.Columns)
+	if err == nil {
+		err = workoutStore.WalkWorkoutHistory(c.Request.Context(), currentUser(c).ID, func(row store.HistoryRow) error {
+			if err := writer.Write(history.Record(row)); err != nil {
+				return err
+			}
+
+			written++
+			if written%historyFlushRows == 0 {
+				writer.Flush()
+				c.Writer.Flush()
+			}
+			return writer.Error()
+		})
+	}
+	writer.Flush()
+
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		logger.LogError("Failed to export workout history: %v", err)
+		c.Abort()
+	}
+}
+
+// exerciseMatch reports what an exercise name of an imported file was
+// matched to.
+type exerciseMatch struct {
+	Name         string  `json:"name"`
+	Sets         int     `json:"sets"`
+	Status       string  `json:"status"`
+	ExerciseID   int     `json:"exercise_id,omitempty"`
+	ExerciseName string  `json:"exercise_name,omitempty"`
+	ExerciseType string  `json:"exercise_type,omitempty"`
+	Score        float64 `json:"score"`
+}
+
+// Exercise match statuses
+const (
+	matchMapped    = "mapped"
+	matchMatched   = "matched"
+	matchCreated   = "created"
+	matchUnmatched = "unmatched"
+)
+
+// importReport describes what an import did, or would do on a dry run.
+type importReport struct {
+	DryRun     bool              `json:"dry_run"`
+	Workouts   int               `json:"workouts"`
+	Sets       int               `json:"sets"`
+	Duplicates int               `json:"duplicates"`
+	Skipped    int               `json:"skipped_sets"`
+	Exercises  []*exerciseMatch  `json:"exercises"`
+	Problems   []history.Problem `json:"problems"`
+	WorkoutIDs []int             `json:"workout_ids,omitempty"`
+}
+
+func importWorkoutHistory(c *gin.Context) {
+	if !authorizeHistory(c) {
+		return
+	}
+	user := currentUser(c)
+	ctx := c.Request.Context()
+
+	// Imports are dry runs unless committed
+	report := importReport{
+		DryRun:    c.Query("commit") != "true",
+		Exercises: []*exerciseMatch{},
+		Problems:  []history.Problem{},
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxHistoryImportSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A CSV file is required in the file field"})
+		return
+	}
+
+	// The mapping defaults to the format of our own exports
+	mapping := history.Mapping{Preset: "fit"}
+	if spec := c.PostForm("mapping"); spec != "" {
+		mapping = history.Mapping{}
+		if err := json.Unmarshal([]byte(spec), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping: " + err.Error()})
+			return
+		}
+	}
+
+	mapping, err = mapping.Resolve()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		logger.LogError("Failed to open uploaded file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workouts"})
+		return
+	}
+	defer file.Close()
+
+	workouts, problems, err := history.Parse(file, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	report.Problems = append(report.Problems, problems...)
+
+	// Match the exercise names of the file to the catalogue and the
+	// user's custom exercises
+	exercises, err := listAll(func(page store.Page) ([]store.Exercise, store.PageInfo, error) {
+		return exerciseStore.ListExercises(ctx, store.ExerciseFilter{Page: page, VisibleTo: user.ID})
+	})
+	if err != nil {
+		logger.LogError("Failed to list exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workouts"})
+		return
+	}
+
+	byID := map[int]store.Exercise{}
+	for _, exercise := range exercises {
+		byID[exercise.ID] = exercise
+	}
+	matcher := history.NewMatcher(exercises)
+
+	matches := map[string]*exerciseMatch{}
+	setsOf := map[string][]history.Set{}
+	for _, workout := range workouts {
+		for _, set := range workout.Sets {
+			match, ok := matches[set.Exercise]
+			if !ok {
+				match = &exerciseMatch{Name: set.Exercise, Status: matchUnmatched}
+				if id, ok := mapping.Exercises[set.Exercise]; ok {
+					if exercise, ok := byID[id]; ok {
+						match.Status, match.ExerciseID, match.Score = matchMapped, id, 1
+						match.ExerciseName, match.ExerciseType = exercise.Name, exercise.ExerciseType
+					} else {
+						report.Problems = append(report.Problems, history.Problem{Row: set.Row, Error: fmt.Sprintf("Exercise %d mapped to %q not found", id, set.Exercise)})
+					}
+				} else if exercise, score, ok := matcher.Match(set.Exercise); ok {
+					match.Status, match.ExerciseID, match.Score = matchMatched, exercise.ID, score
+					match.ExerciseName, match.ExerciseType = exercise.Name, exercise.ExerciseType
+				} else {
+					match.Score = score
+				}
+				matches[set.Exercise] = match
+				report.Exercises = append(report.Exercises, match)
+			}
+			match.Sets++
+			setsOf[set.Exercise] = append(setsOf[set.Exercise], set)
+		}
+	}
+
+	// Unmatched exercises are created or their sets left out
+	for _, match := range report.Exercises {
+		if match.Status == matchUnmatched && mapping.CreateMissing {
+			match.Status = matchCreated
+			match.ExerciseName = match.Name
+			match.ExerciseType = history.InferType(setsOf[match.Name])
+		}
+	}
+
+	// Workouts that were already imported are left out
+	existing := map[time.Time]bool{}
+	if len(workouts) > 0 {
+		from, to := workouts[0].PerformedAt, workouts[len(workouts)-1].PerformedAt.Add(time.Second)
+		logged, err := listAll(func(page store.Page) ([]store.WorkoutWithRoutineName, store.PageInfo, error) {
+			return workoutStore.ListWorkouts(ctx, store.WorkoutFilter{Page: page, UserID: user.ID, From: from, To: to})
+		})
+		if err != nil {
+			logger.LogError("Failed to list workouts: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workouts"})
+			return
+		}
+		for _, workout := range logged {
+			existing[workout.PerformedAt.UTC()] = true
+		}
+	}
+
+	// Validate every set against its exercise before writing anything
+	type plannedWorkout struct {
+		history.Workout
+		sets []*history.Set
+	}
+	var planned []plannedWorkout
+	for _, workout := range workouts {
+		if existing[workout.PerformedAt] {
+			report.Duplicates++
+			continue
+		}
+
+		next := plannedWorkout{Workout: workout}
+		for i := range workout.Sets {
+			set := &workout.Sets[i]
+			match := matches[set.Exercise]
+			if match.Status == matchUnmatched {
+				report.Skipped++
+				continue
+			}
+
+			if err := validateWorkoutSet(&set.WorkoutSet, match.ExerciseType); err != nil {
+				report.Problems = append(report.Problems, history.Problem{Row: set.Row, Error: err.Error()})
+				report.Skipped++
+				continue
+			}
+			next.sets = append(next.sets, set)
+		}
+
+		if len(next.sets) > 0 {
+			planned = append(planned, next)
+			report.Workouts++
+			report.Sets += len(next.sets)
+		}
+	}
+
+	sort.SliceStable(report.Problems, func(i, j int) bool {
+		return report.Problems[i].Row < report.Problems[j].Row
+	})
+
+	if report.DryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	// Exercises are created along with the workouts logging them
+	created := map[string]*store.Exercise{}
+	newExercises := []*store.Exercise{}
+	for _, match := range report.Exercises {
+		if match.Status != matchCreated {
+			continue
+		}
+
+		exercise := &store.Exercise{
+			OwnerID:          &user.ID,
+			Name:             match.Name,
+			ExerciseType:     match.ExerciseType,
+			Equipment:        []string{},
+			PrimaryMuscles:   []string{},
+			SecondaryMuscles: []string{},
+		}
+		created[match.Name] = exercise
+		newExercises = append(newExercises, exercise)
+	}
+
+	imported := make([]store.ImportedWorkout, len(planned))
+	for i, workout := range planned {
+		imported[i].Workout = store.Workout{
+			UserID:      user.ID,
+			PerformedAt: workout.PerformedAt,
+			Status:      store.WorkoutCompleted,
+		}
+		imported[i].Sets = make([]store.ImportedSet, len(workout.sets))
+		for j, set := range workout.sets {
+			imported[i].Sets[j] = store.ImportedSet{WorkoutSet: set.WorkoutSet, NewExercise: created[set.Exercise]}
+			imported[i].Sets[j].ExerciseID = matches[set.Exercise].ExerciseID
+		}
+	}
+
+	// The exercises and workouts are imported all or nothing, so that
+	// a failed import can simply be retried
+	if err := workoutStore.ImportWorkouts(ctx, newExercises, imported); err != nil {
+		logger.LogError("Failed to import workouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workouts"})
+		return
+	}
+
+	for _, match := range report.Exercises {
+		if exercise, ok := created[match.Name]; ok {
+			match.ExerciseID = exercise.ID
+		}
+	}
+
+	// Workouts are imported oldest first so that personal records are
+	// detected in the order they were earned
+	for i := range imported {
+		report.WorkoutIDs = append(report.WorkoutIDs, imported[i].ID)
+		for j := range imported[i].Sets {
+			detectRecords(c, &imported[i].Sets[j].WorkoutSet)
+		}
+	}
+
+	c.JSON(http.StatusCreated, report)
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// idempotencyKeyHeader is the header clients send a key with to make
+// retrying a request safe.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the length of idempotency keys.
+const maxIdempotencyKeyLength = 255
+
+// idempotencyWriteTimeout bounds how long storing or releasing a key
+// may take once the request is handled.
+const idempotencyWriteTimeout = 5 * time.Second
+
+// How long the responses to requests made with a key are replayed for
+var idempotencyTTL time.Duration
+
+// Init idempotency keys
+func initIdempotency() {
+	var err error
+	idempotencyTTL, err = time.ParseDuration(config.GetEnvOrDefault(config.EnvBackendIdempotencyTTL))
+	if err != nil {
+		logger.LogFatal("Failed to parse idempotency TTL: %v", err)
+	}
+}
+
+// recordingWriter keeps a copy of the body written to the response.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// detach returns a context for the writes that follow a request, which
+// must go through even if the client has gone away in the meantime.
+func detach(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), idempotencyWriteTimeout)
+}
+
+// fingerprint identifies a request by its method, URL and body.
+func fingerprint(r *http.Request, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, r.Method+" "+r.URL.RequestURI()+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// idempotent makes POST, PUT and DELETE requests that come with an
+// Idempotency-Key safe to retry. The first request made with a key of
+// the user is handled and its response stored; later requests with the
+// same key replay that response instead of being handled again, as long
+// as they are the same request. Keys are released when the request
+// fails on the server or its response cannot be stored, so that it can
+// be retried. It must run after requireAuth.
+func idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodPost, http.MethodPut, http.MethodDelete:
+		default:
+			c.Next()
+			return
+		}
+
+		value := c.GetHeader(idempotencyKeyHeader)
+		if value == "" {
+			c.Next()
+			return
+		}
+		if len(value) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key must not be longer than 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key := store.IdempotencyKey{
+			UserID:      currentUser(c).ID,
+			Key:         value,
+			Fingerprint: fingerprint(c.Request, body),
+			ExpiresAt:   time.Now().Add(idempotencyTTL),
+		}
+		requested := key.Fingerprint
+
+		err = idempotencyStore.ReserveIdempotencyKey(ctx, &key)
+		switch {
+		case errors.Is(err, store.ErrConflict) && key.Fingerprint != requested:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "Idempotency key was already used for another request"})
+			return
+		case errors.Is(err, store.ErrConflict) && key.StatusCode == 0:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this idempotency key is still in progress"})
+			return
+		case errors.Is(err, store.ErrConflict):
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(key.StatusCode, key.ContentType, key.Body)
+			c.Abort()
+			return
+		case err != nil:
+			logger.LogError("Failed to reserve idempotency key: %v", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		// Requests that failed on the server may succeed when retried, so
+		// the key is released unless the response is stored, even if the
+		// handler panics or the response cannot be stored
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			ctx, cancel := detach(ctx)
+			defer cancel()
+			if err := idempotencyStore.ReleaseIdempotencyKey(ctx, key.UserID, key.Key); err != nil {
+				logger.LogError("Failed to release idempotency key: %v", err)
+			}
+		}()
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		if writer.Status() >= http.StatusInternalServerError {
+			return
+		}
+
+		key.StatusCode = writer.Status()
+		key.ContentType = writer.Header().Get("Content-Type")
+		key.Body = writer.body.Bytes()
+		saveCtx, cancel := detach(ctx)
+		defer cancel()
+		if err := idempotencyStore.SaveIdempotentResponse(saveCtx, &key); err != nil {
+			logger.LogError("Failed to save idempotent response: %v", err)
+			return
+		}
+		stored = true
+	}
+}
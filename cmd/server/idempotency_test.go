@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// countPrograms returns how many programs the user owns.
+func countPrograms(t *testing.T, router http.Handler, token string) int {
+	t.Helper()
+	w := do(router, http.MethodGet, "/api/programs?limit=100", token, nil)
+	return len(decode[struct {
+		Data []store.Program `json:"data"`
+	}](t, w).Data)
+}
+
+func TestIdempotentReplays(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	program := store.Program{Name: "Strength"}
+
+	first := do(router, http.MethodPost, "/api/programs", token, program, idempotencyKeyHeader, "key")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: %d %s", first.Code, first.Body.String())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("first request was replayed")
+	}
+
+	retry := do(router, http.MethodPost, "/api/programs", token, program, idempotencyKeyHeader, "key")
+	if retry.Code != first.Code || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want %d %s", retry.Code, retry.Body.String(), first.Code, first.Body.String())
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry was not replayed")
+	}
+	if got := countPrograms(t, router, token); got != 1 {
+		t.Errorf("created %d programs, want 1", got)
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	other := register(t, router, "other@example.com")
+
+	if w := do(router, http.MethodPost, "/api/programs", token, store.Program{Name: "Strength"}, idempotencyKeyHeader, "key"); w.Code != http.StatusCreated {
+		t.Fatalf("first request: %d %s", w.Code, w.Body.String())
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		program store.Program
+		key     string
+		want    int
+	}{
+		{"same key for another request", token, store.Program{Name: "Hypertrophy"}, "key", http.StatusConflict},
+		{"same key of another user", other, store.Program{Name: "Strength"}, "key", http.StatusCreated},
+		{"other key", token, store.Program{Name: "Strength"}, "other key", http.StatusCreated},
+		{"no key", token, store.Program{Name: "Strength"}, "", http.StatusCreated},
+		{"key too long", token, store.Program{Name: "Strength"}, strings.Repeat("k", maxIdempotencyKeyLength+1), http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(router, http.MethodPost, "/api/programs", tt.token, tt.program, idempotencyKeyHeader, tt.key)
+			if w.Code != tt.want {
+				t.Errorf("request: %d %s, want %d", w.Code, w.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeysAreReleasedOnFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		fail gin.HandlerFunc
+	}{
+		{"server error", func(c *gin.Context) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		}},
+		{"panic", func(c *gin.Context) {
+			panic("handler failed")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := newTestRouter()
+			token := register(t, router, "user@example.com")
+
+			calls := 0
+			router.POST("/test", requireAuth(), idempotent(), func(c *gin.Context) {
+				calls++
+				if calls == 1 {
+					tt.fail(c)
+					return
+				}
+				c.JSON(http.StatusCreated, gin.H{"calls": calls})
+			})
+
+			if w := do(router, http.MethodPost, "/test", token, nil, idempotencyKeyHeader, "key"); w.Code != http.StatusInternalServerError {
+				t.Fatalf("first request: %d %s, want 500", w.Code, w.Body.String())
+			}
+
+			w := do(router, http.MethodPost, "/test", token, nil, idempotencyKeyHeader, "key")
+			if w.Code != http.StatusCreated || calls != 2 {
+				t.Errorf("retry: %d %s after %d calls, want 201 after 2", w.Code, w.Body.String(), calls)
+			}
+		})
+	}
+}
+
+// contextStore fails the writes made with a context that is done, like
+// a database would.
+type contextStore struct {
+	store.IdempotencyStore
+}
+
+func (s contextStore) SaveIdempotentResponse(ctx context.Context, key *store.IdempotencyKey) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.IdempotencyStore.SaveIdempotentResponse(ctx, key)
+}
+
+func (s contextStore) ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.IdempotencyStore.ReleaseIdempotencyKey(ctx, userID, key)
+}
+
+func TestIdempotentResponsesOutliveTheClient(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	idempotencyStore = contextStore{idempotencyStore}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	router.POST("/test", requireAuth(), idempotent(), func(c *gin.Context) {
+		calls++
+		// The client goes away while the request is handled
+		cancel()
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/test", nil).WithContext(ctx)
+	r.Header.Set("Authorization", "Bearer "+token)
+	r.Header.Set(idempotencyKeyHeader, "key")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	w := do(router, http.MethodPost, "/test", token, nil, idempotencyKeyHeader, "key")
+	if w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "true" || calls != 1 {
+		t.Errorf("retry: %d %s after %d calls, want the first response replayed", w.Code, w.Body.String(), calls)
+	}
+}
+
+// failingSaves fails to store any response.
+type failingSaves struct {
+	store.IdempotencyStore
+}
+
+func (failingSaves) SaveIdempotentResponse(ctx context.Context, key *store.IdempotencyKey) error {
+	return errors.New("connection reset")
+}
+
+func TestIdempotencyKeysAreReleasedWhenResponsesAreNotStored(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	idempotencyStore = failingSaves{idempotencyStore}
+
+	calls := 0
+	router.POST("/test", requireAuth(), idempotent(), func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"calls": calls})
+	})
+
+	for i := 1; i <= 2; i++ {
+		w := do(router, http.MethodPost, "/test", token, nil, idempotencyKeyHeader, "key")
+		if w.Code != http.StatusCreated || calls != i {
+			t.Errorf("request %d: %d %s after %d calls, want 201 after %d", i, w.Code, w.Body.String(), calls, i)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/auth"
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/store/memory"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	tokens = auth.NewSigner([]byte("test secret"), 15*time.Minute, 24*time.Hour)
+	idempotencyTTL = time.Hour
+	os.Exit(m.Run())
+}
+
+// newTestRouter makes the handlers use an empty in-memory store and
+// returns the router of the API.
+func newTestRouter() *gin.Engine {
+	useStore(memory.New())
+	return newRouter()
+}
+
+// do sends a request with a JSON body, unless body is nil, as the user
+// of the access token, along with headers given as name-value pairs.
+func do(router http.Handler, method, path, token string, body any, headers ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != nil {
+		encoded, _ := json.Marshal(body)
+		reader = bytes.NewReader(encoded)
+	}
+
+	r := httptest.NewRequest(method, path, reader)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		r.Header.Set(headers[i], headers[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// decode decodes the JSON body of a response.
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var value T
+	if err := json.Unmarshal(w.Body.Bytes(), &value); err != nil {
+		t.Fatalf("Failed to decode %q: %v", w.Body.String(), err)
+	}
+	return value
+}
+
+// register registers a user and returns their access token.
+func register(t *testing.T, router http.Handler, email string) string {
+	t.Helper()
+	w := do(router, http.MethodPost, "/api/auth/register", "", credentials{Email: email, Password: "password1"})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register %s: %d %s", email, w.Code, w.Body.String())
+	}
+	return decode[struct {
+		AccessToken string `json:"access_token"`
+	}](t, w).AccessToken
+}
+
+// createTestProgram creates a program as the user and returns its ID.
+func createTestProgram(t *testing.T, router http.Handler, token string, program store.Program) int {
+	t.Helper()
+	w := do(router, http.MethodPost, "/api/programs", token, program)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create program %q: %d %s", program.Name, w.Code, w.Body.String())
+	}
+	return decode[store.Program](t, w).ID
+}
+
+func TestHealth(t *testing.T) {
+	router := newTestRouter()
+	if w := do(router, http.MethodGet, "/health", "", nil); w.Code != http.StatusOK {
+		t.Errorf("health: %d %s, want 200", w.Code, w.Body.String())
+	}
+}
+
+// listPage is the response of a list paged by getListPage.
+type listPage[T any] struct {
+	Data       []T `json:"data"`
+	Pagination struct {
+		NextCursor *string `json:"next_cursor"`
+		PrevCursor *string `json:"prev_cursor"`
+	} `json:"pagination"`
+}
+
+func TestListCursors(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	for _, name := range []string{"E", "B", "D", "A", "C"} {
+		createTestProgram(t, router, token, store.Program{Name: name})
+	}
+
+	names := func(page listPage[store.Program]) string {
+		var names string
+		for _, program := range page.Data {
+			names += program.Name
+		}
+		return names
+	}
+	list := func(query url.Values) listPage[store.Program] {
+		t.Helper()
+		w := do(router, http.MethodGet, "/api/programs?"+query.Encode(), token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list programs: %d %s", w.Code, w.Body.String())
+		}
+		return decode[listPage[store.Program]](t, w)
+	}
+
+	// Forward through the list, with cursors keeping the sort
+	var forward []string
+	page := list(url.Values{"limit": {"2"}, "sort": {"name"}})
+	forward = append(forward, names(page))
+	for page.Pagination.NextCursor != nil {
+		page = list(url.Values{"limit": {"2"}, "cursor": {*page.Pagination.NextCursor}})
+		forward = append(forward, names(page))
+	}
+	if got, want := forward, []string{"AB", "CD", "E"}; !slices.Equal(got, want) {
+		t.Errorf("pages forward = %v, want %v", got, want)
+	}
+
+	// And back from the last page
+	var backward []string
+	for page.Pagination.PrevCursor != nil {
+		page = list(url.Values{"limit": {"2"}, "cursor": {*page.Pagination.PrevCursor}})
+		backward = append(backward, names(page))
+	}
+	if got, want := backward, []string{"CD", "AB"}; !slices.Equal(got, want) {
+		t.Errorf("pages backward = %v, want %v", got, want)
+	}
+}
+
+func TestListRejectsInvalidCursors(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	for _, name := range []string{"A", "B", "C"} {
+		createTestProgram(t, router, token, store.Program{Name: name})
+	}
+
+	w := do(router, http.MethodGet, "/api/programs?limit=1&sort=name", token, nil)
+	next := *decode[listPage[store.Program]](t, w).Pagination.NextCursor
+
+	tests := []struct {
+		name  string
+		query url.Values
+	}{
+		{"not base64", url.Values{"cursor": {"!"}}},
+		{"not JSON", url.Values{"cursor": {"bm90IGpzb24"}}},
+		{"other sort", url.Values{"cursor": {next}, "sort": {"-name"}}},
+		{"values of another sort", url.Values{"cursor": {*encodeCursor(&store.Cursor{Values: []string{"A", "B"}, ID: 1}, []store.SortKey{{Field: "name"}})}}},
+		{"unknown sort", url.Values{"sort": {"owner"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := do(router, http.MethodGet, "/api/programs?"+tt.query.Encode(), token, nil); w.Code != http.StatusBadRequest {
+				t.Errorf("list programs: %d %s, want 400", w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestEncodeCursor(t *testing.T) {
+	if got := encodeCursor(nil, store.DefaultProgramSort); got != nil {
+		t.Errorf("encodeCursor(nil) = %q, want nil", *got)
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/portable"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// maxImportSize bounds the size of imported documents.
+const maxImportSize = 1 << 20
+
+// loadProgramTree loads a program along with its blocks, routines,
+// their groups and exercises, and the exercises they refer to. Routine
+// exercises refer to their group by its index, as in CreateProgramTree.
+func loadProgramTree(ctx context.Context, programID int) (store.ProgramTree, map[int]store.Exercise, error) {
+	var tree store.ProgramTree
+	exercises := map[int]store.Exercise{}
+
+	program, err := programStore.GetProgram(ctx, programID)
+	if err != nil {
+		return tree, nil, err
+	}
+	tree.Program = program
+
+	tree.Blocks, err = programStore.ListProgramBlocks(ctx, programID)
+	if err != nil {
+		return tree, nil, err
+	}
+
+	routines, err := listAll(func(page store.Page) ([]store.Routine, store.PageInfo, error) {
+		return routineStore.ListRoutines(ctx, store.RoutineFilter{Page: page, VisibleTo: program.UserID, ProgramID: programID})
+	})
+	if err != nil {
+		return tree, nil, err
+	}
+
+	for _, routine := range routines {
+		routineExercises, err := routineStore.ListRoutineExercises(ctx, routine.ID)
+		if err != nil {
+			return tree, nil, err
+		}
+
+		groups, err := routineStore.ListRoutineGroups(ctx, routine.ID)
+		if err != nil {
+			return tree, nil, err
+		}
+		indexes := map[int]int{}
+		for i, group := range groups {
+			indexes[group.ID] = i + 1
+		}
+
+		node := store.RoutineTree{Routine: routine, Groups: groups}
+		for _, routineExercise := range routineExercises {
+			if routineExercise.GroupID != nil {
+				index := indexes[*routineExercise.GroupID]
+				routineExercise.GroupID = &index
+			}
+			node.Exercises = append(node.Exercises, routineExercise.RoutineExercise)
+			if _, ok := exercises[routineExercise.ExerciseID]; ok {
+				continue
+			}
+
+			exercise, err := exerciseStore.GetExercise(ctx, routineExercise.ExerciseID)
+			if err != nil {
+				return tree, nil, err
+			}
+			exercises[exercise.ID] = exercise
+		}
+		tree.Routines = append(tree.Routines, node)
+	}
+	return tree, exercises, nil
+}
+
+// -------------------- Import/Export Handlers --------------------
+
+func exportProgram(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Private programs are only visible to their owner
+	if !authorizeProgram(c, id, readAccess) {
+		return
+	}
+
+	format, err := portable.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be json or yaml"})
+		return
+	}
+
+	tree, exercises, err := loadProgramTree(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+
+		logger.LogError("Failed to load program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export program"})
+		return
+	}
+
+	data, err := portable.Encode(portable.Export(tree, exercises), format)
+	if err != nil {
+		logger.LogError("Failed to encode program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export program"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="program-%d.%s"`, id, format))
+	c.Data(http.StatusOK, format.ContentType(), data)
+}
+
+func importProgram(c *gin.Context) {
+	// The format is given by the query, or else by the content type
+	name := c.Query("format")
+	if name == "" && strings.Contains(c.ContentType(), "yaml") {
+		name = string(portable.YAML)
+	}
+
+	format, err := portable.ParseFormat(name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be json or yaml"})
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Document is too large"})
+		return
+	}
+
+	doc, err := portable.Decode(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document: " + err.Error()})
+		return
+	}
+
+	// Validate the whole document, including that every exercise exists
+	// or is described, before writing anything
+	problems := doc.Validate()
+
+	user := currentUser(c)
+	ctx := c.Request.Context()
+	t, err := loadTaxonomy(ctx)
+	if err != nil {
+		logger.LogError("Failed to load taxonomy: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import program"})
+		return
+	}
+
+	exerciseIDs := map[string]int{}
+	var missing []store.Exercise
+	for _, key := range doc.References() {
+		existing, _, err := exerciseStore.ListExercises(ctx, store.ExerciseFilter{
+			Page:      store.Page{Limit: 1},
+			VisibleTo: user.ID,
+			Name:      key,
+		})
+		if err != nil {
+			logger.LogError("Failed to resolve exercise: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import program"})
+			return
+		}
+
+		if len(existing) > 0 {
+			exerciseIDs[key] = existing[0].ID
+			continue
+		}
+
+		exercise, ok := doc.Describe(key)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("exercises: %q does not exist and is not described", key))
+			continue
+		}
+		if exercise.ExerciseType != "" {
+			if err := t.validate(&exercise); err != nil {
+				problems = append(problems, fmt.Sprintf("exercises: %q: %s", key, err))
+				continue
+			}
+		}
+		missing = append(missing, exercise)
+	}
+
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document", "problems": problems})
+		return
+	}
+
+	// Exercises the user does not have yet become custom exercises
+	created := []store.Exercise{}
+	for _, exercise := range missing {
+		exercise.OwnerID = &user.ID
+		if err := exerciseStore.CreateExercise(ctx, &exercise); err != nil {
+			logger.LogError("Failed to create exercise: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import program"})
+			return
+		}
+		exerciseIDs[portable.Key(exercise.Name)] = exercise.ID
+		created = append(created, exercise)
+	}
+
+	// Imported programs always belong to the authenticated user
+	tree := doc.Tree(user.ID, exerciseIDs)
+	if err := programStore.CreateProgramTree(ctx, &tree); err != nil {
+		logger.LogError("Failed to create program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import program"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"program":           tree.Program,
+		"created_exercises": created,
+	})
+}
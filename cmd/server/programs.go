@@ -0,0 +1,266 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Program Handlers (Milestone 3) --------------------
+
+// defaultCycleDays is the cycle length of programs that do not give
+// one: a week.
+const defaultCycleDays = 7
+
+// validateCycleDays defaults the cycle length of a program and checks
+// that it is positive. Routines whose day number is past the end of the
+// cycle are never scheduled.
+func validateCycleDays(program *store.Program) error {
+	if program.CycleDays == 0 {
+		program.CycleDays = defaultCycleDays
+	}
+
+	if program.CycleDays < 0 {
+		return errors.New("Cycle days must be positive")
+	}
+	return nil
+}
+
+func createProgram(c *gin.Context) {
+	var program store.Program
+	if err := c.ShouldBindJSON(&program); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Programs always belong to the authenticated user
+	program.UserID = currentUser(c).ID
+
+	// Validation
+	if program.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if err := validateCycleDays(&program); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := programStore.CreateProgram(c.Request.Context(), &program); err != nil {
+		logger.LogError("Failed to create program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create program"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, program)
+}
+
+func getProgramByID(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Private programs are only visible to their owner
+	if !authorizeProgram(c, id, readAccess) {
+		return
+	}
+
+	program, err := programStore.GetProgram(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+
+		logger.LogError("Failed to get program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get program"})
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+func listPrograms(c *gin.Context) {
+	page, ok := getListPage(c, store.ProgramSortFields, store.DefaultProgramSort)
+	if !ok {
+		return
+	}
+
+	filter := store.ProgramFilter{
+		Page:      page,
+		VisibleTo: currentUser(c).ID,
+	}
+
+	programs, info, err := programStore.ListPrograms(c.Request.Context(), filter)
+	if err != nil {
+		// Cursors are opaque, but may still be forged
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+
+		logger.LogError("Failed to list programs: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list programs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(programs, info, filter.Page))
+}
+
+func updateProgram(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Check if the program exists and the user may access it
+	if !authorizeProgram(c, id, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var program store.Program
+	if err := c.ShouldBindJSON(&program); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	program.ID = id
+
+	// Validation
+	if program.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if err := validateCycleDays(&program); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := programStore.UpdateProgram(c.Request.Context(), &program); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+
+		logger.LogError("Failed to update program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update program"})
+		return
+	}
+
+	c.JSON(http.StatusOK, program)
+}
+
+// forkRequest optionally renames and publishes a fork; forks keep the
+// name of the original and are private by default.
+type forkRequest struct {
+	Name     string `json:"name"`
+	IsPublic bool   `json:"is_public"`
+}
+
+func forkProgram(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Users may fork their own programs and public ones
+	if !authorizeProgram(c, id, useAccess) {
+		return
+	}
+
+	// The body is optional
+	var request forkRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	fork := store.Program{
+		UserID:              currentUser(c).ID,
+		Name:                request.Name,
+		IsPublic:            request.IsPublic,
+		ForkedFromProgramID: &id,
+	}
+
+	if fork.Name == "" {
+		original, err := programStore.GetProgram(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+				return
+			}
+
+			logger.LogError("Failed to get program: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fork program"})
+			return
+		}
+		fork.Name = original.Name
+	}
+
+	// Copies the program along with its blocks, routines and their
+	// exercises
+	if err := programStore.ForkProgram(c.Request.Context(), &fork); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+
+		logger.LogError("Failed to fork program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fork program"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, fork)
+}
+
+func deleteProgram(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Check if the program exists and the user may access it
+	if !authorizeProgram(c, id, deleteAccess(c)) {
+		return
+	}
+
+	// Soft deleted programs hide their routines until they are restored
+	if !hardDelete(c) {
+		setDeleted(c, "Program", id, true, programStore.SetProgramDeleted)
+		return
+	}
+
+	// Deletes the program along with its routines and their exercises
+	if err := programStore.DeleteProgram(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Program not found"})
+			return
+		}
+
+		logger.LogError("Failed to delete program: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete program"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Program deleted successfully"})
+}
+
+func restoreProgram(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	if !authorizeProgram(c, id, ownerAccess) {
+		return
+	}
+
+	setDeleted(c, "Program", id, false, programStore.SetProgramDeleted)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Personal Record Handlers --------------------
+
+// detectRecords replaces the personal records earned by the set and
+// lists the new ones on it. The set is already stored, so failing to
+// detect records is logged rather than failing the request.
+func detectRecords(c *gin.Context, set *store.WorkoutSet) {
+	records, err := recordStore.SetPersonalRecords(c.Request.Context(), set.ID)
+	if err != nil {
+		logger.LogError("Failed to detect personal records: %v", err)
+		return
+	}
+	set.PersonalRecords = records
+}
+
+// listRecords responds with the records selected by the filter, along
+// with their history if requested.
+func listRecords(c *gin.Context, filter store.RecordFilter) {
+	if history := c.Query("history"); history != "" {
+		var err error
+		filter.History, err = strconv.ParseBool(history)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history flag"})
+			return
+		}
+	}
+
+	records, err := recordStore.ListPersonalRecords(c.Request.Context(), filter)
+	if err != nil {
+		logger.LogError("Failed to list personal records: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list personal records"})
+		return
+	}
+
+	c.JSON(http.StatusOK, records)
+}
+
+func getUserRecords(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "User")
+	if !ok {
+		return
+	}
+
+	// Records are only visible to the user who earned them
+	if id != currentUser(c).ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	listRecords(c, store.RecordFilter{UserID: id})
+}
+
+func getExerciseRecords(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Exercise")
+	if !ok {
+		return
+	}
+
+	// Check if exercise exists and the user may see it
+	if !authorizeExercise(c, id, readAccess) {
+		return
+	}
+
+	listRecords(c, store.RecordFilter{UserID: currentUser(c).ID, ExerciseID: id})
+}
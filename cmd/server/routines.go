@@ -0,0 +1,647 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/progression"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Routine Handlers (Milestone 3) --------------------
+
+func createRoutine(c *gin.Context) {
+	var routine store.Routine
+	if err := c.ShouldBindJSON(&routine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validation
+	if routine.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if routine.ProgramID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Program ID is required"})
+		return
+	}
+
+	// Check if program exists; only its owner may add routines to it
+	owner, err := programStore.ProgramOwner(c.Request.Context(), routine.ProgramID)
+	if errors.Is(err, store.ErrNotFound) || owner.Deleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Program not found"})
+		return
+	}
+
+	if !authorize(c, "Program", owner, err, writeAccess) {
+		return
+	}
+
+	if err := routineStore.CreateRoutine(c.Request.Context(), &routine); err != nil {
+		logger.LogError("Failed to create routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, routine)
+}
+
+func getRoutineByID(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Routines of private programs are only visible to their owner
+	if !authorizeRoutine(c, id, readAccess) {
+		return
+	}
+
+	routine, err := routineStore.GetRoutine(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+			return
+		}
+
+		logger.LogError("Failed to get routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get routine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, routine)
+}
+
+func listRoutines(c *gin.Context) {
+	page, ok := getListPage(c, store.RoutineSortFields, store.DefaultRoutineSort)
+	if !ok {
+		return
+	}
+
+	// Only routines of the user's own and public programs are listed
+	filter := store.RoutineFilter{
+		Page:      page,
+		VisibleTo: currentUser(c).ID,
+	}
+
+	if programID := c.Query("program_id"); programID != "" {
+		id, err := strconv.Atoi(programID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid program ID"})
+			return
+		}
+
+		if !authorizeProgram(c, id, readAccess) {
+			return
+		}
+		filter.ProgramID = id
+	}
+
+	routines, info, err := routineStore.ListRoutines(c.Request.Context(), filter)
+	if err != nil {
+		// Cursors are opaque, but may still be forged
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+
+		logger.LogError("Failed to list routines: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list routines"})
+		return
+	}
+
+	c.JSON(http.StatusOK, listResponse(routines, info, filter.Page))
+}
+
+func updateRoutine(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, id, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var routine store.Routine
+	if err := c.ShouldBindJSON(&routine); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	routine.ID = id
+
+	// Validation
+	if routine.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name is required"})
+		return
+	}
+
+	if err := routineStore.UpdateRoutine(c.Request.Context(), &routine); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+			return
+		}
+
+		logger.LogError("Failed to update routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, routine)
+}
+
+func deleteRoutine(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, id, deleteAccess(c)) {
+		return
+	}
+
+	// Workouts that followed a soft deleted routine keep pointing at it
+	if !hardDelete(c) {
+		setDeleted(c, "Routine", id, true, routineStore.SetRoutineDeleted)
+		return
+	}
+
+	// Deletes the routine along with its exercises
+	if err := routineStore.DeleteRoutine(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Routine not found"})
+			return
+		}
+
+		logger.LogError("Failed to delete routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete routine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Routine deleted successfully"})
+}
+
+func restoreRoutine(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	if !authorizeRoutine(c, id, ownerAccess) {
+		return
+	}
+
+	setDeleted(c, "Routine", id, false, routineStore.SetRoutineDeleted)
+}
+
+// -------------------- Routine-Exercise Handlers (Milestone 3) --------------------
+
+// validateProgression checks the progression scheme of a routine
+// exercise and its config. Exercises without a scheme do not progress.
+func validateProgression(routineExercise *store.RoutineExercise) error {
+	if routineExercise.ProgressionScheme == "" {
+		routineExercise.ProgressionScheme = progression.None
+	}
+
+	err := progression.Validate(routineExercise.ProgressionScheme, routineExercise.ProgressionConfig)
+	if errors.Is(err, progression.ErrUnknownScheme) {
+		return fmt.Errorf("Progression scheme must be one of %s", strings.Join(progression.Schemes(), ", "))
+	}
+	return err
+}
+
+func addExerciseToRoutine(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var routineExercise store.RoutineExercise
+	if err := c.ShouldBindJSON(&routineExercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set routine ID from path parameter
+	routineExercise.RoutineID = routineID
+
+	// Validation
+	if routineExercise.ExerciseID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise ID is required"})
+		return
+	}
+
+	if err := validateProgression(&routineExercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Check if exercise exists and the user may use it
+	if _, err := getVisibleExercise(c.Request.Context(), currentUser(c), routineExercise.ExerciseID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to check if exercise exists: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Exercises are appended unless a position is given, and join groups
+	// through the group endpoints
+	routineExercise.GroupID = nil
+	if err := routineStore.AddRoutineExercise(c.Request.Context(), &routineExercise); err != nil {
+		logger.LogError("Failed to add exercise to routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add exercise to routine"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, routineExercise)
+}
+
+func getRoutineExercises(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, routineID, readAccess) {
+		return
+	}
+
+	exercises, err := routineStore.ListRoutineExercises(c.Request.Context(), routineID)
+	if err != nil {
+		logger.LogError("Failed to get routine exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get routine exercises"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercises)
+}
+
+func updateRoutineExercise(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "routineExerciseId", "Routine exercise")
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var routineExercise store.RoutineExercise
+	if err := c.ShouldBindJSON(&routineExercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	routineExercise.ID = id
+	routineExercise.RoutineID = routineID
+
+	// Validation
+	if err := validateProgression(&routineExercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := routineStore.UpdateRoutineExercise(c.Request.Context(), &routineExercise); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found in routine"})
+			return
+		}
+
+		logger.LogError("Failed to update routine exercise: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine exercise"})
+		return
+	}
+
+	c.JSON(http.StatusOK, routineExercise)
+}
+
+func removeExerciseFromRoutine(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "routineExerciseId", "Routine exercise")
+	if !ok {
+		return
+	}
+
+	if err := routineStore.RemoveRoutineExercise(c.Request.Context(), routineID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found in routine"})
+			return
+		}
+
+		logger.LogError("Failed to remove exercise from routine: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove exercise from routine"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Exercise removed from routine successfully"})
+}
+
+// reorderRequest lists every exercise of a routine in its new order.
+type reorderRequest struct {
+	RoutineExerciseIDs []int `json:"routine_exercise_ids"`
+}
+
+func reorderRoutineExercises(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	var request reorderRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := routineStore.ReorderRoutineExercises(ctx, routineID, request.RoutineExerciseIDs); err != nil {
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Routine exercise IDs must list every exercise of the routine once"})
+			return
+		}
+
+		logger.LogError("Failed to reorder routine exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder routine exercises"})
+		return
+	}
+
+	exercises, err := routineStore.ListRoutineExercises(ctx, routineID)
+	if err != nil {
+		logger.LogError("Failed to get routine exercises: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get routine exercises"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exercises)
+}
+
+// -------------------- Routine Group Handlers --------------------
+
+// validateGroup checks a superset or circuit and drops repeated
+// exercises from it.
+func validateGroup(group *store.RoutineGroup) error {
+	if group.Kind != store.GroupSuperset && group.Kind != store.GroupCircuit {
+		return errors.New("Kind must be superset or circuit")
+	}
+	if group.Rounds < 0 {
+		return errors.New("Rounds must not be negative")
+	}
+	if group.RestSeconds < 0 {
+		return errors.New("Rest seconds must not be negative")
+	}
+
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, id := range group.RoutineExerciseIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	group.RoutineExerciseIDs = ids
+
+	if len(ids) < 2 {
+		return errors.New("A group needs at least two routine exercises")
+	}
+	return nil
+}
+
+func createRoutineGroup(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	var group store.RoutineGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group.RoutineID = routineID
+
+	// Validation
+	if err := validateGroup(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Exercises leave the group they were in
+	if err := routineStore.CreateRoutineGroup(c.Request.Context(), &group); err != nil {
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Routine exercise not found in routine"})
+			return
+		}
+
+		logger.LogError("Failed to create routine group: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create routine group"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+func listRoutineGroups(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, routineID, readAccess) {
+		return
+	}
+
+	groups, err := routineStore.ListRoutineGroups(c.Request.Context(), routineID)
+	if err != nil {
+		logger.LogError("Failed to list routine groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list routine groups"})
+		return
+	}
+
+	c.JSON(http.StatusOK, groups)
+}
+
+func updateRoutineGroup(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "groupId", "Group")
+	if !ok {
+		return
+	}
+
+	var group store.RoutineGroup
+	if err := c.ShouldBindJSON(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	group.ID = id
+	group.RoutineID = routineID
+
+	// Validation
+	if err := validateGroup(&group); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := routineStore.UpdateRoutineGroup(c.Request.Context(), &group); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+		case errors.Is(err, store.ErrPrecondition):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Routine exercise not found in routine"})
+		default:
+			logger.LogError("Failed to update routine group: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update routine group"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, group)
+}
+
+func deleteRoutineGroup(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a routine's exercises
+	if !authorizeRoutine(c, routineID, writeAccess) {
+		return
+	}
+
+	id, ok := parseIDParam(c, "groupId", "Group")
+	if !ok {
+		return
+	}
+
+	// The exercises of the group stay in the routine on their own
+	if err := routineStore.DeleteRoutineGroup(c.Request.Context(), routineID, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Group not found"})
+			return
+		}
+
+		logger.LogError("Failed to delete routine group: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete routine group"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Group deleted successfully"})
+}
+
+// -------------------- Progression Handlers --------------------
+
+// nextTargets computes the targets of the user's next session of the
+// routine from the sets they performed in earlier sessions of it.
+func nextTargets(ctx context.Context, userID, routineID int) ([]progression.Target, error) {
+	exercises, err := routineStore.ListRoutineExercises(ctx, routineID)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := workoutStore.ListPerformedSets(ctx, store.PerformedSetFilter{UserID: userID, RoutineID: routineID})
+	if err != nil {
+		return nil, err
+	}
+
+	// Sets count towards the routine exercise they were planned for.
+	// Sets without one, logged by hand or before routines could hold an
+	// exercise twice, count towards every entry of their exercise.
+	history := map[int][]store.PerformedSet{}
+	for _, exercise := range exercises {
+		for _, set := range sets {
+			if set.RoutineExerciseID != nil && *set.RoutineExerciseID == exercise.ID ||
+				set.RoutineExerciseID == nil && set.ExerciseID == exercise.ExerciseID {
+				history[exercise.ID] = append(history[exercise.ID], set)
+			}
+		}
+	}
+
+	targets := []progression.Target{}
+	for _, exercise := range exercises {
+		target, err := progression.Next(exercise, history[exercise.ID])
+		if err != nil {
+			// Schemes that were removed fall back to the recommendations
+			logger.LogWarn("Failed to compute progression of routine exercise %d: %v", exercise.ID, err)
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+func getNextSession(c *gin.Context) {
+	routineID, ok := parseIDParam(c, "id", "Routine")
+	if !ok {
+		return
+	}
+
+	// Check if the routine exists and the user may access it
+	if !authorizeRoutine(c, routineID, readAccess) {
+		return
+	}
+
+	targets, err := nextTargets(c.Request.Context(), currentUser(c).ID, routineID)
+	if err != nil {
+		logger.LogError("Failed to compute next session: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute next session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
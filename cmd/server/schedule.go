@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/schedule"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const (
+	// defaultScheduleDays is how many days the schedule covers when no
+	// end of the range is given.
+	defaultScheduleDays = 28
+	// maxScheduleDays is the longest range the schedule covers.
+	maxScheduleDays = 366
+)
+
+// listAll pages through a list until it has every item.
+func listAll[T any](list func(page store.Page) ([]T, store.PageInfo, error)) ([]T, error) {
+	all := []T{}
+	page := store.Page{Limit: 100}
+	for {
+		items, info, err := list(page)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, items...)
+		if info.Next == nil {
+			return all, nil
+		}
+		page.Cursor = info.Next
+	}
+}
+
+// -------------------- Program Block Handlers --------------------
+
+func getProgramBlocks(c *gin.Context) {
+	programID, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Check if the program exists and the user may access it
+	if !authorizeProgram(c, programID, readAccess) {
+		return
+	}
+
+	blocks, err := programStore.ListProgramBlocks(c.Request.Context(), programID)
+	if err != nil {
+		logger.LogError("Failed to get program blocks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get program blocks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+func setProgramBlocks(c *gin.Context) {
+	programID, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change the blocks of a program
+	if !authorizeProgram(c, programID, writeAccess) {
+		return
+	}
+
+	// Parse request body; the blocks run in the order they are given
+	var blocks []store.ProgramBlock
+	if err := c.ShouldBindJSON(&blocks); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Validation
+	for i := range blocks {
+		block := &blocks[i]
+		if block.Weeks <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Weeks must be positive"})
+			return
+		}
+
+		// Unscaled blocks keep the recommendations of their routines,
+		// unless they are deloads
+		block.SetScale, block.RPEScale = schedule.DefaultScales(block.Deload, block.SetScale, block.RPEScale)
+		if block.SetScale < 0 || block.RPEScale < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Scales must be positive"})
+			return
+		}
+	}
+
+	if err := programStore.SetProgramBlocks(c.Request.Context(), programID, blocks); err != nil {
+		logger.LogError("Failed to set program blocks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set program blocks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, blocks)
+}
+
+// -------------------- Enrollment Handlers --------------------
+
+type enrollRequest struct {
+	// StartDate is a date; enrollments start today without one.
+	StartDate string `json:"start_date"`
+}
+
+func enrollInProgram(c *gin.Context) {
+	programID, ok := parseIDParam(c, "id", "Program")
+	if !ok {
+		return
+	}
+
+	// Users may follow their own programs and public ones
+	if !authorizeProgram(c, programID, useAccess) {
+		return
+	}
+
+	// The body is optional
+	var request enrollRequest
+	if err := c.ShouldBindJSON(&request); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment := store.Enrollment{
+		UserID:    currentUser(c).ID,
+		ProgramID: programID,
+		StartDate: schedule.Date(time.Now()),
+	}
+
+	if request.StartDate != "" {
+		startDate, err := time.Parse(time.DateOnly, request.StartDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start date, expected YYYY-MM-DD"})
+			return
+		}
+		enrollment.StartDate = startDate
+	}
+
+	if err := enrollmentStore.CreateEnrollment(c.Request.Context(), &enrollment); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Already enrolled in the program"})
+			return
+		}
+
+		logger.LogError("Failed to create enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enroll in program"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+func listEnrollments(c *gin.Context) {
+	activeOnly := c.Query("active") == "true"
+
+	enrollments, err := enrollmentStore.ListEnrollments(c.Request.Context(), currentUser(c).ID, activeOnly)
+	if err != nil {
+		logger.LogError("Failed to list enrollments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list enrollments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollments)
+}
+
+// getOwnEnrollment loads the enrollment in the path. Enrollments of
+// other users are reported as not found. If anything fails, it writes
+// the error response and returns false.
+func getOwnEnrollment(c *gin.Context) (store.Enrollment, bool) {
+	id, ok := parseIDParam(c, "id", "Enrollment")
+	if !ok {
+		return store.Enrollment{}, false
+	}
+
+	enrollment, err := enrollmentStore.GetEnrollment(c.Request.Context(), id)
+	if errors.Is(err, store.ErrNotFound) || (err == nil && enrollment.UserID != currentUser(c).ID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Enrollment not found"})
+		return store.Enrollment{}, false
+	}
+
+	if err != nil {
+		logger.LogError("Failed to get enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get enrollment"})
+		return store.Enrollment{}, false
+	}
+	return enrollment, true
+}
+
+func getEnrollmentByID(c *gin.Context) {
+	enrollment, ok := getOwnEnrollment(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func endEnrollment(c *gin.Context) {
+	enrollment, ok := getOwnEnrollment(c)
+	if !ok {
+		return
+	}
+
+	if err := enrollmentStore.EndEnrollment(c.Request.Context(), &enrollment); err != nil {
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Enrollment has already ended"})
+			return
+		}
+
+		logger.LogError("Failed to end enrollment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end enrollment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// -------------------- Schedule Handlers --------------------
+
+// loadPlan loads everything needed to lay out the enrollment. It
+// returns false if the program has been deleted or is no longer
+// visible to the user.
+func loadPlan(ctx context.Context, user store.User, enrollment store.Enrollment) (schedule.Plan, bool, error) {
+	plan := schedule.Plan{Enrollment: enrollment}
+
+	program, err := programStore.GetProgram(ctx, enrollment.ProgramID)
+	if err != nil {
+		return plan, false, err
+	}
+	if program.DeletedAt != nil || !program.IsPublic && program.UserID != user.ID {
+		return plan, false, nil
+	}
+	plan.Program = program
+
+	plan.Blocks, err = programStore.ListProgramBlocks(ctx, program.ID)
+	if err != nil {
+		return plan, false, err
+	}
+
+	routines, err := listAll(func(page store.Page) ([]store.Routine, store.PageInfo, error) {
+		return routineStore.ListRoutines(ctx, store.RoutineFilter{Page: page, VisibleTo: user.ID, ProgramID: program.ID})
+	})
+	if err != nil {
+		return plan, false, err
+	}
+
+	for _, routine := range routines {
+		exercises, err := routineStore.ListRoutineExercises(ctx, routine.ID)
+		if err != nil {
+			return plan, false, err
+		}
+		plan.Routines = append(plan.Routines, schedule.Routine{Routine: routine, Exercises: exercises})
+	}
+	return plan, true, nil
+}
+
+func getUserSchedule(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "User")
+	if !ok {
+		return
+	}
+
+	// Schedules are only visible to the user they describe
+	user := currentUser(c)
+	if id != user.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	from := schedule.Date(time.Now())
+	if c.Query("from") != "" {
+		if from, ok = parseTimeParam(c, "from", false); !ok {
+			return
+		}
+		from = schedule.Date(from)
+	}
+
+	to := from.AddDate(0, 0, defaultScheduleDays)
+	if c.Query("to") != "" {
+		if to, ok = parseTimeParam(c, "to", true); !ok {
+			return
+		}
+	}
+
+	if !from.Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	if to.Sub(from) > maxScheduleDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The schedule covers at most 366 days"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	enrollments, err := enrollmentStore.ListEnrollments(ctx, user.ID, false)
+	if err != nil {
+		logger.LogError("Failed to list enrollments: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+		return
+	}
+
+	sessions := []schedule.Session{}
+	for _, enrollment := range enrollments {
+		plan, visible, err := loadPlan(ctx, user, enrollment)
+		if err != nil {
+			logger.LogError("Failed to load program of enrollment %d: %v", enrollment.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+			return
+		}
+		if visible {
+			sessions = append(sessions, schedule.Generate(plan, from, to)...)
+		}
+	}
+	schedule.Sort(sessions)
+
+	// Link the sessions to the workouts performed on their day
+	workouts, err := listAll(func(page store.Page) ([]store.WorkoutWithRoutineName, store.PageInfo, error) {
+		return workoutStore.ListWorkouts(ctx, store.WorkoutFilter{Page: page, UserID: user.ID, From: from, To: to})
+	})
+	if err != nil {
+		logger.LogError("Failed to list workouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get schedule"})
+		return
+	}
+	schedule.Match(sessions, workouts)
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":     from.Format(time.DateOnly),
+		"to":       to.Add(-time.Nanosecond).Format(time.DateOnly),
+		"sessions": sessions,
+	})
+}
@@ -0,0 +1,550 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Sync Handlers --------------------
+
+const (
+	// syncPageSize is how many changes are listed at once unless the
+	// client asks for up to maxSyncPageSize.
+	syncPageSize    = 500
+	maxSyncPageSize = 1000
+	// maxSyncMutations bounds the number of mutations applied at once.
+	maxSyncMutations = 500
+)
+
+// changesResponse is the response of GET /api/sync. The cursor is
+// opaque to clients, which send it back to list the changes after it.
+type changesResponse struct {
+	store.Changes
+	Cursor string `json:"cursor"`
+}
+
+// formatChangeCursor formats a cursor the way GET /api/sync takes it.
+func formatChangeCursor(cursor store.ChangeCursor) string {
+	return strconv.FormatInt(cursor.Xact, 10) + "." + strconv.FormatInt(cursor.Seq, 10)
+}
+
+// parseChangeCursor parses a cursor made by formatChangeCursor.
+func parseChangeCursor(token string) (store.ChangeCursor, bool) {
+	xact, seq, ok := strings.Cut(token, ".")
+	if !ok {
+		return store.ChangeCursor{}, false
+	}
+	var cursor store.ChangeCursor
+	var err error
+	if cursor.Xact, err = strconv.ParseInt(xact, 10, 64); err != nil || cursor.Xact < 0 {
+		return store.ChangeCursor{}, false
+	}
+	if cursor.Seq, err = strconv.ParseInt(seq, 10, 64); err != nil || cursor.Seq < 0 {
+		return store.ChangeCursor{}, false
+	}
+	return cursor, true
+}
+
+func getChanges(c *gin.Context) {
+	// Everything is listed without a cursor
+	var since store.ChangeCursor
+	if token := c.Query("since"); token != "" {
+		var ok bool
+		if since, ok = parseChangeCursor(token); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(syncPageSize)))
+	if err != nil || limit < 1 || limit > maxSyncPageSize {
+		limit = syncPageSize
+	}
+
+	changes, err := syncStore.ListChanges(c.Request.Context(), currentUser(c).ID, since, limit)
+	if err != nil {
+		logger.LogError("Failed to list changes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list changes"})
+		return
+	}
+
+	for i := range changes.Workouts {
+		setElapsed(&changes.Workouts[i])
+	}
+
+	c.JSON(http.StatusOK, changesResponse{Changes: changes, Cursor: formatChangeCursor(changes.Cursor)})
+}
+
+// Operations of sync mutations.
+const (
+	opCreate = "create"
+	opUpdate = "update"
+	opDelete = "delete"
+)
+
+// Strategies for mutations of rows that changed on the server since the
+// client last synced them.
+const (
+	// strategyLastWriterWins applies the mutation unless the row was
+	// updated on the server after the client made it.
+	strategyLastWriterWins = "last_writer_wins"
+	// strategyFieldLevel applies the fields the client changed unless
+	// the server changed them too, and reports those fields otherwise.
+	strategyFieldLevel = "field_level"
+)
+
+// Statuses of applied mutations.
+const (
+	mutationApplied = "applied"
+	// mutationSuperseded means the server holds a later write.
+	mutationSuperseded = "superseded"
+	// mutationConflict means the client and server changed the same
+	// fields.
+	mutationConflict = "conflict"
+	// mutationRejected means the mutation is invalid or not allowed.
+	mutationRejected = "rejected"
+	// mutationFailed means the mutation failed on the server and may be
+	// retried.
+	mutationFailed = "failed"
+)
+
+// syncMutation is a change a client made while offline. IDs may refer to
+// rows created earlier in the batch as "$" followed by their ClientID,
+// and so may the values in Data.
+type syncMutation struct {
+	ClientID string      `json:"client_id"`
+	Entity   string      `json:"entity"`
+	Op       string      `json:"op"`
+	ID       interface{} `json:"id"`
+	// ParentID is the routine of a routine exercise or the workout of a
+	// workout set.
+	ParentID interface{} `json:"parent_id"`
+	// Data is the row as sent to the endpoint of the entity.
+	Data map[string]interface{} `json:"data"`
+	// Hard deletes the row for good.
+	Hard bool `json:"hard"`
+	// UpdatedAt is when the client made the change, for last writer
+	// wins.
+	UpdatedAt *time.Time `json:"updated_at"`
+	// Base is the row as the client last synced it, for field-level
+	// conflict reporting.
+	Base map[string]interface{} `json:"base"`
+}
+
+// syncRequest is the body of POST /api/sync.
+type syncRequest struct {
+	Strategy  string         `json:"strategy"`
+	Mutations []syncMutation `json:"mutations"`
+}
+
+// syncResult reports how a mutation was applied.
+type syncResult struct {
+	ClientID string `json:"client_id,omitempty"`
+	Entity   string `json:"entity"`
+	Op       string `json:"op"`
+	ID       int    `json:"id,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	// Conflicts lists the fields changed both by the client and on the
+	// server.
+	Conflicts []string `json:"conflicts,omitempty"`
+	// Data is the response to the mutation once applied.
+	Data json.RawMessage `json:"data,omitempty"`
+	// Current is the row as it is on the server when the mutation was
+	// not applied.
+	Current interface{} `json:"current,omitempty"`
+}
+
+// syncEntity describes how mutations of an entity are applied.
+type syncEntity struct {
+	// hasParent is set if rows are created under a parent row
+	hasParent bool
+	// path is the endpoint rows are created at
+	path func(parentID int) string
+	// current returns the row of the user as it is now, or ErrNotFound
+	// if the user has no such row
+	current func(ctx context.Context, userID, parentID, id int) (interface{}, error)
+}
+
+// ownedBy returns a check of the owner of a resource, which reports the
+// resources of other users as missing.
+func ownedBy(userID int) func(store.Owner, error) error {
+	return func(owner store.Owner, err error) error {
+		if err == nil && owner.UserID != userID {
+			return store.ErrNotFound
+		}
+		return err
+	}
+}
+
+var syncEntities = map[string]syncEntity{
+	store.EntityProgram: {
+		path: func(int) string { return "/api/programs" },
+		current: func(ctx context.Context, userID, _, id int) (interface{}, error) {
+			if err := ownedBy(userID)(programStore.ProgramOwner(ctx, id)); err != nil {
+				return nil, err
+			}
+			return programStore.GetProgram(ctx, id)
+		},
+	},
+	store.EntityRoutine: {
+		path: func(int) string { return "/api/routines" },
+		current: func(ctx context.Context, userID, _, id int) (interface{}, error) {
+			if err := ownedBy(userID)(routineStore.RoutineOwner(ctx, id)); err != nil {
+				return nil, err
+			}
+			return routineStore.GetRoutine(ctx, id)
+		},
+	},
+	store.EntityRoutineExercise: {
+		hasParent: true,
+		path:      func(routineID int) string { return fmt.Sprintf("/api/routines/%d/exercises", routineID) },
+		current: func(ctx context.Context, userID, routineID, id int) (interface{}, error) {
+			if err := ownedBy(userID)(routineStore.RoutineOwner(ctx, routineID)); err != nil {
+				return nil, err
+			}
+			routineExercises, err := routineStore.ListRoutineExercises(ctx, routineID)
+			if err != nil {
+				return nil, err
+			}
+			for _, routineExercise := range routineExercises {
+				if routineExercise.ID == id {
+					return routineExercise.RoutineExercise, nil
+				}
+			}
+			return nil, store.ErrNotFound
+		},
+	},
+	store.EntityWorkout: {
+		path: func(int) string { return "/api/workouts" },
+		current: func(ctx context.Context, userID, _, id int) (interface{}, error) {
+			if err := ownedBy(userID)(workoutStore.WorkoutOwner(ctx, id)); err != nil {
+				return nil, err
+			}
+			return workoutStore.GetWorkout(ctx, id)
+		},
+	},
+	store.EntityWorkoutSet: {
+		hasParent: true,
+		path:      func(workoutID int) string { return fmt.Sprintf("/api/workouts/%d/sets", workoutID) },
+		current: func(ctx context.Context, userID, workoutID, id int) (interface{}, error) {
+			if err := ownedBy(userID)(workoutStore.WorkoutOwner(ctx, workoutID)); err != nil {
+				return nil, err
+			}
+			set, err := workoutStore.GetWorkoutSet(ctx, workoutID, id)
+			return set.WorkoutSet, err
+		},
+	},
+}
+
+// resolveRef resolves a value referring to a row created earlier in the
+// batch to its ID. Other values are returned as they are.
+func resolveRef(value interface{}, created map[string]int) (interface{}, error) {
+	ref, ok := value.(string)
+	if !ok || !strings.HasPrefix(ref, "$") {
+		return value, nil
+	}
+	id, ok := created[strings.TrimPrefix(ref, "$")]
+	if !ok {
+		return nil, fmt.Errorf("Unknown reference %s", ref)
+	}
+	return id, nil
+}
+
+// resolveID resolves the ID of a row, which is 0 if it is missing.
+func resolveID(value interface{}, created map[string]int) (int, error) {
+	value, err := resolveRef(value, created)
+	if err != nil {
+		return 0, err
+	}
+	switch id := value.(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return id, nil
+	case float64:
+		if id == float64(int(id)) && id > 0 {
+			return int(id), nil
+		}
+	}
+	return 0, fmt.Errorf("Invalid ID %v", value)
+}
+
+// toMap returns a row as its JSON fields.
+func toMap(row interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{}
+	return fields, json.Unmarshal(encoded, &fields)
+}
+
+// sameTime reports whether two JSON timestamps are the same instant.
+func sameTime(a, b interface{}) bool {
+	aString, _ := a.(string)
+	bString, _ := b.(string)
+	aTime, aErr := time.Parse(time.RFC3339Nano, aString)
+	bTime, bErr := time.Parse(time.RFC3339Nano, bString)
+	return aErr == nil && bErr == nil && aTime.Equal(bTime)
+}
+
+// reconcile decides how a mutation of a row that the server may have
+// changed since the client synced it applies. It returns the status of
+// the mutation, along with the body to send if it applies and the
+// conflicting fields if it does not.
+func reconcile(strategy string, mutation syncMutation, data, current map[string]interface{}) (string, map[string]interface{}, []string) {
+	if strategy == strategyLastWriterWins {
+		updatedAt, err := time.Parse(time.RFC3339Nano, fmt.Sprint(current["updated_at"]))
+		if err == nil && mutation.UpdatedAt != nil && updatedAt.After(*mutation.UpdatedAt) {
+			return mutationSuperseded, nil, nil
+		}
+		return mutationApplied, data, nil
+	}
+
+	// Rows the server left alone take the mutation as it is
+	if sameTime(mutation.Base["updated_at"], current["updated_at"]) {
+		return mutationApplied, data, nil
+	}
+
+	conflicts := []string{}
+	if mutation.Op == opDelete {
+		// Deleting conflicts with any change made on the server
+		for field, value := range current {
+			if field != "updated_at" && !reflect.DeepEqual(value, mutation.Base[field]) {
+				conflicts = append(conflicts, field)
+			}
+		}
+	}
+
+	// The fields the client changed are applied on top of the row as it
+	// is now, unless the server changed them differently
+	merged := map[string]interface{}{}
+	for field, value := range current {
+		merged[field] = value
+	}
+	for field, value := range data {
+		base := mutation.Base[field]
+		if reflect.DeepEqual(value, base) {
+			continue
+		}
+		if !reflect.DeepEqual(current[field], base) && !reflect.DeepEqual(current[field], value) {
+			conflicts = append(conflicts, field)
+		}
+		merged[field] = value
+	}
+
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		return mutationConflict, nil, conflicts
+	}
+	return mutationApplied, merged, nil
+}
+
+// bufferedResponse keeps a response in memory.
+type bufferedResponse struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *bufferedResponse) Header() http.Header {
+	return r.header
+}
+
+func (r *bufferedResponse) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *bufferedResponse) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+// dispatch sends a request through the router on behalf of the user of
+// c, and returns the response.
+func dispatch(c *gin.Context, router http.Handler, method, path string, body map[string]interface{}) (*bufferedResponse, error) {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequestWithContext(c.Request.Context(), method, path, bytes.NewReader(encoded))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", c.GetHeader("Authorization"))
+	request.Header.Set("Content-Type", "application/json")
+
+	response := &bufferedResponse{header: http.Header{}}
+	router.ServeHTTP(response, request)
+	return response, nil
+}
+
+// applyMutation applies a mutation through the endpoint of its entity.
+func applyMutation(c *gin.Context, router http.Handler, strategy string, mutation syncMutation, created map[string]int) syncResult {
+	result := syncResult{ClientID: mutation.ClientID, Entity: mutation.Entity, Op: mutation.Op}
+	reject := func(message string) syncResult {
+		result.Status = mutationRejected
+		result.Error = message
+		return result
+	}
+	fail := func(message string, err error) syncResult {
+		logger.LogError("%s: %v", message, err)
+		result.Status = mutationFailed
+		result.Error = message
+		return result
+	}
+
+	// Validation
+	entity, ok := syncEntities[mutation.Entity]
+	if !ok {
+		return reject("Entity must be program, routine, routine_exercise, workout or workout_set")
+	}
+
+	id, err := resolveID(mutation.ID, created)
+	if err != nil {
+		return reject(err.Error())
+	}
+	result.ID = id
+
+	parentID, err := resolveID(mutation.ParentID, created)
+	if err != nil {
+		return reject(err.Error())
+	}
+	if entity.hasParent && parentID == 0 {
+		return reject("Parent ID is required")
+	}
+
+	data := map[string]interface{}{}
+	for field, value := range mutation.Data {
+		if data[field], err = resolveRef(value, created); err != nil {
+			return reject(err.Error())
+		}
+	}
+
+	method, path := http.MethodPost, entity.path(parentID)
+	switch mutation.Op {
+	case opCreate:
+	case opUpdate, opDelete:
+		if id == 0 {
+			return reject("ID is required")
+		}
+		if strategy == strategyFieldLevel && mutation.Base == nil {
+			return reject("Base is required for field-level conflict reporting")
+		}
+
+		method, path = http.MethodPut, path+"/"+strconv.Itoa(id)
+		if mutation.Op == opDelete {
+			method = http.MethodDelete
+			if mutation.Hard {
+				path += "?hard=true"
+			}
+		}
+	default:
+		return reject("Op must be create, update or delete")
+	}
+
+	// Rows that changed on the server since the client synced them are
+	// reconciled first. Missing rows are reported by the endpoint.
+	if mutation.Op != opCreate {
+		row, err := entity.current(c.Request.Context(), currentUser(c).ID, parentID, id)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			return fail("Failed to get current row", err)
+		}
+		if err == nil {
+			current, err := toMap(row)
+			if err != nil {
+				return fail("Failed to get current row", err)
+			}
+
+			result.Status, data, result.Conflicts = reconcile(strategy, mutation, data, current)
+			if result.Status != mutationApplied {
+				result.Current = current
+				return result
+			}
+		}
+	}
+
+	response, err := dispatch(c, router, method, path, data)
+	if err != nil {
+		return fail("Failed to apply mutation", err)
+	}
+
+	var body struct {
+		ID    int    `json:"id"`
+		Error string `json:"error"`
+	}
+	json.Unmarshal(response.body.Bytes(), &body)
+	switch {
+	case response.status >= http.StatusInternalServerError:
+		result.Status = mutationFailed
+		result.Error = body.Error
+	case response.status >= http.StatusBadRequest:
+		result.Status = mutationRejected
+		result.Error = body.Error
+	default:
+		result.Status = mutationApplied
+		result.Data = response.body.Bytes()
+		if mutation.Op == opCreate {
+			result.ID = body.ID
+		}
+	}
+	return result
+}
+
+// applyMutations returns the handler of POST /api/sync, which applies a
+// batch of mutations in order through the router, so that they are
+// validated and authorized like any other request. Each mutation is
+// applied on its own: one that fails does not undo the others.
+func applyMutations(router http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var request syncRequest
+		if err := c.ShouldBindJSON(&request); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		// Validation
+		if request.Strategy == "" {
+			request.Strategy = strategyLastWriterWins
+		}
+		if request.Strategy != strategyLastWriterWins && request.Strategy != strategyFieldLevel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Strategy must be last_writer_wins or field_level"})
+			return
+		}
+
+		if len(request.Mutations) > maxSyncMutations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d mutations may be applied at once", maxSyncMutations)})
+			return
+		}
+
+		// Rows created by the batch are known by their client IDs
+		created := map[string]int{}
+		results := []syncResult{}
+		for _, mutation := range request.Mutations {
+			result := applyMutation(c, router, request.Strategy, mutation, created)
+			if result.Status == mutationApplied && mutation.Op == opCreate && mutation.ClientID != "" {
+				created[mutation.ClientID] = result.ID
+			}
+			results = append(results, result)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"results": results})
+	}
+}
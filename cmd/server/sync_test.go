@@ -0,0 +1,82 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestParseChangeCursor(t *testing.T) {
+	tests := []struct {
+		token  string
+		want   store.ChangeCursor
+		wantOK bool
+	}{
+		{"0.0", store.ChangeCursor{}, true},
+		{"12.345", store.ChangeCursor{Xact: 12, Seq: 345}, true},
+		{"", store.ChangeCursor{}, false},
+		// Cursors from before changes were ordered by transaction
+		{"345", store.ChangeCursor{}, false},
+		{"12.", store.ChangeCursor{}, false},
+		{".345", store.ChangeCursor{}, false},
+		{"-1.5", store.ChangeCursor{}, false},
+		{"1.-5", store.ChangeCursor{}, false},
+		{"1.2.3", store.ChangeCursor{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, ok := parseChangeCursor(tt.token)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseChangeCursor(%q) = %+v, %v, want %+v, %v", tt.token, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatChangeCursorRoundTrip(t *testing.T) {
+	cursor := store.ChangeCursor{Xact: 1 << 40, Seq: 7}
+	if got, ok := parseChangeCursor(formatChangeCursor(cursor)); !ok || got != cursor {
+		t.Errorf("parseChangeCursor(formatChangeCursor(%+v)) = %+v, %v", cursor, got, ok)
+	}
+}
+
+func TestGetChanges(t *testing.T) {
+	router := newTestRouter()
+	token := register(t, router, "user@example.com")
+	other := register(t, router, "other@example.com")
+
+	sync := func(token, since string) changesResponse {
+		t.Helper()
+		w := do(router, http.MethodGet, "/api/sync?since="+since, token, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("sync since %q: %d %s", since, w.Code, w.Body.String())
+		}
+		return decode[changesResponse](t, w)
+	}
+
+	createTestProgram(t, router, token, store.Program{Name: "Strength"})
+	first := sync(token, "")
+	if len(first.Programs) != 1 {
+		t.Fatalf("first sync listed %d programs, want 1", len(first.Programs))
+	}
+	if changes := sync(other, ""); len(changes.Programs) != 0 {
+		t.Errorf("sync of another user listed %d programs, want 0", len(changes.Programs))
+	}
+
+	// Nothing changed since
+	if again := sync(token, first.Cursor); len(again.Programs) != 0 || again.Cursor != first.Cursor {
+		t.Errorf("sync since %q = %d programs and cursor %q, want none and the same cursor", first.Cursor, len(again.Programs), again.Cursor)
+	}
+
+	createTestProgram(t, router, token, store.Program{Name: "Hypertrophy"})
+	next := sync(token, first.Cursor)
+	if len(next.Programs) != 1 || next.Programs[0].Name != "Hypertrophy" {
+		t.Errorf("sync since %q listed %+v, want Hypertrophy", first.Cursor, next.Programs)
+	}
+
+	if w := do(router, http.MethodGet, "/api/sync?since=12", token, nil); w.Code != http.StatusBadRequest {
+		t.Errorf("sync with an invalid cursor: %d %s, want 400", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// taxonomy holds the slugs of the canonical muscles, equipment and
+// exercise types that exercises are described with.
+type taxonomy struct {
+	muscles       map[string]bool
+	equipment     map[string]bool
+	exerciseTypes map[string]bool
+}
+
+func loadTaxonomy(ctx context.Context) (taxonomy, error) {
+	t := taxonomy{muscles: map[string]bool{}, equipment: map[string]bool{}, exerciseTypes: map[string]bool{}}
+
+	muscles, err := taxonomyStore.ListMuscles(ctx)
+	if err != nil {
+		return t, err
+	}
+	for _, muscle := range muscles {
+		t.muscles[muscle.Slug] = true
+	}
+
+	equipment, err := taxonomyStore.ListEquipment(ctx)
+	if err != nil {
+		return t, err
+	}
+	for _, item := range equipment {
+		t.equipment[item.Slug] = true
+	}
+
+	exerciseTypes, err := taxonomyStore.ListExerciseTypes(ctx)
+	if err != nil {
+		return t, err
+	}
+	for _, exerciseType := range exerciseTypes {
+		t.exerciseTypes[exerciseType.Slug] = true
+	}
+	return t, nil
+}
+
+// slug turns a value into a taxonomy slug, so that "Lower Back" and
+// "lower-back" both name lower_back.
+func slug(value string) string {
+	words := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	})
+	return strings.Join(words, "_")
+}
+
+// slugs turns values into slugs, dropping empty values and duplicates,
+// and reports those that are not known.
+func slugs(values []string, known map[string]bool) ([]string, []string) {
+	result := []string{}
+	var unknown []string
+	seen := map[string]bool{}
+	for _, value := range values {
+		s := slug(value)
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		if !known[s] {
+			unknown = append(unknown, strings.TrimSpace(value))
+		}
+		result = append(result, s)
+	}
+	return result, unknown
+}
+
+// validate turns the muscles, equipment and type of an exercise into
+// slugs and checks that they are all known.
+func (t taxonomy) validate(exercise *store.Exercise) error {
+	exercise.ExerciseType = slug(exercise.ExerciseType)
+	if !t.exerciseTypes[exercise.ExerciseType] {
+		return fmt.Errorf("Exercise type must be one of %s", strings.Join(sortedKeys(t.exerciseTypes), ", "))
+	}
+
+	var unknown []string
+	exercise.Equipment, unknown = slugs(exercise.Equipment, t.equipment)
+	if len(unknown) > 0 {
+		return fmt.Errorf("Unknown equipment: %s", strings.Join(unknown, ", "))
+	}
+
+	exercise.PrimaryMuscles, unknown = slugs(exercise.PrimaryMuscles, t.muscles)
+	if len(unknown) > 0 {
+		return fmt.Errorf("Unknown primary muscles: %s", strings.Join(unknown, ", "))
+	}
+
+	exercise.SecondaryMuscles, unknown = slugs(exercise.SecondaryMuscles, t.muscles)
+	if len(unknown) > 0 {
+		return fmt.Errorf("Unknown secondary muscles: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// checkTaxonomy validates an exercise against the taxonomy, responding
+// with failure if the taxonomy cannot be loaded.
+func checkTaxonomy(c *gin.Context, exercise *store.Exercise, failure string) bool {
+	t, err := loadTaxonomy(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to load taxonomy: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": failure})
+		return false
+	}
+
+	if err := t.validate(exercise); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// -------------------- Taxonomy Handlers --------------------
+
+// muscleGroupResponse is a muscle group along with its muscles.
+type muscleGroupResponse struct {
+	store.MuscleGroup
+	Muscles []store.Muscle `json:"muscles"`
+}
+
+func listMuscleGroups(c *gin.Context) {
+	groups, err := taxonomyStore.ListMuscleGroups(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to list muscle groups: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list muscle groups"})
+		return
+	}
+
+	muscles, err := taxonomyStore.ListMuscles(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to list muscles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list muscle groups"})
+		return
+	}
+
+	response := []muscleGroupResponse{}
+	for _, group := range groups {
+		entry := muscleGroupResponse{MuscleGroup: group, Muscles: []store.Muscle{}}
+		for _, muscle := range muscles {
+			if muscle.MuscleGroup == group.Slug {
+				entry.Muscles = append(entry.Muscles, muscle)
+			}
+		}
+		response = append(response, entry)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func listMuscles(c *gin.Context) {
+	muscles, err := taxonomyStore.ListMuscles(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to list muscles: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list muscles"})
+		return
+	}
+
+	// Optional filtering by muscle group
+	if group := c.Query("group"); group != "" {
+		filtered := []store.Muscle{}
+		for _, muscle := range muscles {
+			if muscle.MuscleGroup == slug(group) {
+				filtered = append(filtered, muscle)
+			}
+		}
+		muscles = filtered
+	}
+
+	c.JSON(http.StatusOK, muscles)
+}
+
+func listEquipment(c *gin.Context) {
+	equipment, err := taxonomyStore.ListEquipment(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to list equipment: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list equipment"})
+		return
+	}
+
+	c.JSON(http.StatusOK, equipment)
+}
+
+func listExerciseTypes(c *gin.Context) {
+	exerciseTypes, err := taxonomyStore.ListExerciseTypes(c.Request.Context())
+	if err != nil {
+		logger.LogError("Failed to list exercise types: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list exercise types"})
+		return
+	}
+
+	c.JSON(http.StatusOK, exerciseTypes)
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/gps"
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+// maxTrackSize bounds the size of uploaded track files.
+const maxTrackSize = 20 << 20
+
+// trackResponse is a track along with its pace and, when its points
+// are loaded, its splits.
+type trackResponse struct {
+	store.Track
+	Pace   float64     `json:"pace"`
+	Splits []gps.Split `json:"splits,omitempty"`
+}
+
+func newTrackResponse(track store.Track) trackResponse {
+	response := trackResponse{
+		Track: track,
+		Pace:  training.Pace(track.Duration, track.Distance),
+	}
+	if track.Points != nil {
+		response.Splits = gps.Splits(track.Points)
+	}
+	return response
+}
+
+// formInt parses an optional form field as a positive integer; missing
+// fields are 0.
+func formInt(c *gin.Context, field, resource string) (int, bool) {
+	value := c.PostForm(field)
+	if value == "" {
+		return 0, true
+	}
+
+	id, err := strconv.Atoi(value)
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + resource + " ID"})
+		return 0, false
+	}
+	return id, true
+}
+
+// -------------------- Workout Track Handlers --------------------
+
+func uploadTrack(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, workoutID, writeAccess) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxTrackSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A GPX or TCX file is required in the file field"})
+		return
+	}
+
+	// The track either fills in an existing set or logs a new one
+	setID, ok := formInt(c, "set_id", "Set")
+	if !ok {
+		return
+	}
+	exerciseID, ok := formInt(c, "exercise_id", "Exercise")
+	if !ok {
+		return
+	}
+
+	var set store.WorkoutSet
+	if setID > 0 {
+		existing, err := workoutStore.GetWorkoutSet(ctx, workoutID, setID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workout set not found"})
+				return
+			}
+
+			logger.LogError("Failed to get workout set: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload track"})
+			return
+		}
+
+		if existing.ExerciseType != "distance_time" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tracks can only be added to distance_time exercises"})
+			return
+		}
+		set = existing.WorkoutSet
+	} else {
+		if exerciseID == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise ID or set ID is required"})
+			return
+		}
+
+		exercise, err := getVisibleExercise(ctx, currentUser(c), exerciseID)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise not found"})
+				return
+			}
+
+			logger.LogError("Failed to check if exercise exists: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+			return
+		}
+
+		if exercise.ExerciseType != "distance_time" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Tracks can only be added to distance_time exercises"})
+			return
+		}
+		set = store.WorkoutSet{WorkoutID: workoutID, ExerciseID: exerciseID, SetType: store.SetWorking}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		logger.LogError("Failed to open uploaded file: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload track"})
+		return
+	}
+	defer file.Close()
+
+	recording, err := gps.Parse(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be a valid GPX or TCX track"})
+		return
+	}
+
+	if len(recording.Points) < 2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Track must have at least two points"})
+		return
+	}
+
+	// The set takes the distance and duration of the track
+	summary := gps.Summarize(recording.Points)
+	if summary.Duration == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Track points must have times"})
+		return
+	}
+	set.Distance = summary.Distance
+	set.Duration = summary.Duration
+	set.Completed = true
+
+	if err := validateWorkoutSet(&set, "distance_time"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// The set is only written along with its track, which the set may
+	// already have
+	track := store.Track{
+		WorkoutID:     workoutID,
+		Format:        recording.Format,
+		Name:          recording.Name,
+		Distance:      summary.Distance,
+		Duration:      summary.Duration,
+		ElevationGain: summary.ElevationGain,
+		Points:        recording.Points,
+	}
+	if err := trackStore.CreateTrack(ctx, &track, &set); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout set not found"})
+			return
+		}
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Set already has a track"})
+			return
+		}
+
+		logger.LogError("Failed to create track: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload track"})
+		return
+	}
+
+	detectRecords(c, &set)
+
+	// The points are left out of the response; they were just uploaded
+	response := newTrackResponse(track)
+	response.Points = nil
+
+	c.JSON(http.StatusCreated, gin.H{
+		"set":   set,
+		"track": response,
+	})
+}
+
+func getWorkoutTracks(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, workoutID, readAccess) {
+		return
+	}
+
+	tracks, err := trackStore.ListTracks(c.Request.Context(), workoutID)
+	if err != nil {
+		logger.LogError("Failed to list tracks: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tracks"})
+		return
+	}
+
+	responses := []trackResponse{}
+	for _, track := range tracks {
+		responses = append(responses, newTrackResponse(track))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+func getWorkoutTrack(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, workoutID, readAccess) {
+		return
+	}
+
+	trackID, ok := parseIDParam(c, "trackId", "Track")
+	if !ok {
+		return
+	}
+
+	track, err := trackStore.GetTrack(c.Request.Context(), workoutID, trackID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Track not found"})
+			return
+		}
+
+		logger.LogError("Failed to get track: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get track"})
+		return
+	}
+
+	c.JSON(http.StatusOK, newTrackResponse(track))
+}
@@ -0,0 +1,846 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/soa-rs/fit/internal/config/logger"
+	"github.com/soa-rs/fit/internal/progression"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// -------------------- Workout Handlers (Milestone 4) --------------------
+
+// prepareWorkout checks a new workout of the authenticated user and
+// fills in its defaults. It writes the error response and returns false
+// if the workout cannot be created.
+func prepareWorkout(c *gin.Context, workout *store.Workout) bool {
+	// Workouts always belong to the authenticated user
+	workout.UserID = currentUser(c).ID
+
+	// Workouts are logged after the fact unless they are planned or
+	// started right away
+	switch workout.Status {
+	case "":
+		workout.Status = store.WorkoutCompleted
+		fallthrough
+	case store.WorkoutCompleted:
+		if workout.StartedAt != nil && workout.FinishedAt != nil && workout.FinishedAt.Before(*workout.StartedAt) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Finished at must not be before started at"})
+			return false
+		}
+	case store.WorkoutPlanned:
+		workout.StartedAt = nil
+		workout.FinishedAt = nil
+	case store.WorkoutInProgress:
+		now := time.Now()
+		workout.StartedAt = &now
+		workout.FinishedAt = nil
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Status must be one of planned, in_progress or completed"})
+		return false
+	}
+
+	// Check if routine exists (if provided)
+	if workout.RoutineID > 0 {
+		owner, err := routineStore.RoutineOwner(c.Request.Context(), workout.RoutineID)
+		if errors.Is(err, store.ErrNotFound) || owner.Deleted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Routine not found"})
+			return false
+		}
+
+		// Workouts may follow the user's own routines or public ones
+		if !authorize(c, "Routine", owner, err, readAccess) {
+			return false
+		}
+	}
+
+	// If no performed_at is provided, use the start of the session or
+	// the current time
+	if workout.PerformedAt.IsZero() {
+		if workout.StartedAt != nil {
+			workout.PerformedAt = *workout.StartedAt
+		} else {
+			workout.PerformedAt = time.Now()
+		}
+	}
+	return true
+}
+
+// insertWorkout creates a workout along with its sets, all or nothing,
+// and writes the response.
+func insertWorkout(c *gin.Context, workout *store.Workout, sets []store.WorkoutSet) bool {
+	if err := workoutStore.CreateWorkout(c.Request.Context(), workout, sets); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Another workout is already in progress"})
+			return false
+		}
+
+		logger.LogError("Failed to create workout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+		return false
+	}
+
+	setElapsed(workout)
+	return true
+}
+
+func createWorkout(c *gin.Context) {
+	var workout store.Workout
+	if err := c.ShouldBindJSON(&workout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !prepareWorkout(c, &workout) {
+		return
+	}
+
+	// If workout is based on a routine, plan its sets from the targets
+	// of each exercise's progression
+	sets := []store.WorkoutSet{}
+	if workout.RoutineID > 0 {
+		ctx := c.Request.Context()
+		targets, err := nextTargets(ctx, workout.UserID, workout.RoutineID)
+		if err != nil {
+			logger.LogError("Failed to get routine targets: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+			return
+		}
+
+		// Public routines may use custom exercises of their author,
+		// which others can neither see nor log sets for
+		targets, err = visibleTargets(ctx, currentUser(c), targets)
+		if err != nil {
+			logger.LogError("Failed to check if exercise exists: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+			return
+		}
+
+		groups, err := routineStore.ListRoutineGroups(ctx, workout.RoutineID)
+		if err != nil {
+			logger.LogError("Failed to get routine groups: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+			return
+		}
+		sets = planSets(targets, groups)
+	}
+
+	if !insertWorkout(c, &workout, sets) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, store.WorkoutWithSets{Workout: workout, Sets: sets})
+}
+
+// createFullWorkout creates a workout along with every one of its sets,
+// such as a session logged offline, all or nothing.
+func createFullWorkout(c *gin.Context) {
+	var request store.WorkoutWithSets
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	workout := request.Workout
+	sets := request.Sets
+	if sets == nil {
+		sets = []store.WorkoutSet{}
+	}
+
+	if !prepareWorkout(c, &workout) {
+		return
+	}
+
+	// Sets may belong to the exercises and groups of the routine the
+	// workout followed
+	ctx := c.Request.Context()
+	routineExercises := map[int]store.RoutineExercise{}
+	if workout.RoutineID > 0 {
+		exercises, err := routineStore.ListRoutineExercises(ctx, workout.RoutineID)
+		if err != nil {
+			logger.LogError("Failed to get routine exercises: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+			return
+		}
+		for _, exercise := range exercises {
+			routineExercises[exercise.ID] = exercise.RoutineExercise
+		}
+	}
+
+	// Validation. Sets are numbered here, as the store would number
+	// them, so that only another workout in progress can conflict.
+	exerciseTypes := map[int]string{}
+	last := map[int]int{}
+	taken := map[[2]int]bool{}
+	for i := range sets {
+		set := &sets[i]
+		invalid := func(message string) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Set %d: %s", i+1, message)})
+		}
+
+		if set.ExerciseID <= 0 {
+			invalid("Exercise ID is required")
+			return
+		}
+
+		// Check if exercise exists and the user may use it
+		exerciseType, ok := exerciseTypes[set.ExerciseID]
+		if !ok {
+			exercise, err := getVisibleExercise(ctx, currentUser(c), set.ExerciseID)
+			if err != nil {
+				if errors.Is(err, store.ErrNotFound) {
+					invalid("Exercise not found")
+					return
+				}
+
+				logger.LogError("Failed to check if exercise exists: %v", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+				return
+			}
+			exerciseType = exercise.ExerciseType
+			exerciseTypes[set.ExerciseID] = exerciseType
+		}
+
+		// Validate based on exercise type
+		if err := validateWorkoutSet(set, exerciseType); err != nil {
+			invalid(err.Error())
+			return
+		}
+
+		if set.RoutineExerciseID != nil {
+			routineExercise, ok := routineExercises[*set.RoutineExerciseID]
+			if !ok || routineExercise.ExerciseID != set.ExerciseID {
+				invalid("Routine exercise not found in routine")
+				return
+			}
+			set.GroupID = routineExercise.GroupID
+		} else {
+			set.GroupID = nil
+		}
+		if set.GroupID == nil || set.Round < 0 {
+			set.Round = 0
+		}
+
+		if set.SetIndex == 0 {
+			set.SetIndex = last[set.ExerciseID] + 1
+		}
+		key := [2]int{set.ExerciseID, set.SetIndex}
+		if taken[key] {
+			invalid("Set index is already taken for this exercise")
+			return
+		}
+		taken[key] = true
+		last[set.ExerciseID] = max(last[set.ExerciseID], set.SetIndex)
+	}
+
+	if !insertWorkout(c, &workout, sets) {
+		return
+	}
+
+	for i := range sets {
+		detectRecords(c, &sets[i])
+	}
+
+	c.JSON(http.StatusCreated, store.WorkoutWithSets{Workout: workout, Sets: sets})
+}
+
+// visibleTargets drops the targets of exercises the user may not use.
+func visibleTargets(ctx context.Context, user store.User, targets []progression.Target) ([]progression.Target, error) {
+	visible := map[int]bool{}
+	kept := []progression.Target{}
+	for _, target := range targets {
+		ok, checked := visible[target.ExerciseID]
+		if !checked {
+			_, err := getVisibleExercise(ctx, user, target.ExerciseID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				return nil, err
+			}
+			ok = err == nil
+			visible[target.ExerciseID] = ok
+		}
+		if ok {
+			kept = append(kept, target)
+		}
+	}
+	return kept, nil
+}
+
+// planSets plans the sets of a workout from the targets of its routine,
+// in the order of the routine. Each exercise gets one set per target
+// set, and at least one so that every exercise of the routine shows up.
+// The exercises of a superset or circuit take turns, round by round,
+// where the first of them stands; without a number of rounds each
+// exercise stops after its own sets.
+func planSets(targets []progression.Target, groups []store.RoutineGroup) []store.WorkoutSet {
+	groupOf := map[int]store.RoutineGroup{}
+	for _, group := range groups {
+		for _, id := range group.RoutineExerciseIDs {
+			groupOf[id] = group
+		}
+	}
+	byRoutineExercise := map[int]progression.Target{}
+	for _, target := range targets {
+		byRoutineExercise[target.RoutineExerciseID] = target
+	}
+
+	// Set indexes are left to the store, as an exercise may come up more
+	// than once
+	plan := func(target progression.Target) store.WorkoutSet {
+		routineExerciseID := target.RoutineExerciseID
+		return store.WorkoutSet{
+			ExerciseID:        target.ExerciseID,
+			SetType:           store.SetWorking,
+			Reps:              target.Reps,
+			Weight:            target.Weight,
+			RPE:               target.RPE,
+			Duration:          target.Duration,
+			Distance:          target.Distance,
+			RoutineExerciseID: &routineExerciseID,
+		}
+	}
+
+	sets := []store.WorkoutSet{}
+	planned := map[int]bool{}
+	for _, target := range targets {
+		group, grouped := groupOf[target.RoutineExerciseID]
+		if !grouped {
+			for i := 0; i < max(target.Sets, 1); i++ {
+				sets = append(sets, plan(target))
+			}
+			continue
+		}
+		if planned[group.ID] {
+			continue
+		}
+		planned[group.ID] = true
+
+		rounds := group.Rounds
+		if rounds == 0 {
+			for _, id := range group.RoutineExerciseIDs {
+				rounds = max(rounds, byRoutineExercise[id].Sets, 1)
+			}
+		}
+		for round := 1; round <= rounds; round++ {
+			for _, id := range group.RoutineExerciseIDs {
+				member, ok := byRoutineExercise[id]
+				if !ok || group.Rounds == 0 && round > max(member.Sets, 1) {
+					continue
+				}
+				groupID := group.ID
+				set := plan(member)
+				set.GroupID = &groupID
+				set.Round = round
+				sets = append(sets, set)
+			}
+		}
+	}
+	return sets
+}
+
+func getWorkoutByID(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Workouts are only visible to their owner
+	if !authorizeWorkout(c, id, readAccess) {
+		return
+	}
+
+	workout, err := workoutStore.GetWorkout(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+			return
+		}
+
+		logger.LogError("Failed to get workout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workout"})
+		return
+	}
+	setElapsed(&workout)
+
+	// Get routine details if a routine was used
+	if workout.RoutineID > 0 {
+		routine, err := routineStore.GetRoutine(c.Request.Context(), workout.RoutineID)
+		if err == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"workout":      workout,
+				"routine_name": routine.Name,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, workout)
+}
+
+func listWorkouts(c *gin.Context) {
+	page, ok := getListPage(c, store.WorkoutSortFields, store.DefaultWorkoutSort)
+	if !ok {
+		return
+	}
+
+	filter := store.WorkoutFilter{
+		Page:   page,
+		UserID: currentUser(c).ID,
+		Status: c.Query("status"),
+	}
+
+	switch filter.Status {
+	case "", store.WorkoutPlanned, store.WorkoutInProgress, store.WorkoutCompleted, store.WorkoutAbandoned:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+
+	workouts, info, err := workoutStore.ListWorkouts(c.Request.Context(), filter)
+	if err != nil {
+		// Cursors are opaque, but may still be forged
+		if errors.Is(err, store.ErrPrecondition) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+
+		logger.LogError("Failed to list workouts: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workouts"})
+		return
+	}
+
+	for i := range workouts {
+		setElapsed(&workouts[i].Workout)
+	}
+
+	c.JSON(http.StatusOK, listResponse(workouts, info, filter.Page))
+}
+
+func updateWorkout(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, id, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var workout store.Workout
+	if err := c.ShouldBindJSON(&workout); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	workout.ID = id
+
+	// Validation
+	if workout.PerformedAt.IsZero() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Performed at is required"})
+		return
+	}
+
+	// Check if routine exists (if provided)
+	if workout.RoutineID > 0 {
+		existing, err := workoutStore.GetWorkout(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+				return
+			}
+
+			logger.LogError("Failed to get workout: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout"})
+			return
+		}
+
+		owner, err := routineStore.RoutineOwner(c.Request.Context(), workout.RoutineID)
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Routine not found"})
+			return
+		}
+
+		// Deleted routines are only kept for the workouts that already
+		// followed them
+		mode := useAccess
+		if existing.RoutineID == workout.RoutineID {
+			mode = readAccess
+		}
+		if !authorize(c, "Routine", owner, err, mode) {
+			return
+		}
+	}
+
+	if err := workoutStore.UpdateWorkout(c.Request.Context(), &workout); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+			return
+		}
+
+		logger.LogError("Failed to update workout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout"})
+		return
+	}
+
+	setElapsed(&workout)
+	c.JSON(http.StatusOK, workout)
+}
+
+func deleteWorkout(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, id, deleteAccess(c)) {
+		return
+	}
+
+	// Soft deleted workouts no longer count towards history and records
+	if !hardDelete(c) {
+		setDeleted(c, "Workout", id, true, workoutStore.SetWorkoutDeleted)
+		return
+	}
+
+	// Deletes the workout along with its sets
+	if err := workoutStore.DeleteWorkout(c.Request.Context(), id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+			return
+		}
+
+		logger.LogError("Failed to delete workout: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workout deleted successfully"})
+}
+
+func restoreWorkout(c *gin.Context) {
+	id, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	if !authorizeWorkout(c, id, ownerAccess) {
+		return
+	}
+
+	setDeleted(c, "Workout", id, false, workoutStore.SetWorkoutDeleted)
+}
+
+// -------------------- Workout Session Handlers --------------------
+
+// setElapsed fills in the elapsed time of the workout's session.
+func setElapsed(workout *store.Workout) {
+	workout.ElapsedSeconds = int(workout.Elapsed(time.Now()).Seconds())
+}
+
+// sessionAction describes one of the session endpoints.
+type sessionAction struct {
+	verb string
+	// from lists the statuses the workout may be in beforehand
+	from []string
+	to   string
+}
+
+var (
+	startSession   = sessionAction{"start", []string{store.WorkoutPlanned}, store.WorkoutInProgress}
+	finishSession  = sessionAction{"finish", []string{store.WorkoutInProgress}, store.WorkoutCompleted}
+	abandonSession = sessionAction{"abandon", []string{store.WorkoutPlanned, store.WorkoutInProgress}, store.WorkoutAbandoned}
+)
+
+// sessionHandler returns the handler that applies the action to the
+// workout's session.
+func sessionHandler(action sessionAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := parseIDParam(c, "id", "Workout")
+		if !ok {
+			return
+		}
+
+		// Only the owner may run a workout's session
+		if !authorizeWorkout(c, id, writeAccess) {
+			return
+		}
+
+		workout, err := workoutStore.GetWorkout(c.Request.Context(), id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+				return
+			}
+
+			logger.LogError("Failed to get workout: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workout"})
+			return
+		}
+
+		// Check if the workout is in a status the action applies to
+		from := workout.Status
+		if !slices.Contains(action.from, from) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": fmt.Sprintf("Cannot %s a workout that is %s", action.verb, strings.ReplaceAll(from, "_", " ")),
+			})
+			return
+		}
+
+		now := time.Now()
+		workout.Status = action.to
+		if action.to == store.WorkoutInProgress {
+			workout.StartedAt = &now
+			workout.PerformedAt = now
+		} else {
+			workout.FinishedAt = &now
+		}
+
+		if err := workoutStore.SetWorkoutStatus(c.Request.Context(), &workout, from); err != nil {
+			switch {
+			case errors.Is(err, store.ErrConflict):
+				c.JSON(http.StatusConflict, gin.H{"error": "Another workout is already in progress"})
+			case errors.Is(err, store.ErrPrecondition):
+				c.JSON(http.StatusConflict, gin.H{"error": "Workout status changed concurrently, try again"})
+			default:
+				logger.LogError("Failed to %s workout: %v", action.verb, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to %s workout", action.verb)})
+			}
+			return
+		}
+
+		setElapsed(&workout)
+		c.JSON(http.StatusOK, workout)
+	}
+}
+
+// -------------------- Workout Set Handlers (Milestone 4) --------------------
+
+// validateWorkoutSet checks the type of a set and of its exercise and,
+// once it is completed, that it records what its exercise type
+// requires. An empty set type defaults to a working set.
+func validateWorkoutSet(set *store.WorkoutSet, exerciseType string) error {
+	switch set.SetType {
+	case "":
+		set.SetType = store.SetWorking
+	case store.SetWarmup, store.SetWorking, store.SetDropset, store.SetFailure, store.SetAMRAP:
+	default:
+		return errors.New("Set type must be one of warmup, working, dropset, failure or amrap")
+	}
+
+	if set.SetIndex < 0 {
+		return errors.New("Set index must not be negative")
+	}
+
+	switch exerciseType {
+	case "weight_reps", "duration_only", "distance_time":
+	default:
+		return fmt.Errorf("Sets cannot be logged for exercise type %s", exerciseType)
+	}
+
+	// Planned sets are filled in as they are performed
+	if !set.Completed {
+		return nil
+	}
+
+	switch exerciseType {
+	case "weight_reps":
+		if set.Reps <= 0 {
+			return errors.New("Reps must be greater than 0 for weight_reps exercise")
+		}
+	case "duration_only":
+		if set.Duration <= 0 {
+			return errors.New("Duration must be greater than 0 for duration_only exercise")
+		}
+	case "distance_time":
+		if set.Distance <= 0 {
+			return errors.New("Distance must be greater than 0 for distance_time exercise")
+		}
+		if set.Duration <= 0 {
+			return errors.New("Duration must be greater than 0 for distance_time exercise")
+		}
+	}
+	return nil
+}
+
+func addWorkoutSet(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, workoutID, writeAccess) {
+		return
+	}
+
+	// Parse request body
+	var workoutSet store.WorkoutSet
+	if err := c.ShouldBindJSON(&workoutSet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set workout ID from path parameter. Only sets planned from a
+	// routine belong to its exercises and groups.
+	workoutSet.WorkoutID = workoutID
+	workoutSet.RoutineExerciseID = nil
+	workoutSet.GroupID = nil
+	workoutSet.Round = 0
+
+	// Validation
+	if workoutSet.ExerciseID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise ID is required"})
+		return
+	}
+
+	// Check if exercise exists and the user may use it
+	exercise, err := getVisibleExercise(c.Request.Context(), currentUser(c), workoutSet.ExerciseID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Exercise not found"})
+			return
+		}
+
+		logger.LogError("Failed to check if exercise exists: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	// Validate based on exercise type
+	if err := validateWorkoutSet(&workoutSet, exercise.ExerciseType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := workoutStore.AddWorkoutSet(c.Request.Context(), &workoutSet); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Set index is already taken for this exercise"})
+			return
+		}
+
+		logger.LogError("Failed to add workout set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add workout set"})
+		return
+	}
+
+	detectRecords(c, &workoutSet)
+
+	c.JSON(http.StatusCreated, workoutSet)
+}
+
+func getWorkoutSets(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Check if the workout exists and the user may access it
+	if !authorizeWorkout(c, workoutID, readAccess) {
+		return
+	}
+
+	sets, err := workoutStore.ListWorkoutSets(c.Request.Context(), workoutID)
+	if err != nil {
+		logger.LogError("Failed to get workout sets: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get workout sets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sets)
+}
+
+func updateWorkoutSet(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a workout's sets
+	if !authorizeWorkout(c, workoutID, writeAccess) {
+		return
+	}
+
+	setID, ok := parseIDParam(c, "setId", "Workout set")
+	if !ok {
+		return
+	}
+
+	// Parse request body
+	var workoutSet store.WorkoutSet
+	if err := c.ShouldBindJSON(&workoutSet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	workoutSet.ID = setID
+	workoutSet.WorkoutID = workoutID
+
+	// Validate based on the type of the set's exercise
+	existing, err := workoutStore.GetWorkoutSet(c.Request.Context(), workoutID, setID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout set not found"})
+			return
+		}
+
+		logger.LogError("Failed to get workout set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Database error"})
+		return
+	}
+
+	if err := validateWorkoutSet(&workoutSet, existing.ExerciseType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := workoutStore.UpdateWorkoutSet(c.Request.Context(), &workoutSet); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout set not found"})
+			return
+		}
+
+		logger.LogError("Failed to update workout set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout set"})
+		return
+	}
+
+	detectRecords(c, &workoutSet)
+
+	c.JSON(http.StatusOK, workoutSet)
+}
+
+func deleteWorkoutSet(c *gin.Context) {
+	workoutID, ok := parseIDParam(c, "id", "Workout")
+	if !ok {
+		return
+	}
+
+	// Only the owner may change a workout's sets
+	if !authorizeWorkout(c, workoutID, writeAccess) {
+		return
+	}
+
+	setID, ok := parseIDParam(c, "setId", "Workout set")
+	if !ok {
+		return
+	}
+
+	if err := workoutStore.DeleteWorkoutSet(c.Request.Context(), workoutID, setID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workout set not found"})
+			return
+		}
+
+		logger.LogError("Failed to delete workout set: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workout set"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workout set deleted successfully"})
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MinPasswordLength is the minimum number of bytes a password must
+	// contain.
+	MinPasswordLength = 8
+	// MaxPasswordLength is the maximum number of bytes a password may
+	// contain. bcrypt silently ignores everything past 72 bytes, so we
+	// reject longer passwords instead of truncating them.
+	MaxPasswordLength = 72
+)
+
+var (
+	// ErrPasswordTooShort is returned when a password is shorter than
+	// MinPasswordLength.
+	ErrPasswordTooShort = errors.New("password must be at least 8 characters long")
+	// ErrPasswordTooLong is returned when a password is longer than
+	// MaxPasswordLength.
+	ErrPasswordTooLong = errors.New("password must be at most 72 bytes long")
+)
+
+// ValidatePassword checks that a password is acceptable for hashing.
+func ValidatePassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if len(password) > MaxPasswordLength {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// HashPassword hashes a password with bcrypt.
+func HashPassword(password string) (string, error) {
+	if err := ValidatePassword(password); err != nil {
+		return "", err
+	}
+	hash, err := bcrypt.GenerateFromPassword(
+		[]byte(password), bcrypt.DefaultCost,
+	)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword reports whether the password matches the bcrypt hash.
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword(
+		[]byte(hash), []byte(password),
+	) == nil
+}
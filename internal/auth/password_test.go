@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"empty", "", ErrPasswordTooShort},
+		{"too short", "1234567", ErrPasswordTooShort},
+		{"shortest", "12345678", nil},
+		{"longest", strings.Repeat("a", MaxPasswordLength), nil},
+		{"too long", strings.Repeat("a", MaxPasswordLength+1), ErrPasswordTooLong},
+		// Lengths are counted in bytes, which bcrypt is limited to
+		{"too long in bytes", strings.Repeat("é", MaxPasswordLength/2+1), ErrPasswordTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidatePassword(tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("ValidatePassword() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("password1")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if hash == "password1" {
+		t.Fatal("HashPassword() returned the password")
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     bool
+	}{
+		{"same password", "password1", true},
+		{"other password", "password2", false},
+		{"different case", "Password1", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CheckPassword(hash, tt.password); got != tt.want {
+				t.Errorf("CheckPassword() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashPasswordRejectsInvalidPasswords(t *testing.T) {
+	if _, err := HashPassword("short"); !errors.Is(err, ErrPasswordTooShort) {
+		t.Errorf("HashPassword() error = %v, want %v", err, ErrPasswordTooShort)
+	}
+}
+
+func TestHashPasswordSalts(t *testing.T) {
+	first, err := HashPassword("password1")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	second, err := HashPassword("password1")
+	if err != nil {
+		t.Fatalf("HashPassword() error = %v", err)
+	}
+	if first == second {
+		t.Error("HashPassword() returned the same hash twice")
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestSigner returns a Signer whose clock is at the time *now.
+func newTestSigner(secret string, now *time.Time) *Signer {
+	signer := NewSigner([]byte(secret), 15*time.Minute, 24*time.Hour)
+	signer.now = func() time.Time { return *now }
+	return signer
+}
+
+func TestIssueAndVerify(t *testing.T) {
+	now := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+	signer := newTestSigner("secret", &now)
+
+	tests := []struct {
+		kind TokenKind
+		ttl  time.Duration
+	}{
+		{AccessToken, 15 * time.Minute},
+		{RefreshToken, 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.kind), func(t *testing.T) {
+			token, issued, err := signer.Issue(42, tt.kind)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			if want := now.Add(tt.ttl); !issued.Expiry().Equal(want) {
+				t.Errorf("Issue() expiry = %v, want %v", issued.Expiry(), want)
+			}
+
+			claims, err := signer.Verify(token, tt.kind)
+			if err != nil {
+				t.Fatalf("Verify() error = %v", err)
+			}
+			if claims != issued {
+				t.Errorf("Verify() = %+v, want %+v", claims, issued)
+			}
+		})
+	}
+}
+
+func TestIssueUsesUniqueIDs(t *testing.T) {
+	now := time.Now()
+	signer := newTestSigner("secret", &now)
+
+	_, first, err := signer.Issue(1, RefreshToken)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	_, second, err := signer.Issue(1, RefreshToken)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("Issue() returned the ID %q twice", first.ID)
+	}
+}
+
+func TestVerifyExpiry(t *testing.T) {
+	issuedAt := time.Date(2024, 1, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    error
+	}{
+		{"fresh", 0, nil},
+		{"just before expiry", 15*time.Minute - time.Second, nil},
+		{"at expiry", 15 * time.Minute, ErrExpiredToken},
+		{"long expired", 48 * time.Hour, ErrExpiredToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := issuedAt
+			signer := newTestSigner("secret", &now)
+			token, _, err := signer.Issue(1, AccessToken)
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+
+			now = issuedAt.Add(tt.elapsed)
+			if _, err := signer.Verify(token, AccessToken); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	now := time.Now()
+	signer := newTestSigner("secret", &now)
+	access, _, err := signer.Issue(1, AccessToken)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	other, _, err := newTestSigner("other secret", &now).Issue(1, AccessToken)
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	parts := strings.Split(access, ".")
+
+	tests := []struct {
+		name  string
+		token string
+		kind  TokenKind
+	}{
+		{"empty", "", AccessToken},
+		{"not a token", "abc", AccessToken},
+		{"wrong kind", access, RefreshToken},
+		{"other secret", other, AccessToken},
+		{"tampered payload", parts[0] + "." + parts[1] + "x." + parts[2], AccessToken},
+		{"tampered signature", parts[0] + "." + parts[1] + "." + parts[2] + "x", AccessToken},
+		{"other header", "eyJhbGciOiJub25lIn0." + parts[1] + "." + parts[2], AccessToken},
+		{"unsigned", parts[0] + "." + parts[1] + ".", AccessToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := signer.Verify(tt.token, tt.kind); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Verify() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
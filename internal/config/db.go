@@ -0,0 +1,36 @@
+package config
+
+import "fmt"
+
+// Values of EnvBackendDBDriver.
+const (
+	// DBDriverPostgres stores everything in the Postgres database
+	// described by the DB_* environment variables.
+	DBDriverPostgres = "postgres"
+	// DBDriverMemory keeps everything in memory. Data is lost when the
+	// server stops, which makes it suited to tests and demos.
+	DBDriverMemory = "memory"
+)
+
+// Values of EnvBackendDBMigrate.
+const (
+	// DBMigrateCheck refuses to start when the schema is behind the
+	// migrations embedded in the binary.
+	DBMigrateCheck = "check"
+	// DBMigrateAuto applies pending migrations at startup.
+	DBMigrateAuto = "auto"
+	// DBMigrateOff skips the schema check entirely.
+	DBMigrateOff = "off"
+)
+
+// PostgresConnString returns the connection string for the Postgres
+// database, built from the DB_* environment variables.
+func PostgresConnString() string {
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		GetEnvOrDefault("DB_HOST"),
+		GetEnvOrDefault("DB_PORT"),
+		GetEnvOrDefault("DB_USER"),
+		GetEnvOrDefault("DB_PASSWORD"),
+		GetEnvOrDefault("DB_NAME"),
+	)
+}
@@ -19,6 +19,15 @@ const (
 	EnvBackendLogFormat = EnvBackendPrefix + "LOG_FORMAT"
 	EnvBackendLogFile   = EnvBackendPrefix + "LOG_FILE"
 	EnvBackendLogOutput = EnvBackendPrefix + "LOG_OUTPUT"
+
+	EnvBackendAuthSecret      = EnvBackendPrefix + "AUTH_SECRET"
+	EnvBackendAccessTokenTTL  = EnvBackendPrefix + "ACCESS_TOKEN_TTL"
+	EnvBackendRefreshTokenTTL = EnvBackendPrefix + "REFRESH_TOKEN_TTL"
+	EnvBackendAdminEmails     = EnvBackendPrefix + "ADMIN_EMAILS"
+	EnvBackendIdempotencyTTL  = EnvBackendPrefix + "IDEMPOTENCY_TTL"
+
+	EnvBackendDBDriver  = EnvBackendPrefix + "DB_DRIVER"
+	EnvBackendDBMigrate = EnvBackendPrefix + "DB_MIGRATE"
 )
 
 // Default values
@@ -44,6 +53,26 @@ const (
 	DefaultLogOutput = "console"
 	// DefaultLogFile is the default log file for the server.
 	DefaultLogFile = "backend.log"
+	// DefaultAuthSecret is the default secret used to sign tokens.
+	// It is empty on purpose: production deployments must provide
+	// their own, and other profiles fall back to an ephemeral one.
+	DefaultAuthSecret = ""
+	// DefaultAccessTokenTTL is the default lifetime of access tokens.
+	DefaultAccessTokenTTL = "15m"
+	// DefaultRefreshTokenTTL is the default lifetime of refresh tokens.
+	DefaultRefreshTokenTTL = "720h"
+	// DefaultAdminEmails is the default comma-separated list of emails
+	// of users who are admins on top of those flagged in the database.
+	DefaultAdminEmails = ""
+	// DefaultIdempotencyTTL is the default time the responses to
+	// requests made with an Idempotency-Key are replayed for.
+	DefaultIdempotencyTTL = "24h"
+	// DefaultDBDriver is the default storage backend. See the
+	// DBDriver* constants.
+	DefaultDBDriver = DBDriverPostgres
+	// DefaultDBMigrate is the default way the server treats pending
+	// schema migrations at startup. See the DBMigrate* constants.
+	DefaultDBMigrate = DBMigrateCheck
 )
 
 // Defaults is a map of environment variables to their default values.
@@ -57,6 +86,15 @@ var (
 		EnvBackendLogFormat: DefaultLogFormat,
 		EnvBackendLogFile:   DefaultLogFile,
 		EnvBackendLogOutput: DefaultLogOutput,
+
+		EnvBackendAuthSecret:      DefaultAuthSecret,
+		EnvBackendAccessTokenTTL:  DefaultAccessTokenTTL,
+		EnvBackendRefreshTokenTTL: DefaultRefreshTokenTTL,
+		EnvBackendAdminEmails:     DefaultAdminEmails,
+		EnvBackendIdempotencyTTL:  DefaultIdempotencyTTL,
+
+		EnvBackendDBDriver:  DefaultDBDriver,
+		EnvBackendDBMigrate: DefaultDBMigrate,
 	}
 )
 
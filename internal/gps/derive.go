@@ -0,0 +1,136 @@
+package gps
+
+import (
+	"math"
+
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+// earthRadius is the mean radius of the Earth, in metres.
+const earthRadius = 6371008.8
+
+// Summary is what a track adds up to. Distance is in kilometres and
+// Duration in seconds, like those of a workout set, and ElevationGain
+// is in metres.
+type Summary struct {
+	Distance      float64 `json:"distance"`
+	Duration      int     `json:"duration"`
+	ElevationGain float64 `json:"elevation_gain"`
+	// Pace is in seconds per kilometre.
+	Pace float64 `json:"pace"`
+}
+
+// Split is a kilometre of a track. The last split is usually shorter.
+type Split struct {
+	Kilometre     int     `json:"kilometre"`
+	Distance      float64 `json:"distance"`
+	Duration      int     `json:"duration"`
+	Pace          float64 `json:"pace"`
+	ElevationGain float64 `json:"elevation_gain"`
+	// HeartRate is the average heart rate over the split, if recorded.
+	HeartRate int `json:"heart_rate,omitempty"`
+}
+
+// distance returns the great-circle distance between two points, in
+// metres.
+func distance(a, b store.TrackPoint) float64 {
+	lat1, lat2 := a.Latitude*math.Pi/180, b.Latitude*math.Pi/180
+	dLat := lat2 - lat1
+	dLon := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// climb returns the elevation gained between two points, in metres.
+func climb(a, b store.TrackPoint) float64 {
+	if a.Elevation == nil || b.Elevation == nil {
+		return 0
+	}
+	return math.Max(0, *b.Elevation-*a.Elevation)
+}
+
+// elapsed returns the seconds between two points, or 0 if either has no
+// time.
+func elapsed(a, b store.TrackPoint) float64 {
+	if a.Time == nil || b.Time == nil {
+		return 0
+	}
+	return math.Max(0, b.Time.Sub(*a.Time).Seconds())
+}
+
+// Summarize adds up a track. Tracks without times have no duration.
+func Summarize(points []store.TrackPoint) Summary {
+	var summary Summary
+	var metres, seconds float64
+	for i := 1; i < len(points); i++ {
+		metres += distance(points[i-1], points[i])
+		seconds += elapsed(points[i-1], points[i])
+		summary.ElevationGain += climb(points[i-1], points[i])
+	}
+
+	summary.Distance = round(metres/1000, 3)
+	summary.Duration = int(math.Round(seconds))
+	summary.ElevationGain = round(summary.ElevationGain, 1)
+	summary.Pace = round(training.Pace(summary.Duration, summary.Distance), 1)
+	return summary
+}
+
+// Splits divides a track into kilometres. Where a kilometre ends
+// between two points, the time and elevation of the stretch between
+// them are shared in proportion to its distance.
+func Splits(points []store.TrackPoint) []Split {
+	splits := []Split{}
+	current := Split{Kilometre: 1}
+	var metres, seconds, heartRates, heartRateSamples float64
+
+	finish := func() {
+		current.Distance = round(metres/1000, 3)
+		current.Duration = int(math.Round(seconds))
+		current.Pace = round(training.Pace(current.Duration, current.Distance), 1)
+		current.ElevationGain = round(current.ElevationGain, 1)
+		if heartRateSamples > 0 {
+			current.HeartRate = int(math.Round(heartRates / heartRateSamples))
+		}
+		splits = append(splits, current)
+	}
+
+	for i := 1; i < len(points); i++ {
+		step := distance(points[i-1], points[i])
+		stepSeconds := elapsed(points[i-1], points[i])
+		stepClimb := climb(points[i-1], points[i])
+
+		// A step can close several kilometres on a sparse track
+		for step > 0 && metres+step >= 1000 {
+			share := (1000 - metres) / step
+			metres = 1000
+			seconds += stepSeconds * share
+			current.ElevationGain += stepClimb * share
+			finish()
+
+			step, stepSeconds, stepClimb = step*(1-share), stepSeconds*(1-share), stepClimb*(1-share)
+			current = Split{Kilometre: current.Kilometre + 1}
+			metres, seconds, heartRates, heartRateSamples = 0, 0, 0, 0
+		}
+
+		metres += step
+		seconds += stepSeconds
+		current.ElevationGain += stepClimb
+		if points[i].HeartRate != nil {
+			heartRates += float64(*points[i].HeartRate)
+			heartRateSamples++
+		}
+	}
+
+	// Keep the last stretch unless it is a rounding leftover
+	if metres >= 1 {
+		finish()
+	}
+	return splits
+}
+
+func round(value float64, decimals int) float64 {
+	scale := math.Pow(10, float64(decimals))
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,107 @@
+package gps
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// start is when the test tracks were recorded.
+var start = time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+
+// north returns a point the given metres north of the equator, reached
+// after the given seconds, at an elevation and heart rate.
+func north(metres float64, seconds int, elevation float64, heartRate int) store.TrackPoint {
+	at := start.Add(time.Duration(seconds) * time.Second)
+	return store.TrackPoint{
+		Latitude:  metres / (earthRadius * math.Pi / 180),
+		Elevation: &elevation,
+		Time:      &at,
+		HeartRate: &heartRate,
+	}
+}
+
+// run is a 2.5 km run at 200 s/km.
+var run = []store.TrackPoint{
+	north(0, 0, 10, 100),
+	north(400, 80, 20, 120),
+	north(1800, 360, 15, 140),
+	north(2500, 500, 25, 160),
+}
+
+func TestSummarize(t *testing.T) {
+	untimed := make([]store.TrackPoint, len(run))
+	for i, point := range run {
+		point.Time = nil
+		point.Elevation = nil
+		untimed[i] = point
+	}
+
+	tests := []struct {
+		name   string
+		points []store.TrackPoint
+		want   Summary
+	}{
+		{"run", run, Summary{Distance: 2.5, Duration: 500, ElevationGain: 20, Pace: 200}},
+		{"without times or elevations", untimed, Summary{Distance: 2.5}},
+		{"single point", run[:1], Summary{}},
+		{"no points", nil, Summary{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.points); got != tt.want {
+				t.Errorf("Summarize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplits(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []store.TrackPoint
+		want   []Split
+	}{
+		{
+			name:   "run",
+			points: run,
+			// Kilometres ending between two points share the stretch
+			want: []Split{
+				{Kilometre: 1, Distance: 1, Duration: 200, Pace: 200, ElevationGain: 10, HeartRate: 120},
+				{Kilometre: 2, Distance: 1, Duration: 200, Pace: 200, ElevationGain: 2.9, HeartRate: 140},
+				{Kilometre: 3, Distance: 0.5, Duration: 100, Pace: 200, ElevationGain: 7.1, HeartRate: 160},
+			},
+		},
+		{
+			name:   "sparse track",
+			points: []store.TrackPoint{north(0, 0, 0, 100), north(2500, 500, 0, 150)},
+			want: []Split{
+				{Kilometre: 1, Distance: 1, Duration: 200, Pace: 200},
+				{Kilometre: 2, Distance: 1, Duration: 200, Pace: 200},
+				{Kilometre: 3, Distance: 0.5, Duration: 100, Pace: 200, HeartRate: 150},
+			},
+		},
+		{
+			name:   "shorter than a kilometre",
+			points: run[:2],
+			want:   []Split{{Kilometre: 1, Distance: 0.4, Duration: 80, Pace: 200, ElevationGain: 10, HeartRate: 120}},
+		},
+		{
+			name:   "no points",
+			points: nil,
+			want:   []Split{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Splits(tt.points); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Splits() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
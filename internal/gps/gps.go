@@ -0,0 +1,158 @@
+// Package gps reads GPS recordings of runs, rides and other cardio
+// sessions from GPX and TCX files, and derives the distance, duration,
+// elevation gain and per-kilometre splits of their tracks.
+package gps
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Track formats
+const (
+	GPX = "gpx"
+	TCX = "tcx"
+)
+
+// ErrUnknownFormat is returned for files that are neither GPX nor TCX.
+var ErrUnknownFormat = errors.New("gps: file is neither GPX nor TCX")
+
+// Recording is a track read from a file.
+type Recording struct {
+	Format string
+	Name   string
+	Points []store.TrackPoint
+}
+
+type gpxFile struct {
+	Tracks []struct {
+		Name     string `xml:"name"`
+		Segments []struct {
+			Points []struct {
+				Latitude  float64    `xml:"lat,attr"`
+				Longitude float64    `xml:"lon,attr"`
+				Elevation *float64   `xml:"ele"`
+				Time      *time.Time `xml:"time"`
+				// Heart rates come from the Garmin extension
+				HeartRate *int `xml:"extensions>TrackPointExtension>hr"`
+			} `xml:"trkpt"`
+		} `xml:"trkseg"`
+	} `xml:"trk"`
+}
+
+type tcxFile struct {
+	Activities []struct {
+		ID   string `xml:"Id"`
+		Laps []struct {
+			Points []struct {
+				Time      *time.Time `xml:"Time"`
+				Latitude  *float64   `xml:"Position>LatitudeDegrees"`
+				Longitude *float64   `xml:"Position>LongitudeDegrees"`
+				Elevation *float64   `xml:"AltitudeMeters"`
+				HeartRate *int       `xml:"HeartRateBpm>Value"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// Parse reads a GPX or TCX file, telling them apart by their root
+// element. The segments and laps of the file are joined into a single
+// track; TCX points without a position, recorded indoors, are left out.
+func Parse(r io.Reader) (Recording, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Recording{}, err
+	}
+
+	root, err := rootElement(data)
+	if err != nil {
+		return Recording{}, err
+	}
+
+	var recording Recording
+	switch root {
+	case "gpx":
+		var file gpxFile
+		if err := xml.Unmarshal(data, &file); err != nil {
+			return Recording{}, fmt.Errorf("gps: invalid GPX: %w", err)
+		}
+
+		recording.Format = GPX
+		for _, track := range file.Tracks {
+			if recording.Name == "" {
+				recording.Name = track.Name
+			}
+			for _, segment := range track.Segments {
+				for _, point := range segment.Points {
+					recording.Points = append(recording.Points, store.TrackPoint{
+						Latitude:  point.Latitude,
+						Longitude: point.Longitude,
+						Elevation: point.Elevation,
+						Time:      point.Time,
+						HeartRate: point.HeartRate,
+					})
+				}
+			}
+		}
+	case "TrainingCenterDatabase":
+		var file tcxFile
+		if err := xml.Unmarshal(data, &file); err != nil {
+			return Recording{}, fmt.Errorf("gps: invalid TCX: %w", err)
+		}
+
+		recording.Format = TCX
+		for _, activity := range file.Activities {
+			if recording.Name == "" {
+				recording.Name = activity.ID
+			}
+			for _, lap := range activity.Laps {
+				for _, point := range lap.Points {
+					if point.Latitude == nil || point.Longitude == nil {
+						continue
+					}
+					recording.Points = append(recording.Points, store.TrackPoint{
+						Latitude:  *point.Latitude,
+						Longitude: *point.Longitude,
+						Elevation: point.Elevation,
+						Time:      point.Time,
+						HeartRate: point.HeartRate,
+					})
+				}
+			}
+		}
+	default:
+		return Recording{}, ErrUnknownFormat
+	}
+
+	for _, point := range recording.Points {
+		if math.Abs(point.Latitude) > 90 || math.Abs(point.Longitude) > 180 {
+			return Recording{}, fmt.Errorf("gps: invalid position %g, %g", point.Latitude, point.Longitude)
+		}
+	}
+	return recording, nil
+}
+
+// rootElement returns the local name of the root element of an XML
+// document.
+func rootElement(data []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return "", ErrUnknownFormat
+		}
+		if err != nil {
+			return "", fmt.Errorf("gps: invalid XML: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
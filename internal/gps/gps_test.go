@@ -0,0 +1,128 @@
+package gps
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testGPX = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx version="1.1" creator="test" xmlns="http://www.topografix.com/GPX/1/1"
+  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v1">
+  <trk>
+    <name>Morning Run</name>
+    <trkseg>
+      <trkpt lat="51.5" lon="-0.1">
+        <ele>12.5</ele>
+        <time>2024-01-01T07:00:00Z</time>
+        <extensions><gpxtpx:TrackPointExtension><gpxtpx:hr>120</gpxtpx:hr></gpxtpx:TrackPointExtension></extensions>
+      </trkpt>
+    </trkseg>
+    <trkseg>
+      <trkpt lat="51.501" lon="-0.1"><time>2024-01-01T07:00:30Z</time></trkpt>
+    </trkseg>
+  </trk>
+</gpx>`
+
+const testTCX = `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+  <Activities>
+    <Activity Sport="Running">
+      <Id>2024-01-01T07:00:00Z</Id>
+      <Lap>
+        <Track>
+          <Trackpoint>
+            <Time>2024-01-01T07:00:00Z</Time>
+            <Position><LatitudeDegrees>51.5</LatitudeDegrees><LongitudeDegrees>-0.1</LongitudeDegrees></Position>
+            <AltitudeMeters>12.5</AltitudeMeters>
+            <HeartRateBpm><Value>120</Value></HeartRateBpm>
+          </Trackpoint>
+          <Trackpoint>
+            <Time>2024-01-01T07:00:15Z</Time>
+            <HeartRateBpm><Value>125</Value></HeartRateBpm>
+          </Trackpoint>
+        </Track>
+      </Lap>
+      <Lap>
+        <Track>
+          <Trackpoint>
+            <Time>2024-01-01T07:00:30Z</Time>
+            <Position><LatitudeDegrees>51.501</LatitudeDegrees><LongitudeDegrees>-0.1</LongitudeDegrees></Position>
+          </Trackpoint>
+        </Track>
+      </Lap>
+    </Activity>
+  </Activities>
+</TrainingCenterDatabase>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name       string
+		file       string
+		wantFormat string
+		wantName   string
+	}{
+		{"gpx", testGPX, GPX, "Morning Run"},
+		{"tcx", testTCX, TCX, "2024-01-01T07:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recording, err := Parse(strings.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if recording.Format != tt.wantFormat || recording.Name != tt.wantName {
+				t.Errorf("Parse() = %s %q, want %s %q", recording.Format, recording.Name, tt.wantFormat, tt.wantName)
+			}
+
+			// Segments and laps are joined, and points without a
+			// position left out
+			points := recording.Points
+			if len(points) != 2 {
+				t.Fatalf("Parse() read %d points, want 2", len(points))
+			}
+			first, last := points[0], points[1]
+			if first.Latitude != 51.5 || first.Longitude != -0.1 || last.Latitude != 51.501 {
+				t.Errorf("Parse() positions = %+v, %+v", first, last)
+			}
+			if first.Elevation == nil || *first.Elevation != 12.5 || last.Elevation != nil {
+				t.Errorf("Parse() elevations = %v, %v, want 12.5 and none", first.Elevation, last.Elevation)
+			}
+			if first.HeartRate == nil || *first.HeartRate != 120 || last.HeartRate != nil {
+				t.Errorf("Parse() heart rates = %v, %v, want 120 and none", first.HeartRate, last.HeartRate)
+			}
+			if want := time.Date(2024, 1, 1, 7, 0, 30, 0, time.UTC); last.Time == nil || !last.Time.Equal(want) {
+				t.Errorf("Parse() time = %v, want %v", last.Time, want)
+			}
+		})
+	}
+}
+
+func TestParseFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr error
+	}{
+		{"empty", "", ErrUnknownFormat},
+		{"other XML", `<kml><Document/></kml>`, ErrUnknownFormat},
+		{"not XML", `{"type": "FeatureCollection"}`, ErrUnknownFormat},
+		{"invalid XML", `<gpx><trk>`, nil},
+		{"invalid latitude", `<gpx><trk><trkseg><trkpt lat="91" lon="0"/></trkseg></trk></gpx>`, nil},
+		{"invalid longitude", `<gpx><trk><trkseg><trkpt lat="0" lon="-181"/></trkseg></trk></gpx>`, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.file))
+			if err == nil {
+				t.Fatal("Parse() succeeded")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
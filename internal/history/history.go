@@ -0,0 +1,57 @@
+// Package history reads and writes workout histories as CSV, so that
+// users can take their logs elsewhere and bring the logs of other
+// trackers with them.
+//
+// Exports have one row per set, in the columns of Columns. Imports map
+// the columns of a file to the fields of a set with a Mapping, group
+// rows into workouts by date and workout name, and match the exercise
+// names of the file to exercises with a Matcher.
+package history
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Columns are the columns of an exported history, which the "fit"
+// preset reads back.
+var Columns = []string{
+	"workout_id", "date", "workout", "status",
+	"exercise", "exercise_type", "set_index", "set_type", "completed",
+	"weight", "reps", "rpe", "duration", "distance",
+}
+
+// Record returns the CSV record of a row of history. Workouts without
+// sets leave the set columns empty.
+func Record(row store.HistoryRow) []string {
+	workout := row.Workout
+	record := []string{
+		strconv.Itoa(workout.ID),
+		workout.PerformedAt.UTC().Format(time.RFC3339),
+		workout.RoutineName,
+		workout.Status,
+	}
+
+	set := row.Set
+	if set == nil {
+		return append(record, make([]string, len(Columns)-len(record))...)
+	}
+	return append(record,
+		set.ExerciseName,
+		set.ExerciseType,
+		strconv.Itoa(set.SetIndex),
+		set.SetType,
+		strconv.FormatBool(set.Completed),
+		formatFloat(set.Weight),
+		strconv.Itoa(set.Reps),
+		formatFloat(set.RPE),
+		strconv.Itoa(set.Duration),
+		formatFloat(set.Distance),
+	)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
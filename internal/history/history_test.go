@@ -0,0 +1,92 @@
+package history
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestRecord(t *testing.T) {
+	workout := store.WorkoutWithRoutineName{
+		Workout: store.Workout{
+			ID:          7,
+			PerformedAt: time.Date(2024, 1, 2, 19, 0, 0, 0, time.FixedZone("CET", 60*60)),
+			Status:      store.WorkoutCompleted,
+		},
+		RoutineName: "Push",
+	}
+
+	tests := []struct {
+		name string
+		row  store.HistoryRow
+		want []string
+	}{
+		{
+			name: "set",
+			row: store.HistoryRow{Workout: workout, Set: &store.WorkoutSetWithDetails{
+				WorkoutSet: store.WorkoutSet{
+					SetIndex: 2, SetType: store.SetWorking, Completed: true, Weight: 82.5, Reps: 5, RPE: 8.5,
+				},
+				ExerciseName: "Bench Press",
+				ExerciseType: "weight_reps",
+			}},
+			want: []string{
+				"7", "2024-01-02T18:00:00Z", "Push", "completed",
+				"Bench Press", "weight_reps", "2", "working", "true", "82.5", "5", "8.5", "0", "0",
+			},
+		},
+		{
+			name: "workout without sets",
+			row:  store.HistoryRow{Workout: workout},
+			want: []string{"7", "2024-01-02T18:00:00Z", "Push", "completed", "", "", "", "", "", "", "", "", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Record(tt.row); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Record() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportReadsBack(t *testing.T) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	w.Write(Columns)
+	w.Write(Record(store.HistoryRow{
+		Workout: store.WorkoutWithRoutineName{
+			Workout:     store.Workout{ID: 1, PerformedAt: time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)},
+			RoutineName: "Run",
+		},
+		Set: &store.WorkoutSetWithDetails{
+			WorkoutSet:   store.WorkoutSet{SetType: store.SetWorking, Completed: true, Duration: 1500, Distance: 5.25},
+			ExerciseName: "Running",
+			ExerciseType: "distance_time",
+		},
+	}))
+	w.Flush()
+
+	mapping, err := Mapping{Preset: "fit"}.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	workouts, problems, err := Parse(strings.NewReader(b.String()), mapping)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("Parse() = %+v, %v", problems, err)
+	}
+
+	want := []parsedWorkout{{
+		PerformedAt: time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC),
+		Name:        "Run",
+		Sets:        []parsedSet{{Row: 2, Exercise: "Running", SetType: store.SetWorking, Duration: 1500, Distance: 5.25}},
+	}}
+	if got := describe(workouts); !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse() = %+v, want %+v", got, want)
+	}
+}
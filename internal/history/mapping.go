@@ -0,0 +1,374 @@
+package history
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Mapping names the columns of a file that hold each field of a set.
+// Only Date and Exercise are required; fields without a column are
+// left at zero.
+type Mapping struct {
+	// Preset fills in the columns of a known export format. Columns
+	// given alongside it override those of the preset.
+	Preset string `json:"preset"`
+	// Delimiter separates the fields of a row. It defaults to a comma.
+	Delimiter string `json:"delimiter"`
+
+	Date     string `json:"date"`
+	Workout  string `json:"workout"`
+	Exercise string `json:"exercise"`
+	// SetType holds the type of each set, either as a name or as the
+	// markers some trackers put in their set order column: W for
+	// warm-up, D for drop set and F for failure, any number meaning a
+	// working set.
+	SetType  string `json:"set_type"`
+	Weight   string `json:"weight"`
+	Reps     string `json:"reps"`
+	RPE      string `json:"rpe"`
+	Duration string `json:"duration"`
+	Distance string `json:"distance"`
+
+	// DateFormat is the Go layout of dates. Without one, RFC 3339 and
+	// a few common layouts are tried.
+	DateFormat string `json:"date_format"`
+	// WeightScale and DistanceScale convert the units of the file, such
+	// as 0.45359237 for pounds to kilograms. They default to 1.
+	WeightScale   float64 `json:"weight_scale"`
+	DistanceScale float64 `json:"distance_scale"`
+
+	// Exercises maps exercise names of the file to exercise IDs,
+	// overriding the fuzzy matching of those names.
+	Exercises map[string]int `json:"exercises"`
+	// CreateMissing creates the exercises that match nothing rather than
+	// leaving their sets out.
+	CreateMissing bool `json:"create_missing"`
+}
+
+// Presets are the mappings of the export formats this package knows.
+var Presets = map[string]Mapping{
+	"fit": {
+		Date: "date", Workout: "workout", Exercise: "exercise", SetType: "set_type",
+		Weight: "weight", Reps: "reps", RPE: "rpe", Duration: "duration", Distance: "distance",
+	},
+	"strong": {
+		Date: "Date", Workout: "Workout Name", Exercise: "Exercise Name", SetType: "Set Order",
+		Weight: "Weight", Reps: "Reps", RPE: "RPE", Duration: "Seconds", Distance: "Distance",
+		DateFormat: "2006-01-02 15:04:05",
+	},
+	"hevy": {
+		Date: "start_time", Workout: "title", Exercise: "exercise_title", SetType: "set_type",
+		Weight: "weight_kg", Reps: "reps", RPE: "rpe", Duration: "duration_seconds", Distance: "distance_km",
+		DateFormat: "2 Jan 2006, 15:04",
+	},
+}
+
+// dateLayouts are tried in order when the mapping has no date format.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+// setTypes maps the set type names and markers of other trackers to
+// set types.
+var setTypes = map[string]string{
+	"":        store.SetWorking,
+	"normal":  store.SetWorking,
+	"working": store.SetWorking,
+	"warmup":  store.SetWarmup,
+	"warm-up": store.SetWarmup,
+	"w":       store.SetWarmup,
+	"dropset": store.SetDropset,
+	"drop":    store.SetDropset,
+	"d":       store.SetDropset,
+	"failure": store.SetFailure,
+	"f":       store.SetFailure,
+	"amrap":   store.SetAMRAP,
+}
+
+// Resolve applies the preset of the mapping and checks that it can be
+// used.
+func (m Mapping) Resolve() (Mapping, error) {
+	if m.Preset != "" {
+		preset, ok := Presets[strings.ToLower(m.Preset)]
+		if !ok {
+			names := make([]string, 0, len(Presets))
+			for name := range Presets {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return m, fmt.Errorf("Preset must be one of %s", strings.Join(names, ", "))
+		}
+
+		for _, field := range []struct{ value, preset *string }{
+			{&m.Date, &preset.Date},
+			{&m.Workout, &preset.Workout},
+			{&m.Exercise, &preset.Exercise},
+			{&m.SetType, &preset.SetType},
+			{&m.Weight, &preset.Weight},
+			{&m.Reps, &preset.Reps},
+			{&m.RPE, &preset.RPE},
+			{&m.Duration, &preset.Duration},
+			{&m.Distance, &preset.Distance},
+			{&m.DateFormat, &preset.DateFormat},
+		} {
+			if *field.value == "" {
+				*field.value = *field.preset
+			}
+		}
+	}
+
+	if m.Date == "" || m.Exercise == "" {
+		return m, errors.New("Mapping requires date and exercise columns")
+	}
+	if m.Delimiter != "" && utf8.RuneCountInString(m.Delimiter) != 1 {
+		return m, errors.New("Delimiter must be a single character")
+	}
+	if m.WeightScale == 0 {
+		m.WeightScale = 1
+	}
+	if m.DistanceScale == 0 {
+		m.DistanceScale = 1
+	}
+	if m.WeightScale < 0 || m.DistanceScale < 0 {
+		return m, errors.New("Scales must be positive")
+	}
+	return m, nil
+}
+
+// Set is a set read from a file.
+type Set struct {
+	// Row is the line of the file the set was read from.
+	Row      int
+	Exercise string
+	store.WorkoutSet
+}
+
+// Workout is a workout read from a file, with its sets in file order.
+type Workout struct {
+	PerformedAt time.Time
+	Name        string
+	Sets        []Set
+}
+
+// Problem is a row of a file that could not be read.
+type Problem struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// columns holds the index of the column of each field of a set, or -1
+// for fields that are not mapped.
+type columns struct {
+	date, workout, exercise, setType, weight, reps, rpe, duration, distance int
+}
+
+// row reads the fields of a record.
+type row []string
+
+func (r row) field(i int) string {
+	if i < 0 || i >= len(r) {
+		return ""
+	}
+	return strings.TrimSpace(r[i])
+}
+
+// Parse reads the rows of a file with a resolved mapping. Rows that
+// cannot be read are reported as problems and left out; an error is
+// only returned if the file as a whole cannot be read. Workouts are
+// returned oldest first.
+func Parse(r io.Reader, m Mapping) ([]Workout, []Problem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	if m.Delimiter != "" {
+		reader.Comma, _ = utf8.DecodeRuneInString(m.Delimiter)
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to read header: %w", err)
+	}
+
+	names := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimPrefix(name, "\uFEFF")
+		names[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	var cols columns
+	for _, field := range []struct {
+		index *int
+		name  string
+	}{
+		{&cols.date, m.Date},
+		{&cols.workout, m.Workout},
+		{&cols.exercise, m.Exercise},
+		{&cols.setType, m.SetType},
+		{&cols.weight, m.Weight},
+		{&cols.reps, m.Reps},
+		{&cols.rpe, m.RPE},
+		{&cols.duration, m.Duration},
+		{&cols.distance, m.Distance},
+	} {
+		*field.index = -1
+		if field.name == "" {
+			continue
+		}
+
+		i, ok := names[strings.ToLower(strings.TrimSpace(field.name))]
+		if !ok {
+			return nil, nil, fmt.Errorf("Column %q is not in the file", field.name)
+		}
+		*field.index = i
+	}
+
+	var workouts []Workout
+	var problems []Problem
+	byKey := map[string]int{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			problems = append(problems, Problem{Row: parseErr.Line, Error: parseErr.Err.Error()})
+			continue
+		}
+
+		line, _ := reader.FieldPos(0)
+		set, performedAt, err := parseSet(row(record), cols, m)
+		if err != nil {
+			problems = append(problems, Problem{Row: line, Error: err.Error()})
+			continue
+		}
+		set.Row = line
+
+		// Rows of the same workout share its date and name
+		name := row(record).field(cols.workout)
+		key := row(record).field(cols.date) + "\x00" + name
+		i, ok := byKey[key]
+		if !ok {
+			i = len(workouts)
+			byKey[key] = i
+			workouts = append(workouts, Workout{PerformedAt: performedAt, Name: name})
+		}
+		workouts[i].Sets = append(workouts[i].Sets, set)
+	}
+
+	sort.SliceStable(workouts, func(i, j int) bool {
+		return workouts[i].PerformedAt.Before(workouts[j].PerformedAt)
+	})
+	return workouts, problems, nil
+}
+
+// parseSet reads the set of a record and when it was performed.
+func parseSet(record row, cols columns, m Mapping) (Set, time.Time, error) {
+	set := Set{Exercise: record.field(cols.exercise)}
+	set.Completed = true
+
+	performedAt, err := parseDate(record.field(cols.date), m.DateFormat)
+	if err != nil {
+		return set, performedAt, err
+	}
+
+	if set.Exercise == "" {
+		return set, performedAt, errors.New("Exercise is required")
+	}
+
+	setType := record.field(cols.setType)
+	if set.SetType = setTypes[strings.ToLower(setType)]; set.SetType == "" {
+		if _, err := strconv.Atoi(setType); err != nil {
+			return set, performedAt, fmt.Errorf("Unknown set type %q", setType)
+		}
+		set.SetType = store.SetWorking
+	}
+
+	if set.Weight, err = parseNumber(record.field(cols.weight), "weight"); err != nil {
+		return set, performedAt, err
+	}
+	set.Weight *= m.WeightScale
+
+	reps, err := parseNumber(record.field(cols.reps), "reps")
+	if err != nil {
+		return set, performedAt, err
+	}
+	set.Reps = int(math.Round(reps))
+
+	if set.RPE, err = parseNumber(record.field(cols.rpe), "RPE"); err != nil {
+		return set, performedAt, err
+	}
+
+	duration, err := parseNumber(record.field(cols.duration), "duration")
+	if err != nil {
+		return set, performedAt, err
+	}
+	set.Duration = int(math.Round(duration))
+
+	if set.Distance, err = parseNumber(record.field(cols.distance), "distance"); err != nil {
+		return set, performedAt, err
+	}
+	set.Distance *= m.DistanceScale
+	return set, performedAt, nil
+}
+
+func parseDate(value, layout string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("Date is required")
+	}
+
+	layouts := dateLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("Invalid date %q", value)
+}
+
+func parseNumber(value, name string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 || math.IsInf(number, 0) || math.IsNaN(number) {
+		return 0, fmt.Errorf("Invalid %s %q", name, value)
+	}
+	return number, nil
+}
+
+// InferType guesses the exercise type of an exercise from its sets:
+// sets with a distance are timed runs or rides, sets with only a
+// duration are holds, and anything else is lifted.
+func InferType(sets []Set) string {
+	exerciseType := "duration_only"
+	for _, set := range sets {
+		if set.Distance > 0 {
+			return "distance_time"
+		}
+		if set.Weight > 0 || set.Reps > 0 {
+			exerciseType = "weight_reps"
+		}
+	}
+	return exerciseType
+}
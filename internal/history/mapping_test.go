@@ -0,0 +1,289 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		want    Mapping
+		wantErr string
+	}{
+		{
+			name:    "columns",
+			mapping: Mapping{Date: "day", Exercise: "lift", WeightScale: 0.5},
+			want:    Mapping{Date: "day", Exercise: "lift", WeightScale: 0.5, DistanceScale: 1},
+		},
+		{
+			name:    "preset",
+			mapping: Mapping{Preset: "Strong"},
+			want: Mapping{
+				Preset: "Strong", Date: "Date", Workout: "Workout Name", Exercise: "Exercise Name", SetType: "Set Order",
+				Weight: "Weight", Reps: "Reps", RPE: "RPE", Duration: "Seconds", Distance: "Distance",
+				DateFormat: "2006-01-02 15:04:05", WeightScale: 1, DistanceScale: 1,
+			},
+		},
+		{
+			name:    "preset with overrides",
+			mapping: Mapping{Preset: "hevy", Weight: "weight_lbs", WeightScale: 0.45359237},
+			want: Mapping{
+				Preset: "hevy", Date: "start_time", Workout: "title", Exercise: "exercise_title", SetType: "set_type",
+				Weight: "weight_lbs", Reps: "reps", RPE: "rpe", Duration: "duration_seconds", Distance: "distance_km",
+				DateFormat: "2 Jan 2006, 15:04", WeightScale: 0.45359237, DistanceScale: 1,
+			},
+		},
+		{
+			name:    "unknown preset",
+			mapping: Mapping{Preset: "other"},
+			wantErr: "Preset must be one of fit, hevy, strong",
+		},
+		{
+			name:    "without date",
+			mapping: Mapping{Exercise: "lift"},
+			wantErr: "Mapping requires date and exercise columns",
+		},
+		{
+			name:    "without exercise",
+			mapping: Mapping{Date: "day"},
+			wantErr: "Mapping requires date and exercise columns",
+		},
+		{
+			name:    "long delimiter",
+			mapping: Mapping{Preset: "fit", Delimiter: ";;"},
+			wantErr: "Delimiter must be a single character",
+		},
+		{
+			name:    "negative scale",
+			mapping: Mapping{Preset: "fit", DistanceScale: -1},
+			wantErr: "Scales must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.mapping.Resolve()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// parsedSet describes a set read from a file.
+type parsedSet struct {
+	Row      int
+	Exercise string
+	SetType  string
+	Weight   float64
+	Reps     int
+	RPE      float64
+	Duration int
+	Distance float64
+}
+
+// parsedWorkout describes a workout read from a file.
+type parsedWorkout struct {
+	PerformedAt time.Time
+	Name        string
+	Sets        []parsedSet
+}
+
+func describe(workouts []Workout) []parsedWorkout {
+	described := []parsedWorkout{}
+	for _, workout := range workouts {
+		sets := []parsedSet{}
+		for _, set := range workout.Sets {
+			sets = append(sets, parsedSet{
+				Row: set.Row, Exercise: set.Exercise, SetType: set.SetType,
+				Weight: set.Weight, Reps: set.Reps, RPE: set.RPE, Duration: set.Duration, Distance: set.Distance,
+			})
+		}
+		described = append(described, parsedWorkout{PerformedAt: workout.PerformedAt, Name: workout.Name, Sets: sets})
+	}
+	return described
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping Mapping
+		file    string
+		want    []parsedWorkout
+	}{
+		{
+			name:    "strong",
+			mapping: Mapping{Preset: "strong"},
+			file: "\uFEFFDate,Workout Name,Exercise Name,Set Order,Weight,Reps,Distance,Seconds,Notes,RPE\n" +
+				"2024-01-02 18:00:00,Push,Bench Press (Barbell),W,40,10,0,0,,\n" +
+				"2024-01-02 18:00:00,Push,Bench Press (Barbell),1,80,5,0,0,,8\n" +
+				"2024-01-02 18:00:00,Push,Plank,1,0,0,0,60,,\n" +
+				"2024-01-01 07:30:00,Run,Running,1,0,0,5.2,1800,,\n",
+			want: []parsedWorkout{
+				{PerformedAt: time.Date(2024, 1, 1, 7, 30, 0, 0, time.UTC), Name: "Run", Sets: []parsedSet{
+					{Row: 5, Exercise: "Running", SetType: store.SetWorking, Duration: 1800, Distance: 5.2},
+				}},
+				{PerformedAt: time.Date(2024, 1, 2, 18, 0, 0, 0, time.UTC), Name: "Push", Sets: []parsedSet{
+					{Row: 2, Exercise: "Bench Press (Barbell)", SetType: store.SetWarmup, Weight: 40, Reps: 10},
+					{Row: 3, Exercise: "Bench Press (Barbell)", SetType: store.SetWorking, Weight: 80, Reps: 5, RPE: 8},
+					{Row: 4, Exercise: "Plank", SetType: store.SetWorking, Duration: 60},
+				}},
+			},
+		},
+		{
+			name:    "hevy",
+			mapping: Mapping{Preset: "hevy"},
+			file: "title,start_time,exercise_title,set_type,weight_kg,reps,distance_km,duration_seconds,rpe\n" +
+				"Legs,\"3 Jan 2024, 17:15\",Squat (Barbell),warmup,60,5,,,\n" +
+				"Legs,\"3 Jan 2024, 17:15\",Squat (Barbell),normal,100,5,,,8.5\n" +
+				"Legs,\"3 Jan 2024, 17:15\",Squat (Barbell),dropset,80,8,,,\n",
+			want: []parsedWorkout{
+				{PerformedAt: time.Date(2024, 1, 3, 17, 15, 0, 0, time.UTC), Name: "Legs", Sets: []parsedSet{
+					{Row: 2, Exercise: "Squat (Barbell)", SetType: store.SetWarmup, Weight: 60, Reps: 5},
+					{Row: 3, Exercise: "Squat (Barbell)", SetType: store.SetWorking, Weight: 100, Reps: 5, RPE: 8.5},
+					{Row: 4, Exercise: "Squat (Barbell)", SetType: store.SetDropset, Weight: 80, Reps: 8},
+				}},
+			},
+		},
+		{
+			name:    "custom columns in pounds and miles",
+			mapping: Mapping{Delimiter: ";", Date: "When", Exercise: "What", Weight: "Lbs", Reps: "Reps", Distance: "Miles", WeightScale: 0.5, DistanceScale: 1.5},
+			file: "when;what;lbs;reps;miles\n" +
+				"2024-01-04;Deadlift;400;3;\n" +
+				"2024-01-04;Bike;;;10\n",
+			want: []parsedWorkout{
+				{PerformedAt: time.Date(2024, 1, 4, 0, 0, 0, 0, time.UTC), Sets: []parsedSet{
+					{Row: 2, Exercise: "Deadlift", SetType: store.SetWorking, Weight: 200, Reps: 3},
+					{Row: 3, Exercise: "Bike", SetType: store.SetWorking, Distance: 15},
+				}},
+			},
+		},
+		{
+			name:    "header only",
+			mapping: Mapping{Preset: "fit"},
+			file:    strings.Join(Columns, ",") + "\n",
+			want:    []parsedWorkout{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapping, err := tt.mapping.Resolve()
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+
+			workouts, problems, err := Parse(strings.NewReader(tt.file), mapping)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(problems) > 0 {
+				t.Errorf("Parse() problems = %+v, want none", problems)
+			}
+			if got := describe(workouts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReportsProblems(t *testing.T) {
+	file := "date,exercise,set_type,weight,reps\n" +
+		"2024-01-05,Squat,working,100,5\n" +
+		"yesterday,Squat,working,100,5\n" +
+		"2024-01-05,,working,100,5\n" +
+		"2024-01-05,Squat,superset,100,5\n" +
+		"2024-01-05,Squat,working,-100,5\n" +
+		"2024-01-05,Squat,working,100,five\n" +
+		"2024-01-05,\"Squat,working,100,5\n"
+
+	workouts, problems, err := Parse(strings.NewReader(file), Mapping{
+		Date: "date", Exercise: "exercise", SetType: "set_type", Weight: "weight", Reps: "reps",
+	})
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(workouts) != 1 || len(workouts[0].Sets) != 1 {
+		t.Errorf("Parse() = %+v, want a single workout of a single set", workouts)
+	}
+
+	want := []Problem{
+		{Row: 3, Error: `Invalid date "yesterday"`},
+		{Row: 4, Error: "Exercise is required"},
+		{Row: 5, Error: `Unknown set type "superset"`},
+		{Row: 6, Error: `Invalid weight "-100"`},
+		{Row: 7, Error: `Invalid reps "five"`},
+	}
+	if len(problems) != len(want)+1 {
+		t.Fatalf("Parse() problems = %+v, want %d", problems, len(want)+1)
+	}
+	if !reflect.DeepEqual(problems[:len(want)], want) {
+		t.Errorf("Parse() problems = %+v, want %+v", problems[:len(want)], want)
+	}
+	// The unterminated quote is reported by the CSV reader
+	if problems[len(want)].Row != 8 {
+		t.Errorf("Parse() reported the unterminated quote on row %d, want 8", problems[len(want)].Row)
+	}
+}
+
+func TestParseFailures(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{"empty file", "", "Failed to read header: EOF"},
+		{"missing column", "date,weight\n", `Column "exercise" is not in the file`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(strings.NewReader(tt.file), Mapping{Date: "date", Exercise: "exercise"})
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("Parse() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInferType(t *testing.T) {
+	set := func(weight float64, reps, duration int, distance float64) Set {
+		return Set{WorkoutSet: store.WorkoutSet{Weight: weight, Reps: reps, Duration: duration, Distance: distance}}
+	}
+
+	tests := []struct {
+		name string
+		sets []Set
+		want string
+	}{
+		{"lifted", []Set{set(100, 5, 0, 0)}, "weight_reps"},
+		{"bodyweight", []Set{set(0, 12, 0, 0)}, "weight_reps"},
+		{"held", []Set{set(0, 0, 60, 0)}, "duration_only"},
+		{"run", []Set{set(0, 0, 1800, 5)}, "distance_time"},
+		{"distance wins", []Set{set(20, 10, 0, 0), set(0, 0, 0, 1)}, "distance_time"},
+		{"no sets", nil, "duration_only"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InferType(tt.sets); got != tt.want {
+				t.Errorf("InferType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
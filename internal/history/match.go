@@ -0,0 +1,122 @@
+package history
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// MinScore is the lowest score at which a name matches an exercise.
+const MinScore = 0.6
+
+// Matcher matches the exercise names of other trackers, such as
+// "Bench Press (Barbell)", to exercises, such as "Barbell Bench Press".
+//
+// Names are compared as sets of words, ignoring case, punctuation,
+// word order and plurals, and tolerating a typo in longer words. A
+// name scores the average of the share of words the two names have in
+// common and the share of the shorter name that the other contains.
+type Matcher struct {
+	exercises []store.Exercise
+	words     [][]string
+}
+
+// NewMatcher returns a Matcher of the exercises.
+func NewMatcher(exercises []store.Exercise) *Matcher {
+	m := &Matcher{exercises: exercises}
+	for _, exercise := range exercises {
+		m.words = append(m.words, words(exercise.Name))
+	}
+	return m
+}
+
+// Match returns the exercise that best matches the name and its score
+// between 0 and 1, or false if none scores at least MinScore. Ties go
+// to the exercise listed first.
+func (m *Matcher) Match(name string) (store.Exercise, float64, bool) {
+	nameWords := words(name)
+	best, bestScore := -1, 0.0
+	for i, exerciseWords := range m.words {
+		if score := similarity(nameWords, exerciseWords); score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	if best < 0 || bestScore < MinScore {
+		return store.Exercise{}, bestScore, false
+	}
+	return m.exercises[best], bestScore, true
+}
+
+// words splits a name into lower case words without plural endings.
+func words(name string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for i, word := range fields {
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			fields[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return fields
+}
+
+// similarity scores how alike two lists of words are.
+func similarity(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	common := 0
+	used := make([]bool, len(b))
+	for _, word := range a {
+		for j, other := range b {
+			if !used[j] && sameWord(word, other) {
+				used[j] = true
+				common++
+				break
+			}
+		}
+	}
+
+	union := len(a) + len(b) - common
+	shorter := min(len(a), len(b))
+	return (float64(common)/float64(union) + float64(common)/float64(shorter)) / 2
+}
+
+// sameWord reports whether two words are equal, allowing one typo in
+// words of five letters or more.
+func sameWord(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if min(len(a), len(b)) < 5 {
+		return false
+	}
+	return distance(a, b) <= 1
+}
+
+// distance returns the Levenshtein distance between two words.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
@@ -0,0 +1,71 @@
+package history
+
+import (
+	"math"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestMatcher(t *testing.T) {
+	matcher := NewMatcher([]store.Exercise{
+		{ID: 1, Name: "Barbell Bench Press"},
+		{ID: 2, Name: "Dumbbell Bench Press"},
+		{ID: 3, Name: "Barbell Back Squat"},
+		{ID: 4, Name: "Pull-Up"},
+		{ID: 5, Name: "Plank"},
+		{ID: 6, Name: "Running"},
+	})
+
+	tests := []struct {
+		name   string
+		want   int
+		wantOK bool
+	}{
+		{"Barbell Bench Press", 1, true},
+		{"Bench Press (Barbell)", 1, true},
+		{"bench press - dumbbell", 2, true},
+		{"Squat (Barbell)", 3, true},
+		{"pull up", 4, true},
+		{"Planks", 5, true},
+		{"Runing", 6, true},
+		{"Plonk", 5, true},
+		// Typos are only tolerated in words of five letters or more
+		{"Plnk", 0, false},
+		// Ties go to the exercise listed first
+		{"Bench Press", 1, true},
+		{"Deadlift", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exercise, score, ok := matcher.Match(tt.name)
+			if ok != tt.wantOK || exercise.ID != tt.want {
+				t.Errorf("Match(%q) = %d (score %.2f, %v), want %d (%v)", tt.name, exercise.ID, score, ok, tt.want, tt.wantOK)
+			}
+			if ok && score < MinScore {
+				t.Errorf("Match(%q) matched with score %.2f, below %.2f", tt.name, score, MinScore)
+			}
+		})
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Bench Press", "bench press", 1},
+		{"Press Bench", "Bench Press", 1},
+		{"Bench Press", "Barbell Bench Press", (2.0/3 + 1) / 2},
+		{"Squat", "Bench Press", 0},
+		{"", "Bench Press", 0},
+	}
+
+	for _, tt := range tests {
+		if got := similarity(words(tt.a), words(tt.b)); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q) = %g, want %g", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
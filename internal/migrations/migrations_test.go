@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testDatabaseEnv names the connection string of a throwaway Postgres
+// database to run the migrations against. Tests that need it are
+// skipped without it.
+const testDatabaseEnv = "FIT_SOARS_TEST_POSTGRES_URL"
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Load() found no migrations")
+	}
+
+	for i, migration := range migrations {
+		// Versions are numbered from 1 without gaps
+		if migration.Version != i+1 {
+			t.Errorf("migration %d_%s has version %d, want %d", migration.Version, migration.Name, migration.Version, i+1)
+		}
+		if strings.TrimSpace(migration.Up) == "" || strings.TrimSpace(migration.Down) == "" {
+			t.Errorf("migration %d_%s has an empty up or down file", migration.Version, migration.Name)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	migrator, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got, want := migrator.Latest(), len(migrator.migrations); got != want {
+		t.Errorf("Latest() = %d, want %d", got, want)
+	}
+	if got := (&Migrator{}).Latest(); got != 0 {
+		t.Errorf("Latest() without migrations = %d, want 0", got)
+	}
+}
+
+func TestToUnknownVersion(t *testing.T) {
+	migrator, err := New(nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// Unknown versions are refused before the database is touched
+	for _, version := range []int{-1, migrator.Latest() + 1} {
+		if err := migrator.To(context.Background(), version); !errors.Is(err, ErrUnknownVersion) {
+			t.Errorf("To(%d) error = %v, want %v", version, err, ErrUnknownVersion)
+		}
+	}
+}
+
+// TestUpAndDown applies every migration, reverts them all one by one and
+// applies them again, checking that each down file undoes its up file.
+func TestUpAndDown(t *testing.T) {
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s not set", testDatabaseEnv)
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	migrator, err := New(db)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := migrator.To(ctx, 0); err != nil {
+		t.Fatalf("To(0) error = %v", err)
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if current, err := migrator.Current(ctx); err != nil || current != migrator.Latest() {
+		t.Fatalf("Current() = %d, %v, want %d", current, err, migrator.Latest())
+	}
+
+	for version := migrator.Latest() - 1; version >= 0; version-- {
+		if err := migrator.Down(ctx); err != nil {
+			t.Fatalf("Down() to %d error = %v", version, err)
+		}
+		if current, err := migrator.Current(ctx); err != nil || current != version {
+			t.Fatalf("Current() = %d, %v, want %d", current, err, version)
+		}
+	}
+
+	if err := migrator.Up(ctx); err != nil {
+		t.Fatalf("Up() after reverting everything error = %v", err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, status := range statuses {
+		if !status.Applied {
+			t.Errorf("migration %d_%s is not applied", status.Version, status.Name)
+		}
+	}
+}
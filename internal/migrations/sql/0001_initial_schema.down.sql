@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS workout_sets;
+DROP TABLE IF EXISTS workouts;
+DROP TABLE IF EXISTS routine_exercises;
+DROP TABLE IF EXISTS routines;
+DROP TABLE IF EXISTS programs;
+DROP TABLE IF EXISTS exercises;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,97 @@
+CREATE TABLE users (
+    id            SERIAL PRIMARY KEY,
+    email         TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE refresh_tokens (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    token_id   TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+CREATE TABLE exercises (
+    id                SERIAL PRIMARY KEY,
+    name              TEXT NOT NULL,
+    equipment         TEXT[] NOT NULL DEFAULT '{}',
+    primary_muscles   TEXT[] NOT NULL DEFAULT '{}',
+    secondary_muscles TEXT[] NOT NULL DEFAULT '{}',
+    exercise_type     TEXT NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX exercises_name_idx ON exercises (name);
+
+CREATE TABLE programs (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id),
+    name       TEXT NOT NULL,
+    is_public  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX programs_user_id_idx ON programs (user_id);
+
+CREATE TABLE routines (
+    id         SERIAL PRIMARY KEY,
+    program_id INTEGER NOT NULL REFERENCES programs (id),
+    name       TEXT NOT NULL,
+    day_number INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX routines_program_id_idx ON routines (program_id);
+
+CREATE TABLE routine_exercises (
+    id                   SERIAL PRIMARY KEY,
+    routine_id           INTEGER NOT NULL REFERENCES routines (id),
+    exercise_id          INTEGER NOT NULL REFERENCES exercises (id),
+    recommended_sets     INTEGER NOT NULL DEFAULT 0,
+    recommended_reps     INTEGER NOT NULL DEFAULT 0,
+    recommended_rpe      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    recommended_duration INTEGER NOT NULL DEFAULT 0,
+    recommended_distance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (routine_id, exercise_id)
+);
+
+-- Workouts keep their history when the routine they followed is
+-- deleted, so the reference is cleared rather than cascaded.
+CREATE TABLE workouts (
+    id           SERIAL PRIMARY KEY,
+    user_id      INTEGER NOT NULL REFERENCES users (id),
+    routine_id   INTEGER REFERENCES routines (id) ON DELETE SET NULL,
+    performed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX workouts_user_id_performed_at_idx ON workouts (user_id, performed_at DESC);
+
+CREATE TABLE workout_sets (
+    id          SERIAL PRIMARY KEY,
+    workout_id  INTEGER NOT NULL REFERENCES workouts (id),
+    exercise_id INTEGER NOT NULL REFERENCES exercises (id),
+    sets        INTEGER NOT NULL DEFAULT 0,
+    reps        INTEGER NOT NULL DEFAULT 0,
+    weight      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    rpe         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    duration    INTEGER NOT NULL DEFAULT 0,
+    distance    DOUBLE PRECISION NOT NULL DEFAULT 0,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX workout_sets_workout_id_idx ON workout_sets (workout_id);
+CREATE INDEX workout_sets_exercise_id_idx ON workout_sets (exercise_id);
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS workouts_user_id_in_progress_idx;
+
+ALTER TABLE workouts
+    DROP COLUMN IF EXISTS finished_at,
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,9 @@
+-- Workouts logged before sessions existed are considered completed.
+ALTER TABLE workouts
+    ADD COLUMN status      TEXT NOT NULL DEFAULT 'completed'
+        CHECK (status IN ('planned', 'in_progress', 'completed', 'abandoned')),
+    ADD COLUMN started_at  TIMESTAMPTZ,
+    ADD COLUMN finished_at TIMESTAMPTZ;
+
+-- A user can only be in one session at a time.
+CREATE UNIQUE INDEX workouts_user_id_in_progress_idx ON workouts (user_id) WHERE status = 'in_progress';
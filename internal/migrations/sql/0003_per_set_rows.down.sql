@@ -0,0 +1,19 @@
+ALTER TABLE workout_sets ADD COLUMN sets INTEGER NOT NULL DEFAULT 0;
+
+-- Collapse identical sets of an exercise back into one aggregate row.
+-- Set types, completion and the order of differing sets are lost.
+UPDATE workout_sets ws
+SET sets = g.sets
+FROM (
+    SELECT MIN(id) AS id, COUNT(*) AS sets
+    FROM workout_sets
+    GROUP BY workout_id, exercise_id, reps, weight, rpe, duration, distance
+) g
+WHERE ws.id = g.id;
+
+DELETE FROM workout_sets WHERE sets = 0;
+
+ALTER TABLE workout_sets
+    DROP COLUMN completed,
+    DROP COLUMN set_type,
+    DROP COLUMN set_index;
@@ -0,0 +1,37 @@
+ALTER TABLE workout_sets
+    ADD COLUMN set_index INTEGER NOT NULL DEFAULT 1,
+    ADD COLUMN set_type  TEXT NOT NULL DEFAULT 'working'
+        CHECK (set_type IN ('warmup', 'working', 'dropset', 'failure', 'amrap')),
+    ADD COLUMN completed BOOLEAN NOT NULL DEFAULT false;
+
+-- Expand every aggregate row into one row per set. Sets are numbered
+-- per exercise within a workout, so rows of the same exercise are laid
+-- out one after the other in the order they were recorded. Rows logged
+-- before this migration describe sets that were performed.
+CREATE TEMPORARY TABLE workout_set_expansion ON COMMIT DROP AS
+SELECT
+    id,
+    GREATEST(sets, 1) AS sets,
+    SUM(GREATEST(sets, 1)) OVER (PARTITION BY workout_id, exercise_id ORDER BY id) - GREATEST(sets, 1) AS base
+FROM workout_sets;
+
+INSERT INTO workout_sets (
+    workout_id, exercise_id, set_index, reps, weight, rpe, duration, distance,
+    completed, created_at, updated_at
+)
+SELECT
+    ws.workout_id, ws.exercise_id, e.base + n, ws.reps, ws.weight, ws.rpe, ws.duration, ws.distance,
+    true, ws.created_at, ws.updated_at
+FROM workout_sets ws
+JOIN workout_set_expansion e ON e.id = ws.id
+CROSS JOIN LATERAL generate_series(2, e.sets) AS n;
+
+UPDATE workout_sets ws
+SET set_index = e.base + 1, completed = true
+FROM workout_set_expansion e
+WHERE ws.id = e.id;
+
+ALTER TABLE workout_sets
+    DROP COLUMN sets,
+    ALTER COLUMN set_index DROP DEFAULT,
+    ADD CONSTRAINT workout_sets_workout_id_exercise_id_set_index_key UNIQUE (workout_id, exercise_id, set_index);
@@ -0,0 +1,19 @@
+-- Every record a user earned is kept, so that the history of each
+-- record can be shown. A record only exists while the set that earned
+-- it does.
+CREATE TABLE personal_records (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    exercise_id    INTEGER NOT NULL REFERENCES exercises (id) ON DELETE CASCADE,
+    workout_set_id INTEGER NOT NULL REFERENCES workout_sets (id) ON DELETE CASCADE,
+    kind           TEXT NOT NULL
+        CHECK (kind IN ('max_weight', 'estimated_1rm', 'reps_at_weight', 'longest_duration', 'fastest_pace')),
+    value          DOUBLE PRECISION NOT NULL,
+    -- The weight lifted, for reps_at_weight records; 0 otherwise.
+    weight         DOUBLE PRECISION NOT NULL DEFAULT 0,
+    achieved_at    TIMESTAMPTZ NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX personal_records_user_id_exercise_id_idx ON personal_records (user_id, exercise_id, kind, weight);
+CREATE INDEX personal_records_workout_set_id_idx ON personal_records (workout_set_id);
@@ -0,0 +1,3 @@
+ALTER TABLE routine_exercises
+    DROP COLUMN IF EXISTS progression_config,
+    DROP COLUMN IF EXISTS progression_scheme;
@@ -0,0 +1,4 @@
+-- Schemes are registered in code, so they are not constrained here.
+ALTER TABLE routine_exercises
+    ADD COLUMN progression_scheme TEXT NOT NULL DEFAULT 'none',
+    ADD COLUMN progression_config JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS program_enrollments;
+DROP TABLE IF EXISTS program_blocks;
+
+ALTER TABLE programs DROP COLUMN IF EXISTS cycle_days;
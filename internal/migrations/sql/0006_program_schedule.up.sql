@@ -0,0 +1,33 @@
+-- Routines are laid out over cycles of cycle_days days by their
+-- day_number. Days without a routine are rest days.
+ALTER TABLE programs
+    ADD COLUMN cycle_days INTEGER NOT NULL DEFAULT 7 CHECK (cycle_days > 0);
+
+-- Blocks split a program into phases of whole cycles, in order of
+-- position. Programs without blocks repeat their cycle indefinitely.
+CREATE TABLE program_blocks (
+    id         SERIAL PRIMARY KEY,
+    program_id INTEGER NOT NULL REFERENCES programs (id),
+    position   INTEGER NOT NULL,
+    name       TEXT NOT NULL DEFAULT '',
+    weeks      INTEGER NOT NULL CHECK (weeks > 0),
+    deload     BOOLEAN NOT NULL DEFAULT false,
+    set_scale  DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (set_scale > 0),
+    rpe_scale  DOUBLE PRECISION NOT NULL DEFAULT 1 CHECK (rpe_scale > 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (program_id, position)
+);
+
+CREATE TABLE program_enrollments (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    program_id INTEGER NOT NULL REFERENCES programs (id),
+    start_date DATE NOT NULL,
+    ended_at   TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- A user can only follow a program once at a time.
+CREATE UNIQUE INDEX program_enrollments_active_idx ON program_enrollments (user_id, program_id) WHERE ended_at IS NULL;
+CREATE INDEX program_enrollments_program_id_idx ON program_enrollments (program_id);
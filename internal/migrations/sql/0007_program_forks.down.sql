@@ -0,0 +1,2 @@
+ALTER TABLE programs DROP COLUMN IF EXISTS fork_count;
+ALTER TABLE programs DROP COLUMN IF EXISTS forked_from_program_id;
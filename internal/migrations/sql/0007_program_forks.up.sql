@@ -0,0 +1,8 @@
+-- Forks are copies of a program adopted by another user. Forks outlive
+-- the program they were forked from, and fork_count keeps counting
+-- forks that were deleted since.
+ALTER TABLE programs
+    ADD COLUMN forked_from_program_id INTEGER REFERENCES programs (id) ON DELETE SET NULL,
+    ADD COLUMN fork_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX programs_forked_from_program_id_idx ON programs (forked_from_program_id);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS track_points;
+DROP TABLE IF EXISTS workout_tracks;
@@ -0,0 +1,29 @@
+-- Tracks are GPS recordings of distance_time sets, uploaded as GPX or
+-- TCX files. The distance, duration and elevation gain derived from the
+-- points are kept with the track, since the set can be edited later.
+CREATE TABLE workout_tracks (
+    id             SERIAL PRIMARY KEY,
+    workout_id     INTEGER NOT NULL REFERENCES workouts (id) ON DELETE CASCADE,
+    workout_set_id INTEGER NOT NULL UNIQUE REFERENCES workout_sets (id) ON DELETE CASCADE,
+    format         TEXT NOT NULL CHECK (format IN ('gpx', 'tcx')),
+    name           TEXT NOT NULL DEFAULT '',
+    distance       DOUBLE PRECISION NOT NULL,
+    duration       INTEGER NOT NULL,
+    elevation_gain DOUBLE PRECISION NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX workout_tracks_workout_id_idx ON workout_tracks (workout_id);
+
+-- Points are numbered in recording order. Elevation, time and heart
+-- rate are missing from some recordings.
+CREATE TABLE track_points (
+    track_id    INTEGER NOT NULL REFERENCES workout_tracks (id) ON DELETE CASCADE,
+    seq         INTEGER NOT NULL,
+    latitude    DOUBLE PRECISION NOT NULL,
+    longitude   DOUBLE PRECISION NOT NULL,
+    elevation   DOUBLE PRECISION,
+    recorded_at TIMESTAMPTZ,
+    heart_rate  INTEGER,
+    PRIMARY KEY (track_id, seq)
+);
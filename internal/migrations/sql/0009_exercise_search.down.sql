@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS exercises_secondary_muscles_idx;
+DROP INDEX IF EXISTS exercises_primary_muscles_idx;
+DROP INDEX IF EXISTS exercises_equipment_idx;
+DROP INDEX IF EXISTS exercises_search_trgm_idx;
+DROP INDEX IF EXISTS exercises_search_tsv_idx;
+DROP FUNCTION IF EXISTS exercise_search_text(TEXT, TEXT[]);
+ALTER TABLE exercises DROP COLUMN IF EXISTS aliases;
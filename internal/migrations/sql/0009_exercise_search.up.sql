@@ -0,0 +1,20 @@
+-- Exercises are searched by their name and aliases, both by words and,
+-- to forgive typos, by trigram similarity.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE exercises ADD COLUMN aliases TEXT[] NOT NULL DEFAULT '{}';
+
+-- array_to_string is only STABLE, so the searched text is built by an
+-- IMMUTABLE function that the expression indexes can use.
+CREATE FUNCTION exercise_search_text(name TEXT, aliases TEXT[]) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+    AS $$ SELECT name || ' ' || array_to_string(aliases, ' ') $$;
+
+CREATE INDEX exercises_search_tsv_idx ON exercises
+    USING GIN (to_tsvector('simple', exercise_search_text(name, aliases)));
+CREATE INDEX exercises_search_trgm_idx ON exercises
+    USING GIN (exercise_search_text(name, aliases) gin_trgm_ops);
+
+CREATE INDEX exercises_equipment_idx ON exercises USING GIN (equipment);
+CREATE INDEX exercises_primary_muscles_idx ON exercises USING GIN (primary_muscles);
+CREATE INDEX exercises_secondary_muscles_idx ON exercises USING GIN (secondary_muscles);
@@ -0,0 +1,6 @@
+-- Exercises keep their normalized values.
+ALTER TABLE exercises DROP CONSTRAINT IF EXISTS exercises_exercise_type_fkey;
+DROP TABLE IF EXISTS exercise_types;
+DROP TABLE IF EXISTS equipment;
+DROP TABLE IF EXISTS muscles;
+DROP TABLE IF EXISTS muscle_groups;
@@ -0,0 +1,206 @@
+-- Muscles, equipment and exercise types are canonical values referenced
+-- by their slug: lowercase words joined by underscores. Exercises keep
+-- muscles and equipment as arrays of slugs, which the server validates,
+-- and reference their type with a foreign key.
+CREATE TABLE muscle_groups (
+    slug     TEXT PRIMARY KEY,
+    name     TEXT NOT NULL,
+    position INTEGER NOT NULL
+);
+
+CREATE TABLE muscles (
+    slug         TEXT PRIMARY KEY,
+    name         TEXT NOT NULL,
+    muscle_group TEXT NOT NULL REFERENCES muscle_groups (slug)
+);
+
+CREATE TABLE equipment (
+    slug TEXT PRIMARY KEY,
+    name TEXT NOT NULL
+);
+
+-- Exercise types decide how sets are validated and which records they
+-- earn, so only the types the server supports are listed.
+CREATE TABLE exercise_types (
+    slug        TEXT PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL
+);
+
+INSERT INTO muscle_groups (slug, name, position) VALUES
+    ('chest', 'Chest', 1),
+    ('back', 'Back', 2),
+    ('shoulders', 'Shoulders', 3),
+    ('arms', 'Arms', 4),
+    ('core', 'Core', 5),
+    ('legs', 'Legs', 6);
+
+INSERT INTO muscles (slug, name, muscle_group) VALUES
+    ('chest', 'Chest', 'chest'),
+    ('lats', 'Lats', 'back'),
+    ('traps', 'Traps', 'back'),
+    ('upper_back', 'Upper back', 'back'),
+    ('lower_back', 'Lower back', 'back'),
+    ('front_delts', 'Front delts', 'shoulders'),
+    ('side_delts', 'Side delts', 'shoulders'),
+    ('rear_delts', 'Rear delts', 'shoulders'),
+    ('biceps', 'Biceps', 'arms'),
+    ('triceps', 'Triceps', 'arms'),
+    ('forearms', 'Forearms', 'arms'),
+    ('abs', 'Abs', 'core'),
+    ('obliques', 'Obliques', 'core'),
+    ('quads', 'Quads', 'legs'),
+    ('hamstrings', 'Hamstrings', 'legs'),
+    ('glutes', 'Glutes', 'legs'),
+    ('calves', 'Calves', 'legs'),
+    ('adductors', 'Adductors', 'legs'),
+    ('abductors', 'Abductors', 'legs'),
+    ('hip_flexors', 'Hip flexors', 'legs');
+
+INSERT INTO equipment (slug, name) VALUES
+    ('barbell', 'Barbell'),
+    ('dumbbell', 'Dumbbell'),
+    ('kettlebell', 'Kettlebell'),
+    ('ez_bar', 'EZ bar'),
+    ('trap_bar', 'Trap bar'),
+    ('smith_machine', 'Smith machine'),
+    ('cable', 'Cable'),
+    ('machine', 'Machine'),
+    ('bench', 'Bench'),
+    ('pull_up_bar', 'Pull-up bar'),
+    ('resistance_band', 'Resistance band'),
+    ('medicine_ball', 'Medicine ball'),
+    ('bodyweight', 'Bodyweight'),
+    ('treadmill', 'Treadmill'),
+    ('stationary_bike', 'Stationary bike'),
+    ('rowing_machine', 'Rowing machine');
+
+INSERT INTO exercise_types (slug, name, description) VALUES
+    ('weight_reps', 'Weight and reps', 'Sets of repetitions with a weight'),
+    ('duration_only', 'Duration', 'Sets held or performed for a time'),
+    ('distance_time', 'Distance and time', 'Sets covering a distance in a time');
+
+-- Normalize existing exercises: values are turned into slugs and common
+-- synonyms into their canonical slug.
+CREATE TEMPORARY TABLE taxonomy_synonyms (
+    kind  TEXT NOT NULL,
+    value TEXT NOT NULL,
+    slug  TEXT NOT NULL
+) ON COMMIT DROP;
+
+INSERT INTO taxonomy_synonyms (kind, value, slug) VALUES
+    ('muscle', 'pecs', 'chest'),
+    ('muscle', 'pectorals', 'chest'),
+    ('muscle', 'lat', 'lats'),
+    ('muscle', 'latissimus_dorsi', 'lats'),
+    ('muscle', 'trap', 'traps'),
+    ('muscle', 'trapezius', 'traps'),
+    ('muscle', 'rhomboids', 'upper_back'),
+    ('muscle', 'middle_back', 'upper_back'),
+    ('muscle', 'mid_back', 'upper_back'),
+    ('muscle', 'erector_spinae', 'lower_back'),
+    ('muscle', 'shoulders', 'front_delts'),
+    ('muscle', 'delts', 'front_delts'),
+    ('muscle', 'deltoids', 'front_delts'),
+    ('muscle', 'bicep', 'biceps'),
+    ('muscle', 'tricep', 'triceps'),
+    ('muscle', 'forearm', 'forearms'),
+    ('muscle', 'grip', 'forearms'),
+    ('muscle', 'abdominals', 'abs'),
+    ('muscle', 'core', 'abs'),
+    ('muscle', 'oblique', 'obliques'),
+    ('muscle', 'quad', 'quads'),
+    ('muscle', 'quadriceps', 'quads'),
+    ('muscle', 'hamstring', 'hamstrings'),
+    ('muscle', 'glute', 'glutes'),
+    ('muscle', 'gluteus_maximus', 'glutes'),
+    ('muscle', 'calf', 'calves'),
+    ('muscle', 'adductor', 'adductors'),
+    ('muscle', 'abductor', 'abductors'),
+    ('muscle', 'hip_flexor', 'hip_flexors'),
+    ('equipment', 'barbells', 'barbell'),
+    ('equipment', 'bb', 'barbell'),
+    ('equipment', 'dumbbells', 'dumbbell'),
+    ('equipment', 'db', 'dumbbell'),
+    ('equipment', 'kettlebells', 'kettlebell'),
+    ('equipment', 'kb', 'kettlebell'),
+    ('equipment', 'ez_curl_bar', 'ez_bar'),
+    ('equipment', 'hex_bar', 'trap_bar'),
+    ('equipment', 'cables', 'cable'),
+    ('equipment', 'cable_machine', 'cable'),
+    ('equipment', 'pullup_bar', 'pull_up_bar'),
+    ('equipment', 'chin_up_bar', 'pull_up_bar'),
+    ('equipment', 'band', 'resistance_band'),
+    ('equipment', 'bands', 'resistance_band'),
+    ('equipment', 'resistance_bands', 'resistance_band'),
+    ('equipment', 'none', 'bodyweight'),
+    ('equipment', 'body_weight', 'bodyweight'),
+    ('equipment', 'body_only', 'bodyweight'),
+    ('equipment', 'bike', 'stationary_bike'),
+    ('equipment', 'rower', 'rowing_machine'),
+    ('exercise_type', 'weight', 'weight_reps'),
+    ('exercise_type', 'weights', 'weight_reps'),
+    ('exercise_type', 'strength', 'weight_reps'),
+    ('exercise_type', 'reps', 'weight_reps'),
+    ('exercise_type', 'duration', 'duration_only'),
+    ('exercise_type', 'time', 'duration_only'),
+    ('exercise_type', 'timed', 'duration_only'),
+    ('exercise_type', 'cardio', 'distance_time'),
+    ('exercise_type', 'distance', 'distance_time');
+
+-- normalize_taxonomy normalizes an array of values of a kind, dropping
+-- empty values and duplicates but keeping their order.
+CREATE FUNCTION pg_temp.normalize_taxonomy(TEXT, TEXT[]) RETURNS TEXT[]
+    LANGUAGE SQL
+    AS $$
+    SELECT COALESCE(array_agg(normalized.slug ORDER BY normalized.position), '{}')
+    FROM (
+        SELECT COALESCE(synonym.slug, value.slug) AS slug, MIN(value.position) AS position
+        FROM (
+            SELECT btrim(regexp_replace(lower(item), '[^a-z0-9]+', '_', 'g'), '_') AS slug, position
+            FROM unnest($2) WITH ORDINALITY AS items (item, position)
+        ) AS value
+        LEFT JOIN taxonomy_synonyms AS synonym ON synonym.kind = $1 AND synonym.value = value.slug
+        WHERE value.slug <> ''
+        GROUP BY 1
+    ) AS normalized
+    $$;
+
+UPDATE exercises SET
+    equipment = pg_temp.normalize_taxonomy('equipment', equipment),
+    primary_muscles = pg_temp.normalize_taxonomy('muscle', primary_muscles),
+    secondary_muscles = pg_temp.normalize_taxonomy('muscle', secondary_muscles),
+    exercise_type = COALESCE(pg_temp.normalize_taxonomy('exercise_type', ARRAY[exercise_type])[1], '');
+
+DROP FUNCTION pg_temp.normalize_taxonomy(TEXT, TEXT[]);
+
+-- Values that are still unknown are kept rather than dropped: they are
+-- added to the taxonomy, muscles under an Other group, to be renamed or
+-- merged by hand.
+INSERT INTO equipment (slug, name)
+SELECT DISTINCT value, initcap(replace(value, '_', ' '))
+FROM exercises, unnest(equipment) AS value
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO muscle_groups (slug, name, position)
+SELECT 'other', 'Other', 7
+WHERE EXISTS (
+    SELECT 1
+    FROM exercises, unnest(primary_muscles || secondary_muscles) AS value
+    WHERE value NOT IN (SELECT slug FROM muscles)
+);
+
+INSERT INTO muscles (slug, name, muscle_group)
+SELECT DISTINCT value, initcap(replace(value, '_', ' ')), 'other'
+FROM exercises, unnest(primary_muscles || secondary_muscles) AS value
+ON CONFLICT (slug) DO NOTHING;
+
+-- Types are not: exercises of an unknown type are logged with weight
+-- and reps, like most exercises.
+UPDATE exercises SET exercise_type = 'weight_reps'
+WHERE exercise_type NOT IN (SELECT slug FROM exercise_types);
+
+ALTER TABLE exercises
+    ADD CONSTRAINT exercises_exercise_type_fkey FOREIGN KEY (exercise_type) REFERENCES exercise_types (slug);
+
+CREATE INDEX muscles_muscle_group_idx ON muscles (muscle_group);
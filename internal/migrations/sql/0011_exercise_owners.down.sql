@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS exercises_owner_id_idx;
+ALTER TABLE exercises DROP COLUMN IF EXISTS owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
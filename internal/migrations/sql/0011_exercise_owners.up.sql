@@ -0,0 +1,8 @@
+-- Exercises without an owner make up the catalogue shared by every
+-- user, which only admins may change. Other exercises are the custom
+-- exercises of their owner.
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE exercises ADD COLUMN owner_id INTEGER REFERENCES users (id) ON DELETE CASCADE;
+
+CREATE INDEX exercises_owner_id_idx ON exercises (owner_id);
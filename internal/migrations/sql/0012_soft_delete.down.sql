@@ -0,0 +1,9 @@
+-- Deleted workouts come back, so only one of them may stay in progress
+UPDATE workouts SET status = 'abandoned' WHERE deleted_at IS NOT NULL AND status = 'in_progress';
+DROP INDEX IF EXISTS workouts_user_id_in_progress_idx;
+CREATE UNIQUE INDEX workouts_user_id_in_progress_idx ON workouts (user_id) WHERE status = 'in_progress';
+
+ALTER TABLE workouts DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE routines DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE programs DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE exercises DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,11 @@
+-- Deleted exercises, programs, routines and workouts are kept until
+-- they are restored, so that the history referencing them stays intact.
+ALTER TABLE exercises ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE programs ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE routines ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE workouts ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- A deleted workout no longer counts as the workout in progress
+DROP INDEX workouts_user_id_in_progress_idx;
+CREATE UNIQUE INDEX workouts_user_id_in_progress_idx ON workouts (user_id)
+    WHERE status = 'in_progress' AND deleted_at IS NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE workout_sets
+    DROP COLUMN IF EXISTS round,
+    DROP COLUMN IF EXISTS group_id,
+    DROP COLUMN IF EXISTS routine_exercise_id;
+
+-- Only the first entry of an exercise in each routine is kept
+DELETE FROM routine_exercises re
+USING routine_exercises earlier
+WHERE earlier.routine_id = re.routine_id
+AND earlier.exercise_id = re.exercise_id
+AND earlier.id < re.id;
+
+DROP INDEX IF EXISTS routine_exercises_routine_id_position_idx;
+ALTER TABLE routine_exercises
+    DROP COLUMN IF EXISTS group_id,
+    DROP COLUMN IF EXISTS position,
+    ADD CONSTRAINT routine_exercises_routine_id_exercise_id_key UNIQUE (routine_id, exercise_id);
+
+DROP TABLE IF EXISTS routine_groups;
@@ -0,0 +1,40 @@
+-- Groups link exercises of a routine that are performed back to back:
+-- supersets alternate between their exercises and circuits cycle
+-- through them, resting after each round. Groups without rounds take
+-- as many rounds as their exercises have sets.
+CREATE TABLE routine_groups (
+    id           SERIAL PRIMARY KEY,
+    routine_id   INTEGER NOT NULL REFERENCES routines (id) ON DELETE CASCADE,
+    kind         TEXT NOT NULL CHECK (kind IN ('superset', 'circuit')),
+    rounds       INTEGER NOT NULL DEFAULT 0 CHECK (rounds >= 0),
+    rest_seconds INTEGER NOT NULL DEFAULT 0 CHECK (rest_seconds >= 0),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX routine_groups_routine_id_idx ON routine_groups (routine_id);
+
+-- Routine exercises are ordered by a 1-based position and may repeat an
+-- exercise, such as a heavy and a light squat. Existing routines keep
+-- the order they were listed in.
+ALTER TABLE routine_exercises
+    DROP CONSTRAINT routine_exercises_routine_id_exercise_id_key,
+    ADD COLUMN position INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN group_id INTEGER REFERENCES routine_groups (id) ON DELETE SET NULL;
+
+UPDATE routine_exercises re
+SET position = numbered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY routine_id ORDER BY id) AS position
+    FROM routine_exercises
+) numbered
+WHERE re.id = numbered.id;
+
+CREATE INDEX routine_exercises_routine_id_position_idx ON routine_exercises (routine_id, position);
+
+-- Sets planned from a routine remember the routine exercise and group
+-- they were planned for, and the round of the group they belong to.
+ALTER TABLE workout_sets
+    ADD COLUMN routine_exercise_id INTEGER REFERENCES routine_exercises (id) ON DELETE SET NULL,
+    ADD COLUMN group_id            INTEGER REFERENCES routine_groups (id) ON DELETE SET NULL,
+    ADD COLUMN round               INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,23 @@
+DROP TABLE IF EXISTS sync_tombstones;
+
+DROP TRIGGER IF EXISTS programs_sync_tombstone ON programs;
+DROP TRIGGER IF EXISTS routines_sync_tombstone ON routines;
+DROP TRIGGER IF EXISTS routine_exercises_sync_tombstone ON routine_exercises;
+DROP TRIGGER IF EXISTS workouts_sync_tombstone ON workouts;
+DROP TRIGGER IF EXISTS workout_sets_sync_tombstone ON workout_sets;
+DROP FUNCTION IF EXISTS record_sync_tombstone();
+
+DROP TRIGGER IF EXISTS programs_change_seq ON programs;
+DROP TRIGGER IF EXISTS routines_change_seq ON routines;
+DROP TRIGGER IF EXISTS routine_exercises_change_seq ON routine_exercises;
+DROP TRIGGER IF EXISTS workouts_change_seq ON workouts;
+DROP TRIGGER IF EXISTS workout_sets_change_seq ON workout_sets;
+DROP FUNCTION IF EXISTS bump_change_seq();
+
+ALTER TABLE programs DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE routines DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE routine_exercises DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE workouts DROP COLUMN IF EXISTS change_seq;
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS change_seq;
+
+DROP SEQUENCE IF EXISTS sync_change_seq;
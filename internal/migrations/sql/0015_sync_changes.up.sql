@@ -0,0 +1,93 @@
+-- Programs, routines, routine exercises, workouts and workout sets are
+-- numbered from a single sequence each time they are written, so that
+-- clients keeping a copy can ask for everything that changed after the
+-- last change they have seen. Existing rows are numbered as they are.
+CREATE SEQUENCE sync_change_seq;
+
+ALTER TABLE programs ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('sync_change_seq');
+ALTER TABLE routines ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('sync_change_seq');
+ALTER TABLE routine_exercises ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('sync_change_seq');
+ALTER TABLE workouts ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('sync_change_seq');
+ALTER TABLE workout_sets ADD COLUMN change_seq BIGINT NOT NULL DEFAULT nextval('sync_change_seq');
+
+CREATE INDEX programs_user_id_change_seq_idx ON programs (user_id, change_seq);
+CREATE INDEX routines_change_seq_idx ON routines (change_seq);
+CREATE INDEX routine_exercises_change_seq_idx ON routine_exercises (change_seq);
+CREATE INDEX workouts_user_id_change_seq_idx ON workouts (user_id, change_seq);
+CREATE INDEX workout_sets_change_seq_idx ON workout_sets (change_seq);
+
+CREATE FUNCTION bump_change_seq() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    NEW.change_seq := nextval('sync_change_seq');
+    RETURN NEW;
+END
+$$;
+
+CREATE TRIGGER programs_change_seq BEFORE UPDATE ON programs
+    FOR EACH ROW EXECUTE FUNCTION bump_change_seq();
+CREATE TRIGGER routines_change_seq BEFORE UPDATE ON routines
+    FOR EACH ROW EXECUTE FUNCTION bump_change_seq();
+CREATE TRIGGER routine_exercises_change_seq BEFORE UPDATE ON routine_exercises
+    FOR EACH ROW EXECUTE FUNCTION bump_change_seq();
+CREATE TRIGGER workouts_change_seq BEFORE UPDATE ON workouts
+    FOR EACH ROW EXECUTE FUNCTION bump_change_seq();
+CREATE TRIGGER workout_sets_change_seq BEFORE UPDATE ON workout_sets
+    FOR EACH ROW EXECUTE FUNCTION bump_change_seq();
+
+-- Deleting one of those rows leaves a tombstone numbered from the same
+-- sequence. Children are deleted before their parents, so the owner of
+-- a row can still be found through them.
+CREATE TABLE sync_tombstones (
+    change_seq BIGINT PRIMARY KEY DEFAULT nextval('sync_change_seq'),
+    user_id    INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    entity     TEXT NOT NULL,
+    entity_id  INTEGER NOT NULL,
+    deleted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX sync_tombstones_user_id_change_seq_idx ON sync_tombstones (user_id, change_seq);
+
+CREATE FUNCTION record_sync_tombstone() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+DECLARE
+    owner_id INTEGER;
+BEGIN
+    CASE TG_ARGV[0]
+    WHEN 'program', 'workout' THEN
+        owner_id := OLD.user_id;
+    WHEN 'routine' THEN
+        SELECT p.user_id INTO owner_id
+        FROM programs p
+        WHERE p.id = OLD.program_id;
+    WHEN 'routine_exercise' THEN
+        SELECT p.user_id INTO owner_id
+        FROM routines r
+        JOIN programs p ON p.id = r.program_id
+        WHERE r.id = OLD.routine_id;
+    WHEN 'workout_set' THEN
+        SELECT w.user_id INTO owner_id
+        FROM workouts w
+        WHERE w.id = OLD.workout_id;
+    END CASE;
+
+    IF owner_id IS NOT NULL THEN
+        INSERT INTO sync_tombstones (user_id, entity, entity_id)
+        VALUES (owner_id, TG_ARGV[0], OLD.id);
+    END IF;
+    RETURN OLD;
+END
+$$;
+
+CREATE TRIGGER programs_sync_tombstone AFTER DELETE ON programs
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('program');
+CREATE TRIGGER routines_sync_tombstone AFTER DELETE ON routines
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('routine');
+CREATE TRIGGER routine_exercises_sync_tombstone AFTER DELETE ON routine_exercises
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('routine_exercise');
+CREATE TRIGGER workouts_sync_tombstone AFTER DELETE ON workouts
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('workout');
+CREATE TRIGGER workout_sets_sync_tombstone AFTER DELETE ON workout_sets
+    FOR EACH ROW EXECUTE FUNCTION record_sync_tombstone('workout_set');
@@ -0,0 +1,29 @@
+CREATE OR REPLACE FUNCTION bump_change_seq() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    NEW.change_seq := nextval('sync_change_seq');
+    RETURN NEW;
+END
+$$;
+
+DROP INDEX IF EXISTS programs_user_id_change_idx;
+DROP INDEX IF EXISTS routines_change_idx;
+DROP INDEX IF EXISTS routine_exercises_change_idx;
+DROP INDEX IF EXISTS workouts_user_id_change_idx;
+DROP INDEX IF EXISTS workout_sets_change_idx;
+DROP INDEX IF EXISTS sync_tombstones_user_id_change_idx;
+
+CREATE INDEX programs_user_id_change_seq_idx ON programs (user_id, change_seq);
+CREATE INDEX routines_change_seq_idx ON routines (change_seq);
+CREATE INDEX routine_exercises_change_seq_idx ON routine_exercises (change_seq);
+CREATE INDEX workouts_user_id_change_seq_idx ON workouts (user_id, change_seq);
+CREATE INDEX workout_sets_change_seq_idx ON workout_sets (change_seq);
+CREATE INDEX sync_tombstones_user_id_change_seq_idx ON sync_tombstones (user_id, change_seq);
+
+ALTER TABLE programs DROP COLUMN IF EXISTS change_xact;
+ALTER TABLE routines DROP COLUMN IF EXISTS change_xact;
+ALTER TABLE routine_exercises DROP COLUMN IF EXISTS change_xact;
+ALTER TABLE workouts DROP COLUMN IF EXISTS change_xact;
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS change_xact;
+ALTER TABLE sync_tombstones DROP COLUMN IF EXISTS change_xact;
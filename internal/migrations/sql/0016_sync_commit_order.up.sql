@@ -0,0 +1,36 @@
+-- Rows are numbered as they are written rather than as their
+-- transaction commits, so a transaction could commit rows numbered
+-- before rows of another that clients had already listed, and that they
+-- would never list. Rows are stamped with the transaction that wrote
+-- them as well, and listed in the order their transactions started,
+-- only once every transaction that started before theirs has finished.
+ALTER TABLE programs ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE routines ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE routine_exercises ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE workouts ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE workout_sets ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+ALTER TABLE sync_tombstones ADD COLUMN change_xact XID8 NOT NULL DEFAULT pg_current_xact_id();
+
+DROP INDEX IF EXISTS programs_user_id_change_seq_idx;
+DROP INDEX IF EXISTS routines_change_seq_idx;
+DROP INDEX IF EXISTS routine_exercises_change_seq_idx;
+DROP INDEX IF EXISTS workouts_user_id_change_seq_idx;
+DROP INDEX IF EXISTS workout_sets_change_seq_idx;
+DROP INDEX IF EXISTS sync_tombstones_user_id_change_seq_idx;
+
+CREATE INDEX programs_user_id_change_idx ON programs (user_id, change_xact, change_seq);
+CREATE INDEX routines_change_idx ON routines (change_xact, change_seq);
+CREATE INDEX routine_exercises_change_idx ON routine_exercises (change_xact, change_seq);
+CREATE INDEX workouts_user_id_change_idx ON workouts (user_id, change_xact, change_seq);
+CREATE INDEX workout_sets_change_idx ON workout_sets (change_xact, change_seq);
+CREATE INDEX sync_tombstones_user_id_change_idx ON sync_tombstones (user_id, change_xact, change_seq);
+
+CREATE OR REPLACE FUNCTION bump_change_seq() RETURNS TRIGGER
+    LANGUAGE plpgsql
+    AS $$
+BEGIN
+    NEW.change_xact := pg_current_xact_id();
+    NEW.change_seq := nextval('sync_change_seq');
+    RETURN NEW;
+END
+$$;
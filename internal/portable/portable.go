@@ -0,0 +1,446 @@
+// Package portable converts programs to and from documents that can be
+// moved between environments and shared with other tools.
+//
+// Documents hold the Program → Routine → RoutineExercise tree of a
+// program, with the supersets and circuits of each routine. Exercises are referenced by name rather than by database ID,
+// and the exercises section describes them so that an environment that
+// lacks one can create it on import.
+package portable
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/soa-rs/fit/internal/progression"
+	"github.com/soa-rs/fit/internal/schedule"
+	"github.com/soa-rs/fit/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the version of the documents written by Encode, and the
+// only one Decode accepts.
+const Version = 1
+
+// defaultCycleDays is the cycle length of programs that do not give one.
+const defaultCycleDays = 7
+
+// Format is the serialization of a document.
+type Format string
+
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+)
+
+// ErrUnknownFormat is returned for formats other than JSON and YAML.
+var ErrUnknownFormat = errors.New("portable: unknown format")
+
+// ParseFormat parses the name of a format. It defaults to JSON.
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "", "json":
+		return JSON, nil
+	case "yaml", "yml":
+		return YAML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// ContentType returns the MIME type of the format.
+func (f Format) ContentType() string {
+	if f == YAML {
+		return "application/yaml"
+	}
+	return "application/json"
+}
+
+// Document is a portable program.
+type Document struct {
+	Version   int        `json:"version" yaml:"version"`
+	Program   Program    `json:"program" yaml:"program"`
+	Exercises []Exercise `json:"exercises" yaml:"exercises"`
+}
+
+type Program struct {
+	Name      string    `json:"name" yaml:"name"`
+	CycleDays int       `json:"cycle_days" yaml:"cycle_days"`
+	Blocks    []Block   `json:"blocks,omitempty" yaml:"blocks,omitempty"`
+	Routines  []Routine `json:"routines" yaml:"routines"`
+}
+
+type Block struct {
+	Name     string  `json:"name,omitempty" yaml:"name,omitempty"`
+	Weeks    int     `json:"weeks" yaml:"weeks"`
+	Deload   bool    `json:"deload,omitempty" yaml:"deload,omitempty"`
+	SetScale float64 `json:"set_scale,omitempty" yaml:"set_scale,omitempty"`
+	RPEScale float64 `json:"rpe_scale,omitempty" yaml:"rpe_scale,omitempty"`
+}
+
+type Routine struct {
+	Name      string            `json:"name" yaml:"name"`
+	DayNumber int               `json:"day_number" yaml:"day_number"`
+	Groups    []Group           `json:"groups,omitempty" yaml:"groups,omitempty"`
+	Exercises []RoutineExercise `json:"exercises" yaml:"exercises"`
+}
+
+// Group is a superset or circuit of the routine.
+type Group struct {
+	Kind        string `json:"kind" yaml:"kind"`
+	Rounds      int    `json:"rounds,omitempty" yaml:"rounds,omitempty"`
+	RestSeconds int    `json:"rest_seconds,omitempty" yaml:"rest_seconds,omitempty"`
+}
+
+// RoutineExercise references its exercise by name, and its group by
+// its 1-based index in the groups of the routine.
+type RoutineExercise struct {
+	Exercise    string       `json:"exercise" yaml:"exercise"`
+	Group       int          `json:"group,omitempty" yaml:"group,omitempty"`
+	Sets        int          `json:"sets,omitempty" yaml:"sets,omitempty"`
+	Reps        int          `json:"reps,omitempty" yaml:"reps,omitempty"`
+	RPE         float64      `json:"rpe,omitempty" yaml:"rpe,omitempty"`
+	Duration    int          `json:"duration,omitempty" yaml:"duration,omitempty"`
+	Distance    float64      `json:"distance,omitempty" yaml:"distance,omitempty"`
+	Progression *Progression `json:"progression,omitempty" yaml:"progression,omitempty"`
+}
+
+type Progression struct {
+	Scheme string                  `json:"scheme" yaml:"scheme"`
+	Config store.ProgressionConfig `json:"config" yaml:"config,omitempty"`
+}
+
+// Exercise describes an exercise referenced by the program.
+type Exercise struct {
+	Name             string   `json:"name" yaml:"name"`
+	ExerciseType     string   `json:"exercise_type" yaml:"exercise_type"`
+	Equipment        []string `json:"equipment,omitempty" yaml:"equipment,omitempty"`
+	PrimaryMuscles   []string `json:"primary_muscles,omitempty" yaml:"primary_muscles,omitempty"`
+	SecondaryMuscles []string `json:"secondary_muscles,omitempty" yaml:"secondary_muscles,omitempty"`
+}
+
+// Key returns the key exercise names are matched by: names are matched
+// ignoring case and surrounding spaces.
+func Key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Export returns the document of a program. The exercises must hold
+// every exercise the routines of the program refer to, and routine
+// exercises refer to their group by its index, as in CreateProgramTree.
+func Export(tree store.ProgramTree, exercises map[int]store.Exercise) Document {
+	doc := Document{
+		Version: Version,
+		Program: Program{
+			Name:      tree.Name,
+			CycleDays: tree.CycleDays,
+			Routines:  []Routine{},
+		},
+		Exercises: []Exercise{},
+	}
+
+	for _, block := range tree.Blocks {
+		doc.Program.Blocks = append(doc.Program.Blocks, Block{
+			Name:     block.Name,
+			Weeks:    block.Weeks,
+			Deload:   block.Deload,
+			SetScale: block.SetScale,
+			RPEScale: block.RPEScale,
+		})
+	}
+
+	described := map[int]bool{}
+	for _, routine := range tree.Routines {
+		exported := Routine{
+			Name:      routine.Name,
+			DayNumber: routine.DayNumber,
+			Exercises: []RoutineExercise{},
+		}
+		for _, group := range routine.Groups {
+			exported.Groups = append(exported.Groups, Group{
+				Kind:        group.Kind,
+				Rounds:      group.Rounds,
+				RestSeconds: group.RestSeconds,
+			})
+		}
+
+		for _, routineExercise := range routine.Exercises {
+			exercise := exercises[routineExercise.ExerciseID]
+			entry := RoutineExercise{
+				Exercise: exercise.Name,
+				Sets:     routineExercise.RecommendedSets,
+				Reps:     routineExercise.RecommendedReps,
+				RPE:      routineExercise.RecommendedRPE,
+				Duration: routineExercise.RecommendedDuration,
+				Distance: routineExercise.RecommendedDistance,
+			}
+			if routineExercise.GroupID != nil {
+				entry.Group = *routineExercise.GroupID
+			}
+			if routineExercise.ProgressionScheme != "" && routineExercise.ProgressionScheme != progression.None {
+				entry.Progression = &Progression{
+					Scheme: routineExercise.ProgressionScheme,
+					Config: routineExercise.ProgressionConfig,
+				}
+			}
+			exported.Exercises = append(exported.Exercises, entry)
+
+			if !described[exercise.ID] {
+				described[exercise.ID] = true
+				doc.Exercises = append(doc.Exercises, Exercise{
+					Name:             exercise.Name,
+					ExerciseType:     exercise.ExerciseType,
+					Equipment:        exercise.Equipment,
+					PrimaryMuscles:   exercise.PrimaryMuscles,
+					SecondaryMuscles: exercise.SecondaryMuscles,
+				})
+			}
+		}
+		doc.Program.Routines = append(doc.Program.Routines, exported)
+	}
+	return doc
+}
+
+// Encode serializes a document.
+func Encode(doc Document, format Format) ([]byte, error) {
+	switch format {
+	case JSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case YAML:
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), encoder.Close()
+	}
+	return nil, ErrUnknownFormat
+}
+
+// Decode parses a document, rejecting fields it does not know.
+func Decode(data []byte, format Format) (Document, error) {
+	var doc Document
+	switch format {
+	case JSON:
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&doc); err != nil {
+			return doc, err
+		}
+	case YAML:
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&doc); err != nil {
+			return doc, err
+		}
+	default:
+		return doc, ErrUnknownFormat
+	}
+	return doc, nil
+}
+
+// Validate fills in the defaults of the document and returns every
+// problem found in it, or nil if it can be imported. Whether the
+// exercises it references exist is left to the caller.
+func (d *Document) Validate() []string {
+	var problems []string
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if d.Version != Version {
+		report("version: Unsupported version %d, expected %d", d.Version, Version)
+	}
+
+	program := &d.Program
+	if strings.TrimSpace(program.Name) == "" {
+		report("program.name: Name is required")
+	}
+	if program.CycleDays == 0 {
+		program.CycleDays = defaultCycleDays
+	}
+	if program.CycleDays < 0 {
+		report("program.cycle_days: Cycle days must be positive")
+	}
+
+	for i := range program.Blocks {
+		block := &program.Blocks[i]
+		if block.Weeks <= 0 {
+			report("program.blocks[%d].weeks: Weeks must be positive", i)
+		}
+		block.SetScale, block.RPEScale = schedule.DefaultScales(block.Deload, block.SetScale, block.RPEScale)
+		if block.SetScale < 0 || block.RPEScale < 0 {
+			report("program.blocks[%d]: Scales must be positive", i)
+		}
+	}
+
+	for i, routine := range program.Routines {
+		if strings.TrimSpace(routine.Name) == "" {
+			report("program.routines[%d].name: Name is required", i)
+		}
+		if routine.DayNumber < 0 {
+			report("program.routines[%d].day_number: Day number must not be negative", i)
+		}
+
+		for j, group := range routine.Groups {
+			path := fmt.Sprintf("program.routines[%d].groups[%d]", i, j)
+			if group.Kind != store.GroupSuperset && group.Kind != store.GroupCircuit {
+				report("%s.kind: Kind must be superset or circuit", path)
+			}
+			if group.Rounds < 0 || group.RestSeconds < 0 {
+				report("%s: Rounds and rest seconds must not be negative", path)
+			}
+		}
+
+		members := make([]int, len(routine.Groups))
+		for j, routineExercise := range routine.Exercises {
+			path := fmt.Sprintf("program.routines[%d].exercises[%d]", i, j)
+			if routineExercise.Group < 0 || routineExercise.Group > len(routine.Groups) {
+				report("%s.group: Group must be between 1 and %d", path, len(routine.Groups))
+			} else if routineExercise.Group > 0 {
+				members[routineExercise.Group-1]++
+			}
+
+			if Key(routineExercise.Exercise) == "" {
+				report("%s.exercise: Exercise is required", path)
+				continue
+			}
+
+			if routineExercise.Progression != nil {
+				err := progression.Validate(routineExercise.Progression.Scheme, routineExercise.Progression.Config)
+				if errors.Is(err, progression.ErrUnknownScheme) {
+					report("%s.progression.scheme: Progression scheme must be one of %s", path, strings.Join(progression.Schemes(), ", "))
+				} else if err != nil {
+					report("%s.progression: %v", path, err)
+				}
+			}
+		}
+
+		for j, count := range members {
+			if count < 2 {
+				report("program.routines[%d].groups[%d]: A group needs at least two exercises", i, j)
+			}
+		}
+	}
+
+	defined := map[string]bool{}
+	for i, exercise := range d.Exercises {
+		key := Key(exercise.Name)
+		if key == "" {
+			report("exercises[%d].name: Name is required", i)
+			continue
+		}
+		if defined[key] {
+			report("exercises[%d].name: %s is described more than once", i, exercise.Name)
+		}
+		defined[key] = true
+
+		if exercise.ExerciseType == "" {
+			report("exercises[%d].exercise_type: Exercise type is required", i)
+		}
+	}
+	return problems
+}
+
+// References returns the keys of the exercises the routines refer to,
+// each once, in the order they first appear.
+func (d Document) References() []string {
+	var keys []string
+	seen := map[string]bool{}
+	for _, routine := range d.Program.Routines {
+		for _, routineExercise := range routine.Exercises {
+			key := Key(routineExercise.Exercise)
+			if key != "" && !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	return keys
+}
+
+// Describe returns the description of the exercise with the key.
+func (d Document) Describe(key string) (store.Exercise, bool) {
+	for _, exercise := range d.Exercises {
+		if Key(exercise.Name) == key {
+			return store.Exercise{
+				Name:             strings.TrimSpace(exercise.Name),
+				ExerciseType:     exercise.ExerciseType,
+				Equipment:        nonNil(exercise.Equipment),
+				PrimaryMuscles:   nonNil(exercise.PrimaryMuscles),
+				SecondaryMuscles: nonNil(exercise.SecondaryMuscles),
+			}, true
+		}
+	}
+	return store.Exercise{}, false
+}
+
+// Tree returns the program of a validated document, owned by the user.
+// The exercise IDs map the key of every referenced exercise to its ID.
+func (d Document) Tree(userID int, exerciseIDs map[string]int) store.ProgramTree {
+	tree := store.ProgramTree{
+		Program: store.Program{
+			UserID:    userID,
+			Name:      strings.TrimSpace(d.Program.Name),
+			CycleDays: d.Program.CycleDays,
+		},
+	}
+
+	for _, block := range d.Program.Blocks {
+		tree.Blocks = append(tree.Blocks, store.ProgramBlock{
+			Name:     block.Name,
+			Weeks:    block.Weeks,
+			Deload:   block.Deload,
+			SetScale: block.SetScale,
+			RPEScale: block.RPEScale,
+		})
+	}
+
+	for _, routine := range d.Program.Routines {
+		imported := store.RoutineTree{
+			Routine: store.Routine{
+				Name:      strings.TrimSpace(routine.Name),
+				DayNumber: routine.DayNumber,
+			},
+		}
+		for _, group := range routine.Groups {
+			imported.Groups = append(imported.Groups, store.RoutineGroup{
+				Kind:        group.Kind,
+				Rounds:      group.Rounds,
+				RestSeconds: group.RestSeconds,
+			})
+		}
+
+		for _, routineExercise := range routine.Exercises {
+			entry := store.RoutineExercise{
+				ExerciseID:          exerciseIDs[Key(routineExercise.Exercise)],
+				RecommendedSets:     routineExercise.Sets,
+				RecommendedReps:     routineExercise.Reps,
+				RecommendedRPE:      routineExercise.RPE,
+				RecommendedDuration: routineExercise.Duration,
+				RecommendedDistance: routineExercise.Distance,
+				ProgressionScheme:   progression.None,
+			}
+			if routineExercise.Group > 0 {
+				group := routineExercise.Group
+				entry.GroupID = &group
+			}
+			if routineExercise.Progression != nil {
+				entry.ProgressionScheme = routineExercise.Progression.Scheme
+				entry.ProgressionConfig = routineExercise.Progression.Config
+			}
+			imported.Exercises = append(imported.Exercises, entry)
+		}
+		tree.Routines = append(tree.Routines, imported)
+	}
+	return tree
+}
+
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
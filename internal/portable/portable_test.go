@@ -0,0 +1,235 @@
+package portable
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/progression"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// testTree returns a program with a block, a deload and a routine
+// pairing two exercises into a superset, as exported.
+func testTree() (store.ProgramTree, map[int]store.Exercise) {
+	group := 1
+	tree := store.ProgramTree{
+		Program: store.Program{UserID: 7, Name: "Strength", CycleDays: 7},
+		Blocks: []store.ProgramBlock{
+			{Name: "Base", Weeks: 3, SetScale: 1, RPEScale: 1},
+			{Name: "Deload", Weeks: 1, Deload: true, SetScale: 0.5, RPEScale: 0.85},
+		},
+		Routines: []store.RoutineTree{{
+			Routine: store.Routine{Name: "Day 1", DayNumber: 1},
+			Groups:  []store.RoutineGroup{{Kind: store.GroupSuperset, Rounds: 3, RestSeconds: 90}},
+			Exercises: []store.RoutineExercise{
+				{
+					ExerciseID: 10, GroupID: &group, RecommendedSets: 3, RecommendedReps: 5, RecommendedRPE: 8,
+					ProgressionScheme: "linear", ProgressionConfig: store.ProgressionConfig{StartWeight: 60, Increment: 2.5},
+				},
+				{ExerciseID: 11, GroupID: &group, RecommendedSets: 3, RecommendedReps: 10, ProgressionScheme: progression.None},
+				{ExerciseID: 10, RecommendedSets: 1, RecommendedReps: 8, ProgressionScheme: progression.None},
+			},
+		}},
+	}
+	exercises := map[int]store.Exercise{
+		10: {ID: 10, Name: "Bench Press", ExerciseType: "weight_reps", Equipment: []string{"barbell"}, PrimaryMuscles: []string{"chest"}},
+		11: {ID: 11, Name: "Pull-Up", ExerciseType: "weight_reps"},
+	}
+	return tree, exercises
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Format
+		wantErr error
+	}{
+		{"", JSON, nil},
+		{"JSON", JSON, nil},
+		{"yaml", YAML, nil},
+		{"yml", YAML, nil},
+		{"xml", "", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.name)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseFormat(%q) = %q, %v, want %q, %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []Format{JSON, YAML} {
+		t.Run(string(format), func(t *testing.T) {
+			tree, exercises := testTree()
+			doc := Export(tree, exercises)
+
+			// Exercises are described once, however often they are used
+			if len(doc.Exercises) != 2 {
+				t.Errorf("Export() described %d exercises, want 2", len(doc.Exercises))
+			}
+
+			data, err := Encode(doc, format)
+			if err != nil {
+				t.Fatalf("Encode() error = %v", err)
+			}
+			decoded, err := Decode(data, format)
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if problems := decoded.Validate(); problems != nil {
+				t.Fatalf("Validate() = %v", problems)
+			}
+
+			if got, want := decoded.References(), []string{"bench press", "pull-up"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("References() = %v, want %v", got, want)
+			}
+			if got := decoded.Tree(7, map[string]int{"bench press": 10, "pull-up": 11}); !reflect.DeepEqual(got, tree) {
+				t.Errorf("Tree() = %+v, want %+v", got, tree)
+			}
+		})
+	}
+}
+
+func TestDecodeRejectsUnknownFields(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   string
+	}{
+		{JSON, `{"version": 1, "program": {"name": "Strength", "owner": 7}}`},
+		{YAML, "version: 1\nprogram:\n  name: Strength\n  owner: 7\n"},
+	}
+
+	for _, tt := range tests {
+		if _, err := Decode([]byte(tt.data), tt.format); err == nil {
+			t.Errorf("Decode(%s) accepted an unknown field", tt.format)
+		}
+	}
+	if _, err := Decode([]byte("{}"), "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("Decode(xml) error = %v, want %v", err, ErrUnknownFormat)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() Document {
+		return Document{
+			Version: Version,
+			Program: Program{
+				Name: "Strength",
+				Routines: []Routine{{
+					Name:      "Day 1",
+					DayNumber: 1,
+					Exercises: []RoutineExercise{{Exercise: "Squat", Sets: 5, Reps: 5}},
+				}},
+			},
+			Exercises: []Exercise{{Name: "Squat", ExerciseType: "weight_reps"}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(*Document)
+		want   []string
+	}{
+		{"valid", func(*Document) {}, nil},
+		{"other version", func(d *Document) { d.Version = 2 }, []string{"version: Unsupported version 2, expected 1"}},
+		{"no name", func(d *Document) { d.Program.Name = " " }, []string{"program.name: Name is required"}},
+		{"negative cycle", func(d *Document) { d.Program.CycleDays = -1 }, []string{"program.cycle_days: Cycle days must be positive"}},
+		{"empty block", func(d *Document) { d.Program.Blocks = []Block{{Name: "Base"}} }, []string{"program.blocks[0].weeks: Weeks must be positive"}},
+		{"negative scale", func(d *Document) { d.Program.Blocks = []Block{{Weeks: 1, SetScale: -1}} }, []string{"program.blocks[0]: Scales must be positive"}},
+		{"routine without name", func(d *Document) { d.Program.Routines[0].Name = "" }, []string{"program.routines[0].name: Name is required"}},
+		{"negative day", func(d *Document) { d.Program.Routines[0].DayNumber = -1 }, []string{"program.routines[0].day_number: Day number must not be negative"}},
+		{"exercise without name", func(d *Document) { d.Program.Routines[0].Exercises[0].Exercise = "" }, []string{"program.routines[0].exercises[0].exercise: Exercise is required"}},
+		{
+			name: "lonely group",
+			change: func(d *Document) {
+				d.Program.Routines[0].Groups = []Group{{Kind: store.GroupCircuit}}
+				d.Program.Routines[0].Exercises[0].Group = 1
+			},
+			want: []string{"program.routines[0].groups[0]: A group needs at least two exercises"},
+		},
+		{
+			name: "unknown group kind",
+			change: func(d *Document) {
+				d.Program.Routines[0].Groups = []Group{{Kind: "giant set", Rounds: -1}}
+				d.Program.Routines[0].Exercises[0].Group = 2
+			},
+			want: []string{
+				"program.routines[0].groups[0].kind: Kind must be superset or circuit",
+				"program.routines[0].groups[0]: Rounds and rest seconds must not be negative",
+				"program.routines[0].exercises[0].group: Group must be between 1 and 1",
+				"program.routines[0].groups[0]: A group needs at least two exercises",
+			},
+		},
+		{
+			name:   "unknown progression",
+			change: func(d *Document) { d.Program.Routines[0].Exercises[0].Progression = &Progression{Scheme: "magic"} },
+			want:   []string{"program.routines[0].exercises[0].progression.scheme: Progression scheme must be one of double, linear, none, rpe, wave"},
+		},
+		{
+			name: "duplicate exercise",
+			change: func(d *Document) {
+				d.Exercises = append(d.Exercises, Exercise{Name: " squat ", ExerciseType: "weight_reps"})
+			},
+			want: []string{"exercises[1].name:  squat  is described more than once"},
+		},
+		{
+			name:   "exercise without type",
+			change: func(d *Document) { d.Exercises[0].ExerciseType = "" },
+			want:   []string{"exercises[0].exercise_type: Exercise type is required"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := valid()
+			tt.change(&doc)
+			if got := doc.Validate(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFillsInDefaults(t *testing.T) {
+	doc := Document{
+		Version: Version,
+		Program: Program{Name: "Strength", Blocks: []Block{{Weeks: 3}, {Weeks: 1, Deload: true}}},
+	}
+	if problems := doc.Validate(); problems != nil {
+		t.Fatalf("Validate() = %v", problems)
+	}
+
+	if doc.Program.CycleDays != defaultCycleDays {
+		t.Errorf("cycle days = %d, want %d", doc.Program.CycleDays, defaultCycleDays)
+	}
+	want := []Block{{Weeks: 3, SetScale: 1, RPEScale: 1}, {Weeks: 1, Deload: true, SetScale: 0.5, RPEScale: 0.85}}
+	if !reflect.DeepEqual(doc.Program.Blocks, want) {
+		t.Errorf("blocks = %+v, want %+v", doc.Program.Blocks, want)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	doc := Document{Exercises: []Exercise{{Name: " Bench Press ", ExerciseType: "weight_reps", PrimaryMuscles: []string{"chest"}}}}
+
+	exercise, ok := doc.Describe(Key("BENCH PRESS"))
+	if !ok {
+		t.Fatal("Describe() found nothing")
+	}
+	want := store.Exercise{
+		Name: "Bench Press", ExerciseType: "weight_reps",
+		Equipment: []string{}, PrimaryMuscles: []string{"chest"}, SecondaryMuscles: []string{},
+	}
+	if !reflect.DeepEqual(exercise, want) {
+		t.Errorf("Describe() = %+v, want %+v", exercise, want)
+	}
+
+	if _, ok := doc.Describe(Key("Squat")); ok {
+		t.Error("Describe() found an exercise that is not described")
+	}
+	if Key("  Squat ") != "squat" {
+		t.Errorf("Key() = %q, want squat", Key("  Squat "))
+	}
+}
@@ -0,0 +1,175 @@
+// Package progression computes the targets of the next session of a
+// routine from the sessions that came before it.
+//
+// Each routine exercise names a Scheme, configured by its
+// store.ProgressionConfig. Schemes are registered by name, and the
+// built-in ones only progress weight_reps exercises: other exercises
+// keep the recommendations of their routine.
+package progression
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// None is the scheme that repeats the recommendations of the routine
+// every session.
+const None = "none"
+
+// ErrUnknownScheme is returned for schemes that are not registered.
+var ErrUnknownScheme = errors.New("progression: unknown scheme")
+
+// defaultIncrement is the step weights are rounded to when the config
+// has no increment.
+const defaultIncrement = 2.5
+
+// Target is what the next session of a routine exercise aims for.
+type Target struct {
+	RoutineExerciseID int     `json:"routine_exercise_id"`
+	ExerciseID        int     `json:"exercise_id"`
+	ExerciseName      string  `json:"exercise_name"`
+	Scheme            string  `json:"progression_scheme"`
+	Sets              int     `json:"sets"`
+	Reps              int     `json:"reps"`
+	Weight            float64 `json:"weight"`
+	RPE               float64 `json:"rpe"`
+	Duration          int     `json:"duration"`
+	Distance          float64 `json:"distance"`
+	// Reason explains how the target was computed.
+	Reason string `json:"reason"`
+}
+
+// Session is the completed sets of an exercise in one workout, warm-up
+// sets excluded.
+type Session struct {
+	WorkoutID   int
+	PerformedAt time.Time
+	Sets        []store.PerformedSet
+}
+
+// TopWeight returns the heaviest weight lifted in the session.
+func (s Session) TopWeight() float64 {
+	var weight float64
+	for _, set := range s.Sets {
+		weight = max(weight, set.Weight)
+	}
+	return weight
+}
+
+// SetsAt returns the sets of the session lifted at the weight.
+func (s Session) SetsAt(weight float64) []store.PerformedSet {
+	var sets []store.PerformedSet
+	for _, set := range s.Sets {
+		if set.Weight == weight {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// Sessions groups sets by workout, oldest first. Sets that are not
+// completed, warm-up sets and sets without weight are left out.
+func Sessions(sets []store.PerformedSet) []Session {
+	sessions := []Session{}
+	index := map[int]int{}
+	for _, set := range sets {
+		if !set.Completed || set.SetType == store.SetWarmup || set.Weight <= 0 {
+			continue
+		}
+
+		i, ok := index[set.WorkoutID]
+		if !ok {
+			i = len(sessions)
+			index[set.WorkoutID] = i
+			sessions = append(sessions, Session{WorkoutID: set.WorkoutID, PerformedAt: set.PerformedAt})
+		}
+		sessions[i].Sets = append(sessions[i].Sets, set)
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].PerformedAt.Before(sessions[j].PerformedAt)
+	})
+	return sessions
+}
+
+// Scheme computes the target of the next session of a routine
+// exercise.
+type Scheme interface {
+	// Validate checks that the config holds what the scheme needs.
+	Validate(config store.ProgressionConfig) error
+	// Next returns the target following the sessions, oldest first.
+	// The target starts out as the recommendations of the routine.
+	Next(exercise store.RoutineExercise, sessions []Session, target Target) Target
+}
+
+var schemes = map[string]Scheme{}
+
+// Register makes a scheme available under the name. It panics if the
+// name is taken, since that is a programming error.
+func Register(name string, scheme Scheme) {
+	if _, ok := schemes[name]; ok {
+		panic("progression: scheme " + name + " registered twice")
+	}
+	schemes[name] = scheme
+}
+
+// Schemes returns the names of the registered schemes, sorted.
+func Schemes() []string {
+	names := make([]string, 0, len(schemes))
+	for name := range schemes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks that the scheme exists and that the config suits it.
+func Validate(name string, config store.ProgressionConfig) error {
+	scheme, ok := schemes[name]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownScheme, name)
+	}
+	return scheme.Validate(config)
+}
+
+// Next computes the target of the next session of a routine exercise
+// from the sets the user performed on it in earlier sessions of the
+// routine.
+func Next(exercise store.RoutineExerciseWithDetails, history []store.PerformedSet) (Target, error) {
+	target := Target{
+		RoutineExerciseID: exercise.ID,
+		ExerciseID:        exercise.ExerciseID,
+		ExerciseName:      exercise.ExerciseName,
+		Scheme:            exercise.ProgressionScheme,
+		Sets:              exercise.RecommendedSets,
+		Reps:              exercise.RecommendedReps,
+		RPE:               exercise.RecommendedRPE,
+		Duration:          exercise.RecommendedDuration,
+		Distance:          exercise.RecommendedDistance,
+		Reason:            "Following the routine's recommendations",
+	}
+
+	scheme, ok := schemes[exercise.ProgressionScheme]
+	if !ok {
+		return target, fmt.Errorf("%w %q", ErrUnknownScheme, exercise.ProgressionScheme)
+	}
+
+	if exercise.ExerciseType != "weight_reps" {
+		return target, nil
+	}
+	return scheme.Next(exercise.RoutineExercise, Sessions(history), target), nil
+}
+
+// round rounds the weight to the increment of the config.
+func round(weight float64, config store.ProgressionConfig) float64 {
+	step := config.Increment
+	if step <= 0 {
+		step = defaultIncrement
+	}
+	return math.Round(weight/step) * step
+}
@@ -0,0 +1,305 @@
+package progression
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// start is when the first test workout was performed; workout n was
+// performed n days later.
+var start = time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC)
+
+// set returns a completed working set of the workout.
+func set(workoutID int, weight float64, reps int, rpe float64) store.PerformedSet {
+	return store.PerformedSet{
+		WorkoutSetWithDetails: store.WorkoutSetWithDetails{
+			WorkoutSet: store.WorkoutSet{
+				WorkoutID: workoutID,
+				SetType:   store.SetWorking,
+				Completed: true,
+				Reps:      reps,
+				Weight:    weight,
+				RPE:       rpe,
+			},
+			ExerciseType: "weight_reps",
+		},
+		PerformedAt: start.AddDate(0, 0, workoutID),
+	}
+}
+
+// warmup returns a completed warm-up set of the workout.
+func warmup(workoutID int, weight float64, reps int) store.PerformedSet {
+	performed := set(workoutID, weight, reps, 0)
+	performed.SetType = store.SetWarmup
+	return performed
+}
+
+// exercise returns a weight_reps routine exercise recommending 3 sets
+// of 5 reps, progressed by the scheme.
+func exercise(scheme string, config store.ProgressionConfig) store.RoutineExerciseWithDetails {
+	return store.RoutineExerciseWithDetails{
+		RoutineExercise: store.RoutineExercise{
+			ID:                1,
+			ExerciseID:        2,
+			RecommendedSets:   3,
+			RecommendedReps:   5,
+			ProgressionScheme: scheme,
+			ProgressionConfig: config,
+		},
+		ExerciseName: "Squat",
+		ExerciseType: "weight_reps",
+	}
+}
+
+func TestSessions(t *testing.T) {
+	incomplete := set(1, 100, 5, 0)
+	incomplete.Completed = false
+
+	sets := []store.PerformedSet{
+		set(2, 105, 5, 0),
+		warmup(1, 60, 5),
+		set(1, 100, 5, 0),
+		incomplete,
+		set(1, 0, 10, 0),
+		set(2, 105, 4, 0),
+	}
+
+	sessions := Sessions(sets)
+	if len(sessions) != 2 {
+		t.Fatalf("Sessions() returned %d sessions, want 2", len(sessions))
+	}
+	if sessions[0].WorkoutID != 1 || sessions[1].WorkoutID != 2 {
+		t.Errorf("Sessions() = workouts %d and %d, want 1 and 2", sessions[0].WorkoutID, sessions[1].WorkoutID)
+	}
+	if len(sessions[0].Sets) != 1 || len(sessions[1].Sets) != 2 {
+		t.Errorf("Sessions() kept %d and %d sets, want 1 and 2", len(sessions[0].Sets), len(sessions[1].Sets))
+	}
+	if got := sessions[1].TopWeight(); got != 105 {
+		t.Errorf("TopWeight() = %g, want 105", got)
+	}
+}
+
+func TestSchemes(t *testing.T) {
+	want := []string{"double", "linear", "none", "rpe", "wave"}
+	if got := Schemes(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Schemes() = %v, want %v", got, want)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  string
+		config  store.ProgressionConfig
+		wantErr bool
+	}{
+		{"none", None, store.ProgressionConfig{}, false},
+		{"unknown", "magic", store.ProgressionConfig{}, true},
+		{"linear", "linear", store.ProgressionConfig{Increment: 2.5}, false},
+		{"linear without increment", "linear", store.ProgressionConfig{}, true},
+		{"double", "double", store.ProgressionConfig{Increment: 2.5, MinReps: 8, MaxReps: 12}, false},
+		{"double with a single rep count", "double", store.ProgressionConfig{Increment: 2.5, MinReps: 8, MaxReps: 8}, false},
+		{"double without increment", "double", store.ProgressionConfig{MinReps: 8, MaxReps: 12}, true},
+		{"double with inverted range", "double", store.ProgressionConfig{Increment: 2.5, MinReps: 12, MaxReps: 8}, true},
+		{"double without range", "double", store.ProgressionConfig{Increment: 2.5}, true},
+		{"rpe", "rpe", store.ProgressionConfig{TargetRPE: 8}, false},
+		{"rpe without target", "rpe", store.ProgressionConfig{}, true},
+		{"rpe above 10", "rpe", store.ProgressionConfig{TargetRPE: 11}, true},
+		{"wave", "wave", store.ProgressionConfig{Waves: []float64{0.7, 0.8, 0.9}}, false},
+		{"wave without waves", "wave", store.ProgressionConfig{}, true},
+		{"wave with a negative fraction", "wave", store.ProgressionConfig{Waves: []float64{-0.5}}, true},
+		{"wave with a large fraction", "wave", store.ProgressionConfig{Waves: []float64{2}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.scheme, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNextFallsBackToRecommendations(t *testing.T) {
+	history := []store.PerformedSet{set(1, 100, 5, 0), set(1, 100, 5, 0), set(1, 100, 5, 0)}
+
+	t.Run("unknown scheme", func(t *testing.T) {
+		target, err := Next(exercise("magic", store.ProgressionConfig{}), history)
+		if !errors.Is(err, ErrUnknownScheme) {
+			t.Errorf("Next() error = %v, want %v", err, ErrUnknownScheme)
+		}
+		if target.Sets != 3 || target.Reps != 5 || target.Weight != 0 {
+			t.Errorf("Next() = %+v, want the recommendations", target)
+		}
+	})
+
+	t.Run("other exercise type", func(t *testing.T) {
+		running := exercise("linear", store.ProgressionConfig{Increment: 2.5})
+		running.ExerciseType = "distance_time"
+		running.RecommendedDistance = 5
+		target, err := Next(running, history)
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if target.Weight != 0 || target.Distance != 5 {
+			t.Errorf("Next() = %+v, want the recommendations", target)
+		}
+	})
+}
+
+func TestNext(t *testing.T) {
+	linear := store.ProgressionConfig{StartWeight: 60, Increment: 2.5}
+	double := store.ProgressionConfig{StartWeight: 40, Increment: 5, MinReps: 8, MaxReps: 12}
+	rpe := store.ProgressionConfig{StartWeight: 50, Increment: 2.5, TargetRPE: 8}
+	wave := store.ProgressionConfig{StartWeight: 70, Increment: 2.5, Waves: []float64{0.7, 0.8, 0.9}}
+
+	tests := []struct {
+		name       string
+		scheme     string
+		config     store.ProgressionConfig
+		history    []store.PerformedSet
+		wantWeight float64
+		wantReps   int
+		wantRPE    float64
+	}{
+		{
+			name:       "none repeats the recommendations",
+			scheme:     None,
+			history:    []store.PerformedSet{set(1, 100, 5, 0)},
+			wantWeight: 0, wantReps: 5,
+		},
+		{
+			name:       "linear starts out at the start weight",
+			scheme:     "linear",
+			config:     linear,
+			wantWeight: 60, wantReps: 5,
+		},
+		{
+			name:       "linear adds the increment once every set is completed",
+			scheme:     "linear",
+			config:     linear,
+			history:    []store.PerformedSet{warmup(1, 60, 5), set(1, 100, 5, 0), set(1, 100, 5, 0), set(1, 100, 6, 0)},
+			wantWeight: 102.5, wantReps: 5,
+		},
+		{
+			name:       "linear repeats the weight after a missed set",
+			scheme:     "linear",
+			config:     linear,
+			history:    []store.PerformedSet{set(1, 100, 5, 0), set(1, 100, 5, 0), set(1, 100, 4, 0)},
+			wantWeight: 100, wantReps: 5,
+		},
+		{
+			name:    "linear follows the last session",
+			scheme:  "linear",
+			config:  linear,
+			history: []store.PerformedSet{set(2, 100, 3, 0), set(1, 95, 5, 0), set(1, 95, 5, 0), set(1, 95, 5, 0)},
+			// The session of workout 2 fell short
+			wantWeight: 100, wantReps: 5,
+		},
+		{
+			name:       "double starts at the bottom of the range",
+			scheme:     "double",
+			config:     double,
+			wantWeight: 40, wantReps: 8,
+		},
+		{
+			name:       "double adds the increment at the top of the range",
+			scheme:     "double",
+			config:     double,
+			history:    []store.PerformedSet{set(1, 50, 12, 0), set(1, 50, 12, 0), set(1, 50, 12, 0)},
+			wantWeight: 55, wantReps: 8,
+		},
+		{
+			name:       "double adds a rep to the weakest set",
+			scheme:     "double",
+			config:     double,
+			history:    []store.PerformedSet{set(1, 50, 12, 0), set(1, 50, 11, 0), set(1, 50, 10, 0)},
+			wantWeight: 50, wantReps: 11,
+		},
+		{
+			name:       "double climbs back to the range",
+			scheme:     "double",
+			config:     double,
+			history:    []store.PerformedSet{set(1, 50, 6, 0), set(1, 50, 6, 0), set(1, 50, 6, 0)},
+			wantWeight: 50, wantReps: 8,
+		},
+		{
+			name:       "double needs every recommended set",
+			scheme:     "double",
+			config:     double,
+			history:    []store.PerformedSet{set(1, 50, 12, 0), set(1, 50, 12, 0)},
+			wantWeight: 50, wantReps: 12,
+		},
+		{
+			name:       "rpe starts out at the start weight",
+			scheme:     "rpe",
+			config:     rpe,
+			wantWeight: 50, wantReps: 5, wantRPE: 8,
+		},
+		{
+			name:       "rpe repeats a set at the target RPE",
+			scheme:     "rpe",
+			config:     rpe,
+			history:    []store.PerformedSet{set(1, 100, 5, 8)},
+			wantWeight: 100, wantReps: 5, wantRPE: 8,
+		},
+		{
+			name:       "rpe lightens a set taken to failure",
+			scheme:     "rpe",
+			config:     rpe,
+			history:    []store.PerformedSet{set(1, 100, 5, 10)},
+			wantWeight: 95, wantReps: 5, wantRPE: 8,
+		},
+		{
+			name:       "wave starts out at the start weight",
+			scheme:     "wave",
+			config:     wave,
+			wantWeight: 70, wantReps: 5,
+		},
+		{
+			name:       "wave moves on to the second wave",
+			scheme:     "wave",
+			config:     wave,
+			history:    []store.PerformedSet{set(1, 100, 1, 0)},
+			wantWeight: 80, wantReps: 5,
+		},
+		{
+			name:       "wave keeps the best estimate",
+			scheme:     "wave",
+			config:     wave,
+			history:    []store.PerformedSet{set(1, 100, 1, 0), set(2, 90, 1, 0)},
+			wantWeight: 90, wantReps: 5,
+		},
+		{
+			name:       "wave starts over after the last wave",
+			scheme:     "wave",
+			config:     wave,
+			history:    []store.PerformedSet{set(1, 100, 1, 0), set(2, 90, 1, 0), set(3, 80, 1, 0)},
+			wantWeight: 70, wantReps: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, err := Next(exercise(tt.scheme, tt.config), tt.history)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if target.Weight != tt.wantWeight || target.Reps != tt.wantReps || target.RPE != tt.wantRPE {
+				t.Errorf("Next() = %g kg x %d @ RPE %g, want %g kg x %d @ RPE %g",
+					target.Weight, target.Reps, target.RPE, tt.wantWeight, tt.wantReps, tt.wantRPE)
+			}
+			if target.Sets != 3 || target.RoutineExerciseID != 1 || target.ExerciseID != 2 {
+				t.Errorf("Next() = %+v, want the sets and IDs of the routine exercise", target)
+			}
+			if target.Reason == "" {
+				t.Error("Next() gave no reason")
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package progression
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+func init() {
+	Register(None, none{})
+	Register("linear", linear{})
+	Register("double", double{})
+	Register("rpe", rpe{})
+	Register("wave", wave{})
+}
+
+// none repeats the recommendations of the routine.
+type none struct{}
+
+func (none) Validate(config store.ProgressionConfig) error {
+	return nil
+}
+
+func (none) Next(exercise store.RoutineExercise, sessions []Session, target Target) Target {
+	return target
+}
+
+// starting sets the target to the starting weight when there is no
+// session to progress from yet.
+func starting(config store.ProgressionConfig, target Target) Target {
+	target.Weight = config.StartWeight
+	target.Reason = "No earlier session, starting out"
+	return target
+}
+
+// linear adds the increment whenever every recommended set was
+// completed for the recommended reps, and repeats the weight
+// otherwise.
+type linear struct{}
+
+func (linear) Validate(config store.ProgressionConfig) error {
+	if config.Increment <= 0 {
+		return errors.New("Linear progression requires an increment greater than 0")
+	}
+	return nil
+}
+
+func (linear) Next(exercise store.RoutineExercise, sessions []Session, target Target) Target {
+	config := exercise.ProgressionConfig
+	if len(sessions) == 0 {
+		return starting(config, target)
+	}
+
+	last := sessions[len(sessions)-1]
+	weight := last.TopWeight()
+	done := 0
+	for _, set := range last.SetsAt(weight) {
+		if set.Reps >= exercise.RecommendedReps {
+			done++
+		}
+	}
+
+	if done >= max(exercise.RecommendedSets, 1) {
+		target.Weight = weight + config.Increment
+		target.Reason = fmt.Sprintf("All sets completed at %g, adding %g", weight, config.Increment)
+		return target
+	}
+
+	target.Weight = weight
+	target.Reason = fmt.Sprintf("Repeating %g until all sets are completed", weight)
+	return target
+}
+
+// double works up the rep range at a weight, then adds the increment
+// and starts again from the bottom of the range.
+type double struct{}
+
+func (double) Validate(config store.ProgressionConfig) error {
+	if config.Increment <= 0 {
+		return errors.New("Double progression requires an increment greater than 0")
+	}
+	if config.MinReps <= 0 || config.MaxReps < config.MinReps {
+		return errors.New("Double progression requires min reps greater than 0 and max reps no lower than min reps")
+	}
+	return nil
+}
+
+func (double) Next(exercise store.RoutineExercise, sessions []Session, target Target) Target {
+	config := exercise.ProgressionConfig
+	if len(sessions) == 0 {
+		target = starting(config, target)
+		target.Reps = config.MinReps
+		return target
+	}
+
+	last := sessions[len(sessions)-1]
+	weight := last.TopWeight()
+	sets := last.SetsAt(weight)
+
+	// The weakest set decides whether the whole range was covered
+	reps := sets[0].Reps
+	for _, set := range sets {
+		reps = min(reps, set.Reps)
+	}
+
+	if reps >= config.MaxReps && len(sets) >= max(exercise.RecommendedSets, 1) {
+		target.Weight = weight + config.Increment
+		target.Reps = config.MinReps
+		target.Reason = fmt.Sprintf("Reached %d reps on every set at %g, adding %g", config.MaxReps, weight, config.Increment)
+		return target
+	}
+
+	target.Weight = weight
+	target.Reps = min(max(reps+1, config.MinReps), config.MaxReps)
+	target.Reason = fmt.Sprintf("Working up to %d reps at %g", config.MaxReps, weight)
+	return target
+}
+
+// rpe picks the weight that should feel like the target RPE for the
+// recommended reps, given the one-rep max estimated from the last
+// session.
+type rpe struct{}
+
+func (rpe) Validate(config store.ProgressionConfig) error {
+	if config.TargetRPE <= 0 || config.TargetRPE > 10 {
+		return errors.New("RPE progression requires a target RPE between 0 and 10")
+	}
+	return nil
+}
+
+func (rpe) Next(exercise store.RoutineExercise, sessions []Session, target Target) Target {
+	config := exercise.ProgressionConfig
+	target.RPE = config.TargetRPE
+	if len(sessions) == 0 || exercise.RecommendedReps <= 0 {
+		return starting(config, target)
+	}
+
+	e1rm := bestOneRepMax(sessions[len(sessions)-1:])
+	reps := training.EffectiveReps(exercise.RecommendedReps, config.TargetRPE)
+	target.Weight = round(training.Epley.Weight(e1rm, reps), config)
+	target.Reason = fmt.Sprintf("Estimated 1RM of %.1f from the last session, aiming for RPE %g", e1rm, config.TargetRPE)
+	return target
+}
+
+// wave cycles through fractions of the best estimated one-rep max, one
+// session after the other.
+type wave struct{}
+
+func (wave) Validate(config store.ProgressionConfig) error {
+	if len(config.Waves) == 0 {
+		return errors.New("Wave progression requires at least one wave")
+	}
+	for _, fraction := range config.Waves {
+		if fraction <= 0 || fraction > 1.5 {
+			return errors.New("Waves must be fractions of the 1RM between 0 and 1.5")
+		}
+	}
+	return nil
+}
+
+func (wave) Next(exercise store.RoutineExercise, sessions []Session, target Target) Target {
+	config := exercise.ProgressionConfig
+	if len(sessions) == 0 {
+		return starting(config, target)
+	}
+
+	e1rm := bestOneRepMax(sessions)
+	fraction := config.Waves[len(sessions)%len(config.Waves)]
+	target.Weight = round(e1rm*fraction, config)
+	target.Reason = fmt.Sprintf("Wave %d of %d at %g%% of an estimated 1RM of %.1f",
+		len(sessions)%len(config.Waves)+1, len(config.Waves), fraction*100, e1rm)
+	return target
+}
+
+// bestOneRepMax returns the best one-rep max estimated over the
+// sessions, adjusting reps for the RPE of each set.
+func bestOneRepMax(sessions []Session) float64 {
+	var best float64
+	for _, session := range sessions {
+		for _, set := range session.Sets {
+			best = max(best, training.Epley.OneRepMax(set.Weight, training.EffectiveReps(set.Reps, set.RPE)))
+		}
+	}
+	return best
+}
@@ -0,0 +1,247 @@
+// Package schedule lays the routines of the programs a user follows out
+// on a calendar.
+//
+// An enrollment starts a program on a date. From then on, the program
+// runs in cycles of its CycleDays days, each routine falling on its
+// day number within the cycle; days without a routine are rest days.
+// Blocks group cycles into phases and end the program after the last
+// one, while programs without blocks repeat their cycle until the
+// enrollment ends.
+package schedule
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// day is the length of a calendar day. Dates are handled in UTC, where
+// every day lasts that long.
+const day = 24 * time.Hour
+
+// Date returns the UTC date of t, as a time at midnight.
+func Date(t time.Time) time.Time {
+	year, month, dayOfMonth := t.UTC().Date()
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, time.UTC)
+}
+
+// Routine is a routine along with its exercises.
+type Routine struct {
+	store.Routine
+	Exercises []store.RoutineExerciseWithDetails
+}
+
+// Plan is everything needed to lay out an enrollment.
+type Plan struct {
+	Enrollment store.Enrollment
+	Program    store.Program
+	Blocks     []store.ProgramBlock
+	Routines   []Routine
+}
+
+// Prescription is what a session asks of an exercise, once scaled by
+// its block.
+type Prescription struct {
+	ExerciseID   int     `json:"exercise_id"`
+	ExerciseName string  `json:"exercise_name"`
+	Sets         int     `json:"sets"`
+	Reps         int     `json:"reps"`
+	RPE          float64 `json:"rpe"`
+	Duration     int     `json:"duration"`
+	Distance     float64 `json:"distance"`
+}
+
+// WorkoutMatch is the workout that was performed for a session.
+type WorkoutMatch struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// Session is a day of an enrollment: either a routine to perform or a
+// rest day.
+type Session struct {
+	Date         string         `json:"date"`
+	EnrollmentID int            `json:"enrollment_id"`
+	ProgramID    int            `json:"program_id"`
+	ProgramName  string         `json:"program_name"`
+	Week         int            `json:"week"`
+	Block        string         `json:"block,omitempty"`
+	Deload       bool           `json:"deload"`
+	Rest         bool           `json:"rest"`
+	RoutineID    int            `json:"routine_id,omitempty"`
+	RoutineName  string         `json:"routine_name,omitempty"`
+	Exercises    []Prescription `json:"exercises,omitempty"`
+	Workout      *WorkoutMatch  `json:"workout"`
+}
+
+// Scales of deload blocks that leave them out: half the sets, at a
+// lower RPE.
+const (
+	DeloadSetScale = 0.5
+	DeloadRPEScale = 0.85
+)
+
+// DefaultScales fills in the scales a block leaves out at 0. Deloads
+// lighten the load even without them, while other blocks keep the
+// recommendations of their routines.
+func DefaultScales(deload bool, setScale, rpeScale float64) (float64, float64) {
+	if setScale == 0 {
+		setScale = 1
+		if deload {
+			setScale = DeloadSetScale
+		}
+	}
+	if rpeScale == 0 {
+		rpeScale = 1
+		if deload {
+			rpeScale = DeloadRPEScale
+		}
+	}
+	return setScale, rpeScale
+}
+
+// blockOf returns the block the cycle falls in, or false if the
+// program is over by then. Programs without blocks never end.
+func blockOf(blocks []store.ProgramBlock, cycle int) (store.ProgramBlock, bool) {
+	if len(blocks) == 0 {
+		return store.ProgramBlock{SetScale: 1, RPEScale: 1}, true
+	}
+
+	for _, block := range blocks {
+		if cycle < block.Weeks {
+			return block, true
+		}
+		cycle -= block.Weeks
+	}
+	return store.ProgramBlock{}, false
+}
+
+// prescribe scales the recommendations of an exercise for a block.
+func prescribe(exercise store.RoutineExerciseWithDetails, block store.ProgramBlock) Prescription {
+	prescription := Prescription{
+		ExerciseID:   exercise.ExerciseID,
+		ExerciseName: exercise.ExerciseName,
+		Sets:         exercise.RecommendedSets,
+		Reps:         exercise.RecommendedReps,
+		RPE:          exercise.RecommendedRPE,
+		Duration:     exercise.RecommendedDuration,
+		Distance:     exercise.RecommendedDistance,
+	}
+
+	if block.SetScale > 0 && prescription.Sets > 0 {
+		prescription.Sets = max(1, int(math.Round(float64(prescription.Sets)*block.SetScale)))
+	}
+	if block.RPEScale > 0 {
+		prescription.RPE = math.Round(prescription.RPE*block.RPEScale*2) / 2
+	}
+	return prescription
+}
+
+// Generate returns the sessions of the plan over the dates in
+// [from, to), ordered by date. Days after the enrollment ended are left
+// out.
+func Generate(plan Plan, from, to time.Time) []Session {
+	sessions := []Session{}
+	cycleDays := max(plan.Program.CycleDays, 1)
+
+	start := Date(plan.Enrollment.StartDate)
+	end := Date(to)
+	if plan.Enrollment.EndedAt != nil {
+		end = minTime(end, Date(*plan.Enrollment.EndedAt).Add(day))
+	}
+
+	for date := maxTime(Date(from), start); date.Before(end); date = date.Add(day) {
+		elapsed := int(date.Sub(start) / day)
+		cycle := elapsed / cycleDays
+		block, ok := blockOf(plan.Blocks, cycle)
+		if !ok {
+			break
+		}
+
+		base := Session{
+			Date:         date.Format(time.DateOnly),
+			EnrollmentID: plan.Enrollment.ID,
+			ProgramID:    plan.Program.ID,
+			ProgramName:  plan.Program.Name,
+			Week:         cycle + 1,
+			Block:        block.Name,
+			Deload:       block.Deload,
+		}
+
+		dayNumber := elapsed%cycleDays + 1
+		rest := true
+		for _, routine := range plan.Routines {
+			if routine.DayNumber != dayNumber {
+				continue
+			}
+			rest = false
+
+			session := base
+			session.RoutineID = routine.ID
+			session.RoutineName = routine.Name
+			session.Exercises = []Prescription{}
+			for _, exercise := range routine.Exercises {
+				session.Exercises = append(session.Exercises, prescribe(exercise, block))
+			}
+			sessions = append(sessions, session)
+		}
+
+		if rest {
+			base.Rest = true
+			sessions = append(sessions, base)
+		}
+	}
+	return sessions
+}
+
+// Match links the sessions to the workouts that followed their routine
+// on their date. Each workout matches at most one session.
+func Match(sessions []Session, workouts []store.WorkoutWithRoutineName) {
+	used := map[int]bool{}
+	for i := range sessions {
+		session := &sessions[i]
+		if session.Rest {
+			continue
+		}
+
+		for _, workout := range workouts {
+			if used[workout.ID] || workout.RoutineID != session.RoutineID {
+				continue
+			}
+			if Date(workout.PerformedAt).Format(time.DateOnly) != session.Date {
+				continue
+			}
+
+			used[workout.ID] = true
+			session.Workout = &WorkoutMatch{ID: workout.ID, Status: workout.Status}
+			break
+		}
+	}
+}
+
+// Sort orders sessions of several enrollments by date, keeping the
+// order of each enrollment's sessions within a day.
+func Sort(sessions []Session) {
+	sort.SliceStable(sessions, func(i, j int) bool {
+		if sessions[i].Date != sessions[j].Date {
+			return sessions[i].Date < sessions[j].Date
+		}
+		return sessions[i].EnrollmentID < sessions[j].EnrollmentID
+	})
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
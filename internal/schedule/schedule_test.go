@@ -0,0 +1,227 @@
+package schedule
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// date returns midnight of a day of January 2024.
+func date(day int) time.Time {
+	return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+}
+
+// testPlan returns an enrollment starting on January 1st in a program
+// of 3-day cycles: a squat day, a bench day and a rest day.
+func testPlan() Plan {
+	return Plan{
+		Enrollment: store.Enrollment{ID: 1, ProgramID: 2, StartDate: date(1).Add(9 * time.Hour)},
+		Program:    store.Program{ID: 2, Name: "Strength", CycleDays: 3},
+		Routines: []Routine{
+			{
+				Routine: store.Routine{ID: 10, Name: "Squat", DayNumber: 1},
+				Exercises: []store.RoutineExerciseWithDetails{{
+					RoutineExercise: store.RoutineExercise{ExerciseID: 100, RecommendedSets: 4, RecommendedReps: 5, RecommendedRPE: 8},
+					ExerciseName:    "Squat",
+				}},
+			},
+			{
+				Routine:   store.Routine{ID: 11, Name: "Bench", DayNumber: 2},
+				Exercises: []store.RoutineExerciseWithDetails{},
+			},
+		},
+	}
+}
+
+// summarize describes each session by its date, routine and week.
+func summarize(sessions []Session) []string {
+	summary := []string{}
+	for _, session := range sessions {
+		name := session.RoutineName
+		if session.Rest {
+			name = "rest"
+		}
+		summary = append(summary, fmt.Sprintf("%s %s w%d", session.Date[len("2024-01-"):], name, session.Week))
+	}
+	return summary
+}
+
+func TestGenerate(t *testing.T) {
+	ended := date(5).Add(15 * time.Hour)
+
+	tests := []struct {
+		name   string
+		blocks []store.ProgramBlock
+		ended  *time.Time
+		from   time.Time
+		to     time.Time
+		want   []string
+	}{
+		{
+			name: "repeats the cycle",
+			from: date(1),
+			to:   date(8),
+			want: []string{"01 Squat w1", "02 Bench w1", "03 rest w1", "04 Squat w2", "05 Bench w2", "06 rest w2", "07 Squat w3"},
+		},
+		{
+			name: "starts at the enrollment",
+			from: date(1).AddDate(0, 0, -3),
+			to:   date(3),
+			want: []string{"01 Squat w1", "02 Bench w1"},
+		},
+		{
+			name: "starts within a cycle",
+			from: date(5).Add(12 * time.Hour),
+			to:   date(7),
+			want: []string{"05 Bench w2", "06 rest w2"},
+		},
+		{
+			name:  "stops on the day the enrollment ended",
+			ended: &ended,
+			from:  date(1),
+			to:    date(10),
+			want:  []string{"01 Squat w1", "02 Bench w1", "03 rest w1", "04 Squat w2", "05 Bench w2"},
+		},
+		{
+			name:   "stops after the last block",
+			blocks: []store.ProgramBlock{{Name: "Base", Weeks: 1}, {Name: "Peak", Weeks: 1}},
+			from:   date(1),
+			to:     date(31),
+			want:   []string{"01 Squat w1", "02 Bench w1", "03 rest w1", "04 Squat w2", "05 Bench w2", "06 rest w2"},
+		},
+		{
+			name: "empty range",
+			from: date(4),
+			to:   date(4),
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := testPlan()
+			plan.Blocks = tt.blocks
+			plan.Enrollment.EndedAt = tt.ended
+
+			if got := summarize(Generate(plan, tt.from, tt.to)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Generate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateScalesBlocks(t *testing.T) {
+	plan := testPlan()
+	plan.Blocks = []store.ProgramBlock{
+		{Name: "Base", Weeks: 1, SetScale: 1, RPEScale: 1},
+		{Name: "Deload", Weeks: 1, Deload: true, SetScale: DeloadSetScale, RPEScale: DeloadRPEScale},
+	}
+
+	sessions := Generate(plan, date(1), date(7))
+	if len(sessions) != 6 {
+		t.Fatalf("Generate() returned %d sessions, want 6", len(sessions))
+	}
+
+	tests := []struct {
+		session Session
+		block   string
+		deload  bool
+		want    Prescription
+	}{
+		{sessions[0], "Base", false, Prescription{ExerciseID: 100, ExerciseName: "Squat", Sets: 4, Reps: 5, RPE: 8}},
+		// RPEs are rounded to half points
+		{sessions[3], "Deload", true, Prescription{ExerciseID: 100, ExerciseName: "Squat", Sets: 2, Reps: 5, RPE: 7}},
+	}
+
+	for _, tt := range tests {
+		if tt.session.Block != tt.block || tt.session.Deload != tt.deload {
+			t.Errorf("session of %s is in block %q (deload %v), want %q (deload %v)",
+				tt.session.Date, tt.session.Block, tt.session.Deload, tt.block, tt.deload)
+		}
+		if want := []Prescription{tt.want}; !reflect.DeepEqual(tt.session.Exercises, want) {
+			t.Errorf("session of %s prescribes %+v, want %+v", tt.session.Date, tt.session.Exercises, want)
+		}
+	}
+}
+
+func TestDefaultScales(t *testing.T) {
+	tests := []struct {
+		name         string
+		deload       bool
+		setScale     float64
+		rpeScale     float64
+		wantSetScale float64
+		wantRPEScale float64
+	}{
+		{"regular block", false, 0, 0, 1, 1},
+		{"deload", true, 0, 0, DeloadSetScale, DeloadRPEScale},
+		{"deload with its own set scale", true, 0.6, 0, 0.6, DeloadRPEScale},
+		{"deload with its own scales", true, 0.6, 0.9, 0.6, 0.9},
+		{"regular block with its own scales", false, 1.2, 1.05, 1.2, 1.05},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setScale, rpeScale := DefaultScales(tt.deload, tt.setScale, tt.rpeScale)
+			if setScale != tt.wantSetScale || rpeScale != tt.wantRPEScale {
+				t.Errorf("DefaultScales() = %g, %g, want %g, %g", setScale, rpeScale, tt.wantSetScale, tt.wantRPEScale)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	workout := func(id, routineID int, performedAt time.Time) store.WorkoutWithRoutineName {
+		return store.WorkoutWithRoutineName{Workout: store.Workout{
+			ID:          id,
+			RoutineID:   routineID,
+			PerformedAt: performedAt,
+			Status:      store.WorkoutCompleted,
+		}}
+	}
+
+	sessions := Generate(testPlan(), date(1), date(7))
+	Match(sessions, []store.WorkoutWithRoutineName{
+		workout(1, 10, date(1).Add(18*time.Hour)),
+		// A second workout of the same routine on the same day
+		workout(2, 10, date(1).Add(20*time.Hour)),
+		// The bench routine on the squat day
+		workout(3, 11, date(4).Add(18*time.Hour)),
+		workout(4, 11, date(5).Add(23*time.Hour)),
+		// Late on the 1st in UTC-5 is the 2nd in UTC
+		workout(5, 11, time.Date(2024, 1, 1, 22, 0, 0, 0, time.FixedZone("EST", -5*60*60))),
+	})
+
+	want := map[string]int{"2024-01-01": 1, "2024-01-02": 5, "2024-01-05": 4}
+	for _, session := range sessions {
+		got := 0
+		if session.Workout != nil {
+			got = session.Workout.ID
+		}
+		if got != want[session.Date] {
+			t.Errorf("session of %s matched workout %d, want %d", session.Date, got, want[session.Date])
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	sessions := []Session{
+		{Date: "2024-01-02", EnrollmentID: 1, RoutineID: 1},
+		{Date: "2024-01-01", EnrollmentID: 2, RoutineID: 2},
+		{Date: "2024-01-01", EnrollmentID: 1, RoutineID: 3},
+		{Date: "2024-01-01", EnrollmentID: 1, RoutineID: 4},
+	}
+
+	Sort(sessions)
+
+	var got []int
+	for _, session := range sessions {
+		got = append(got, session.RoutineID)
+	}
+	if want := []int{3, 4, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort() ordered routines %v, want %v", got, want)
+	}
+}
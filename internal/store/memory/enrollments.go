@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) CreateEnrollment(ctx context.Context, enrollment *store.Enrollment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[enrollment.UserID]; !ok {
+		return errReferenced
+	}
+	if _, ok := s.programs[enrollment.ProgramID]; !ok {
+		return errReferenced
+	}
+	for _, existing := range s.enrollments {
+		if existing.UserID == enrollment.UserID && existing.ProgramID == enrollment.ProgramID && existing.EndedAt == nil {
+			return store.ErrConflict
+		}
+	}
+
+	// Start dates are stored as dates, like the DATE column in Postgres
+	year, month, day := enrollment.StartDate.Date()
+	enrollment.StartDate = time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	enrollment.ID = s.nextID("program_enrollments")
+	enrollment.EndedAt = nil
+	enrollment.CreatedAt = s.now()
+	s.enrollments[enrollment.ID] = *enrollment
+	return nil
+}
+
+func (s *Store) GetEnrollment(ctx context.Context, id int) (store.Enrollment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	enrollment, ok := s.enrollments[id]
+	if !ok {
+		return store.Enrollment{}, store.ErrNotFound
+	}
+	return enrollment, nil
+}
+
+func (s *Store) ListEnrollments(ctx context.Context, userID int, activeOnly bool) ([]store.Enrollment, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	enrollments := []store.Enrollment{}
+	for _, enrollment := range byID(s.enrollments) {
+		if enrollment.UserID != userID || (activeOnly && enrollment.EndedAt != nil) {
+			continue
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+
+	// Most recent first, then by ID descending
+	sort.SliceStable(enrollments, func(i, j int) bool {
+		if !enrollments[i].StartDate.Equal(enrollments[j].StartDate) {
+			return enrollments[i].StartDate.After(enrollments[j].StartDate)
+		}
+		return enrollments[i].ID > enrollments[j].ID
+	})
+	return enrollments, nil
+}
+
+func (s *Store) EndEnrollment(ctx context.Context, enrollment *store.Enrollment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.enrollments[enrollment.ID]
+	if !ok || existing.EndedAt != nil {
+		return store.ErrPrecondition
+	}
+
+	now := s.now()
+	existing.EndedAt = &now
+	s.enrollments[existing.ID] = existing
+	*enrollment = existing
+	return nil
+}
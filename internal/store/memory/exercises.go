@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// copyExercise returns a copy of the exercise that shares no slices
+// with the original.
+func copyExercise(exercise store.Exercise) store.Exercise {
+	if exercise.OwnerID != nil {
+		ownerID := *exercise.OwnerID
+		exercise.OwnerID = &ownerID
+	}
+	exercise.Aliases = clone(exercise.Aliases)
+	exercise.Equipment = clone(exercise.Equipment)
+	exercise.PrimaryMuscles = clone(exercise.PrimaryMuscles)
+	exercise.SecondaryMuscles = clone(exercise.SecondaryMuscles)
+	return exercise
+}
+
+// visibleExercise reports whether the exercise is part of the catalogue
+// or a custom exercise of the user, and has not been deleted.
+func visibleExercise(exercise store.Exercise, userID int) bool {
+	return exercise.DeletedAt == nil && (exercise.OwnerID == nil || *exercise.OwnerID == userID)
+}
+
+func (s *Store) CreateExercise(ctx context.Context, exercise *store.Exercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exercise.ID = s.nextID("exercises")
+	exercise.CreatedAt = s.now()
+	exercise.UpdatedAt = exercise.CreatedAt
+	*exercise = copyExercise(*exercise)
+	s.exercises[exercise.ID] = copyExercise(*exercise)
+	return nil
+}
+
+func (s *Store) GetExercise(ctx context.Context, id int) (store.Exercise, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exercise, ok := s.exercises[id]
+	if !ok {
+		return store.Exercise{}, store.ErrNotFound
+	}
+	return copyExercise(exercise), nil
+}
+
+// exerciseSorts are the fields exercises may be sorted by.
+var exerciseSorts = sortable[store.Exercise]{
+	fields: map[string]func(store.Exercise) interface{}{
+		"name":          func(e store.Exercise) interface{} { return e.Name },
+		"exercise_type": func(e store.Exercise) interface{} { return e.ExerciseType },
+		"created_at":    func(e store.Exercise) interface{} { return e.CreatedAt },
+	},
+	id: func(e store.Exercise) int { return e.ID },
+}
+
+func (s *Store) ListExercises(ctx context.Context, filter store.ExerciseFilter) ([]store.Exercise, store.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exercises := []store.Exercise{}
+	for _, exercise := range byID(s.exercises) {
+		if !visibleExercise(exercise, filter.VisibleTo) {
+			continue
+		}
+		if filter.Type != "" && exercise.ExerciseType != filter.Type {
+			continue
+		}
+		if filter.Name != "" && !strings.EqualFold(exercise.Name, filter.Name) {
+			continue
+		}
+		exercises = append(exercises, copyExercise(exercise))
+	}
+
+	return exerciseSorts.page(exercises, filter.Page, filter.Order(store.DefaultExerciseSort))
+}
+
+// wordSimilarityThreshold is the default threshold of the pg_trgm <%
+// operator.
+const wordSimilarityThreshold = 0.6
+
+// trigrams returns the set of trigrams of the words of a text the way
+// pg_trgm extracts them: each word is lowercased and padded with two
+// spaces before and one after.
+func trigrams(words []string) map[string]bool {
+	set := map[string]bool{}
+	for _, word := range words {
+		padded := "  " + word + " "
+		for i := 0; i+3 <= len(padded); i++ {
+			set[padded[i:i+3]] = true
+		}
+	}
+	return set
+}
+
+// similarity is the share of the trigrams of a and b that they have in
+// common.
+func similarity(a, b map[string]bool) float64 {
+	shared := 0
+	for trigram := range a {
+		if b[trigram] {
+			shared++
+		}
+	}
+	all := len(a) + len(b) - shared
+	if all == 0 {
+		return 0
+	}
+	return float64(shared) / float64(all)
+}
+
+// searchWords splits a text into lowercase words.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchRank ranks an exercise against the words of a query. It stands
+// in for the full-text and trigram matching of the Postgres store: the
+// exercise matches if its name and aliases contain every word of the
+// query, or if a run of their words looks like the query, and ranks
+// higher the closer they are. It returns false if it does not match.
+func searchRank(exercise store.Exercise, query []string) (float64, bool) {
+	words := searchWords(exercise.Name + " " + strings.Join(exercise.Aliases, " "))
+
+	contained := true
+	for _, word := range query {
+		if !slices.Contains(words, word) {
+			contained = false
+			break
+		}
+	}
+
+	best := 0.0
+	queryTrigrams := trigrams(query)
+	for i := range words {
+		end := min(i+len(query), len(words))
+		best = max(best, similarity(queryTrigrams, trigrams(words[i:end])))
+	}
+
+	if !contained && best < wordSimilarityThreshold {
+		return 0, false
+	}
+	if contained {
+		best++
+	}
+	return best, true
+}
+
+// hasValues reports whether values include any of wanted or, with all,
+// every one of them.
+func hasValues(values, wanted []string, all bool) bool {
+	for _, value := range wanted {
+		found := slices.Contains(values, value)
+		if found && !all {
+			return true
+		}
+		if !found && all {
+			return false
+		}
+	}
+	return all
+}
+
+// countFacets adds one to the count of each distinct value.
+func countFacets(counts map[string]int, values ...[]string) {
+	seen := map[string]bool{}
+	for _, list := range values {
+		for _, value := range list {
+			if !seen[value] {
+				seen[value] = true
+				counts[value]++
+			}
+		}
+	}
+}
+
+// sortedFacets orders facet counts, most common first.
+func sortedFacets(counts map[string]int) []store.Facet {
+	facets := []store.Facet{}
+	for value, count := range counts {
+		facets = append(facets, store.Facet{Value: value, Count: count})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
+
+func (s *Store) SearchExercises(ctx context.Context, search store.ExerciseSearch) ([]store.Exercise, int, store.ExerciseFacets, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	query := searchWords(search.Query)
+	equipment := map[string]int{}
+	muscles := map[string]int{}
+	ranks := map[int]float64{}
+
+	exercises := []store.Exercise{}
+	for _, exercise := range byID(s.exercises) {
+		if !visibleExercise(exercise, search.VisibleTo) {
+			continue
+		}
+		if search.Type != "" && exercise.ExerciseType != search.Type {
+			continue
+		}
+		if len(search.Equipment) > 0 && !hasValues(exercise.Equipment, search.Equipment, search.MatchAll) {
+			continue
+		}
+		worked := append(clone(exercise.PrimaryMuscles), exercise.SecondaryMuscles...)
+		if len(search.Muscles) > 0 && !hasValues(worked, search.Muscles, search.MatchAll) {
+			continue
+		}
+		if len(query) > 0 {
+			rank, ok := searchRank(exercise, query)
+			if !ok {
+				continue
+			}
+			ranks[exercise.ID] = rank
+		}
+
+		countFacets(equipment, exercise.Equipment)
+		countFacets(muscles, worked)
+		exercises = append(exercises, copyExercise(exercise))
+	}
+
+	sort.SliceStable(exercises, func(i, j int) bool {
+		if ranks[exercises[i].ID] != ranks[exercises[j].ID] {
+			return ranks[exercises[i].ID] > ranks[exercises[j].ID]
+		}
+		return exercises[i].Name < exercises[j].Name
+	})
+
+	facets := store.ExerciseFacets{
+		Equipment: sortedFacets(equipment),
+		Muscles:   sortedFacets(muscles),
+	}
+	return paginate(exercises, search.Page), len(exercises), facets, nil
+}
+
+func (s *Store) UpdateExercise(ctx context.Context, exercise *store.Exercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.exercises[exercise.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	existing.Name = exercise.Name
+	existing.Aliases = clone(exercise.Aliases)
+	existing.Equipment = clone(exercise.Equipment)
+	existing.PrimaryMuscles = clone(exercise.PrimaryMuscles)
+	existing.SecondaryMuscles = clone(exercise.SecondaryMuscles)
+	existing.ExerciseType = exercise.ExerciseType
+	existing.UpdatedAt = s.now()
+	s.exercises[existing.ID] = existing
+	*exercise = copyExercise(existing)
+	return nil
+}
+
+func (s *Store) DeleteExercise(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.exercises[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	if references := s.exerciseReferences(id); references.RoutineExercises > 0 || references.WorkoutSets > 0 {
+		return store.ErrConflict
+	}
+
+	delete(s.exercises, id)
+	return nil
+}
+
+func (s *Store) SetExerciseDeleted(ctx context.Context, id int, deleted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exercise, ok := s.exercises[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	deletedAt, err := s.setDeleted(exercise.DeletedAt, deleted)
+	if err != nil {
+		return err
+	}
+	exercise.DeletedAt = deletedAt
+	exercise.UpdatedAt = s.now()
+	s.exercises[id] = exercise
+	return nil
+}
+
+func (s *Store) ExerciseReferences(ctx context.Context, id int) (store.ExerciseReferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.exerciseReferences(id), nil
+}
+
+// exerciseReferences counts the rows that would make deleting the
+// exercise violate a foreign key in Postgres.
+func (s *Store) exerciseReferences(id int) store.ExerciseReferences {
+	var references store.ExerciseReferences
+	for _, routineExercise := range s.routineExercises {
+		if routineExercise.ExerciseID == id {
+			references.RoutineExercises++
+		}
+	}
+	for _, set := range s.workoutSets {
+		if set.ExerciseID == id {
+			references.WorkoutSets++
+		}
+	}
+	return references
+}
+
+func (s *Store) ExerciseOwner(ctx context.Context, id int) (store.Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exercise, ok := s.exercises[id]
+	if !ok {
+		return store.Owner{}, store.ErrNotFound
+	}
+	if exercise.OwnerID == nil {
+		return store.Owner{Public: true, Deleted: exercise.DeletedAt != nil}, nil
+	}
+	return store.Owner{UserID: *exercise.OwnerID, Deleted: exercise.DeletedAt != nil}, nil
+}
+
+func (s *Store) MergeExercise(ctx context.Context, fromID, intoID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.exercises[fromID]; !ok {
+		return store.ErrNotFound
+	}
+
+	for id, routineExercise := range s.routineExercises {
+		if routineExercise.ExerciseID == fromID {
+			routineExercise.ExerciseID = intoID
+			s.routineExercises[id] = routineExercise
+		}
+	}
+
+	// Set indexes start at 1, so shifting the merged sets by the last
+	// index of the other exercise keeps them unique
+	lastIndex := map[int]int{}
+	for _, set := range s.workoutSets {
+		if set.ExerciseID == intoID {
+			lastIndex[set.WorkoutID] = max(lastIndex[set.WorkoutID], set.SetIndex)
+		}
+	}
+	for id, set := range s.workoutSets {
+		if set.ExerciseID == fromID {
+			set.ExerciseID = intoID
+			set.SetIndex += lastIndex[set.WorkoutID]
+			s.workoutSets[id] = set
+		}
+	}
+
+	// Records only ever improve, so drop those that an earlier record of
+	// the other exercise was already at least as good as
+	for id, record := range s.personalRecords {
+		if record.ExerciseID == fromID {
+			record.ExerciseID = intoID
+			s.personalRecords[id] = record
+		}
+	}
+	var records []store.PersonalRecord
+	for _, record := range byID(s.personalRecords) {
+		if record.ExerciseID == intoID {
+			records = append(records, record)
+		}
+	}
+	for _, record := range records {
+		for _, earlier := range records {
+			if earlier.ID < record.ID && earlier.UserID == record.UserID && earlier.Kind == record.Kind &&
+				earlier.Weight == record.Weight && !record.Beats(earlier) {
+				delete(s.personalRecords, record.ID)
+				break
+			}
+		}
+	}
+
+	delete(s.exercises, fromID)
+	return nil
+}
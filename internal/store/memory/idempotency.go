@@ -0,0 +1,75 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// idempotencyKeyID identifies a row of the idempotency_keys table.
+type idempotencyKeyID struct {
+	userID int
+	key    string
+}
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key *store.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[key.UserID]; !ok {
+		return errReferenced
+	}
+
+	now := s.now()
+	for id, existing := range s.idempotencyKeys {
+		if id.userID != key.UserID {
+			continue
+		}
+		expired := !existing.ExpiresAt.After(now)
+		abandoned := existing.StatusCode == 0 && !existing.CreatedAt.Add(store.IdempotencyLease).After(now)
+		if expired || abandoned {
+			delete(s.idempotencyKeys, id)
+		}
+	}
+
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+	if existing, ok := s.idempotencyKeys[id]; ok {
+		*key = existing
+		key.Body = append([]byte(nil), existing.Body...)
+		return store.ErrConflict
+	}
+
+	key.CreatedAt = now
+	s.idempotencyKeys[id] = *key
+	return nil
+}
+
+func (s *Store) SaveIdempotentResponse(ctx context.Context, key *store.IdempotencyKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKeyID{userID: key.UserID, key: key.Key}
+	existing, ok := s.idempotencyKeys[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	existing.StatusCode = key.StatusCode
+	existing.ContentType = key.ContentType
+	existing.Body = append([]byte(nil), key.Body...)
+	s.idempotencyKeys[id] = existing
+	return nil
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := idempotencyKeyID{userID: userID, key: key}
+	if _, ok := s.idempotencyKeys[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	delete(s.idempotencyKeys, id)
+	return nil
+}
@@ -0,0 +1,274 @@
+// Package memory implements the store interfaces in memory.
+//
+// It mirrors the behaviour of the Postgres store, including ordering,
+// pagination totals, uniqueness conflicts and cascading deletes, so it
+// can stand in for Postgres in tests and demos. Nothing is persisted.
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+var _ store.Store = (*Store)(nil)
+
+// errReferenced mirrors a foreign key violation in Postgres: the row
+// cannot be deleted while other rows still reference it.
+var errReferenced = errors.New("memory: row is still referenced")
+
+// refreshToken is a row of the refresh_tokens table.
+type refreshToken struct {
+	userID    int
+	expiresAt time.Time
+	revokedAt time.Time
+}
+
+// Store implements every store interface in memory. It is safe for
+// concurrent use.
+type Store struct {
+	mu sync.RWMutex
+
+	// lastID holds the last ID handed out per table, like a Postgres
+	// sequence.
+	lastID map[string]int
+
+	users            map[int]store.User
+	refreshTokens    map[string]refreshToken
+	exercises        map[int]store.Exercise
+	programs         map[int]store.Program
+	routines         map[int]store.Routine
+	routineExercises map[int]store.RoutineExercise
+	routineGroups    map[int]store.RoutineGroup
+	workouts         map[int]store.Workout
+	workoutSets      map[int]store.WorkoutSet
+	personalRecords  map[int]store.PersonalRecord
+	programBlocks    map[int]store.ProgramBlock
+	enrollments      map[int]store.Enrollment
+	tracks           map[int]store.Track
+	idempotencyKeys  map[idempotencyKeyID]store.IdempotencyKey
+
+	// lastChange, synced and tombstones track the changes listed by
+	// ListChanges.
+	lastChange int64
+	synced     map[syncKey]syncedRow
+	tombstones []tombstone
+
+	now func() time.Time
+}
+
+// New creates an empty Store.
+func New() *Store {
+	return &Store{
+		lastID:           map[string]int{},
+		users:            map[int]store.User{},
+		refreshTokens:    map[string]refreshToken{},
+		exercises:        map[int]store.Exercise{},
+		programs:         map[int]store.Program{},
+		routines:         map[int]store.Routine{},
+		routineExercises: map[int]store.RoutineExercise{},
+		routineGroups:    map[int]store.RoutineGroup{},
+		workouts:         map[int]store.Workout{},
+		workoutSets:      map[int]store.WorkoutSet{},
+		personalRecords:  map[int]store.PersonalRecord{},
+		programBlocks:    map[int]store.ProgramBlock{},
+		enrollments:      map[int]store.Enrollment{},
+		tracks:           map[int]store.Track{},
+		idempotencyKeys:  map[idempotencyKeyID]store.IdempotencyKey{},
+		synced:           map[syncKey]syncedRow{},
+		now:              time.Now,
+	}
+}
+
+// nextID returns the next ID of the table.
+func (s *Store) nextID(table string) int {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// byID returns the rows of a table ordered by ID.
+func byID[T any](rows map[int]T) []T {
+	ids := make([]int, 0, len(rows))
+	for id := range rows {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	values := make([]T, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, rows[id])
+	}
+	return values
+}
+
+// paginate returns the window of items selected by the page.
+func paginate[T any](items []T, page store.Page) []T {
+	if page.Offset >= len(items) {
+		return items[:0]
+	}
+	items = items[page.Offset:]
+	if page.Limit < len(items) {
+		items = items[:page.Limit]
+	}
+	return items
+}
+
+// sortable gives the value of each field a list may be sorted by, an
+// int, a string or a time.Time, and the ID that breaks ties.
+type sortable[T any] struct {
+	fields map[string]func(item T) interface{}
+	id     func(item T) int
+}
+
+// compareValues compares two values of a sort field.
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		return cmpInts(a, b.(int))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	return 0
+}
+
+func cmpInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// formatValue formats the value of a sort field for a cursor.
+func formatValue(value interface{}) string {
+	switch value := value.(type) {
+	case int:
+		return strconv.Itoa(value)
+	case time.Time:
+		return value.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// parseValue parses the value of a sort field from a cursor, like the
+// value of the field in another item.
+func parseValue(value string, like interface{}) (interface{}, error) {
+	switch like.(type) {
+	case int:
+		return strconv.Atoi(value)
+	case time.Time:
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return value, nil
+}
+
+// page sorts the items by keys and returns the page of them selected by
+// page, like the Postgres store. It returns ErrPrecondition if the
+// cursor of the page holds invalid values.
+func (t sortable[T]) page(items []T, page store.Page, keys []store.SortKey) ([]T, store.PageInfo, error) {
+	total := -1
+	if page.Count {
+		total = len(items)
+	}
+
+	// compare compares an item with the values and ID of another
+	compare := func(item T, values []interface{}, id int) int {
+		for i, key := range keys {
+			c := compareValues(t.fields[key.Field](item), values[i])
+			if key.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return cmpInts(t.id(item), id)
+	}
+	valuesOf := func(item T) []interface{} {
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = t.fields[key.Field](item)
+		}
+		return values
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return compare(items[i], valuesOf(items[j]), t.id(items[j])) < 0
+	})
+
+	switch {
+	case page.Cursor != nil:
+		// Items past the cursor, closest first
+		var zero T
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			value, err := parseValue(page.Cursor.Values[i], t.fields[key.Field](zero))
+			if err != nil {
+				return nil, store.PageInfo{}, store.ErrPrecondition
+			}
+			values[i] = value
+		}
+
+		past := items[:0]
+		for _, item := range items {
+			c := compare(item, values, page.Cursor.ID)
+			if page.Cursor.Backward && c < 0 || !page.Cursor.Backward && c > 0 {
+				past = append(past, item)
+			}
+		}
+		if page.Cursor.Backward {
+			for i, j := 0, len(past)-1; i < j; i, j = i+1, j-1 {
+				past[i], past[j] = past[j], past[i]
+			}
+		}
+		items = past
+	case page.Offset < len(items):
+		items = items[page.Offset:]
+	default:
+		items = items[:0]
+	}
+
+	if len(items) > page.Limit+1 {
+		items = items[:page.Limit+1]
+	}
+	cursors := make([]store.Cursor, len(items))
+	for i, item := range items {
+		cursors[i].ID = t.id(item)
+		for _, value := range valuesOf(item) {
+			cursors[i].Values = append(cursors[i].Values, formatValue(value))
+		}
+	}
+
+	items, info := store.FinishPage(items, cursors, page)
+	info.Total = total
+	return items, info, nil
+}
+
+// setDeleted returns the deleted_at of a row that is soft deleted or
+// restored, or ErrPrecondition if the row is already in that state.
+func (s *Store) setDeleted(deletedAt *time.Time, deleted bool) (*time.Time, error) {
+	if (deletedAt != nil) == deleted {
+		return nil, store.ErrPrecondition
+	}
+	if !deleted {
+		return nil, nil
+	}
+	now := s.now()
+	return &now, nil
+}
+
+// clone copies a slice so that callers cannot mutate stored rows. Like
+// the NOT NULL array columns in Postgres, it never returns nil.
+func clone(values []string) []string {
+	return append([]string{}, values...)
+}
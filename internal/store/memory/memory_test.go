@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/store/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return New()
+	})
+}
+
+func TestExercisesAreCopied(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+
+	exercise := store.Exercise{Name: "Squat", ExerciseType: "weight_reps", PrimaryMuscles: []string{"quads"}}
+	if err := s.CreateExercise(ctx, &exercise); err != nil {
+		t.Fatalf("CreateExercise() error = %v", err)
+	}
+	exercise.PrimaryMuscles[0] = "glutes"
+
+	got, err := s.GetExercise(ctx, exercise.ID)
+	if err != nil {
+		t.Fatalf("GetExercise() error = %v", err)
+	}
+	got.PrimaryMuscles[0] = "calves"
+
+	// Neither the exercise created nor the one read share the stored row
+	got, _ = s.GetExercise(ctx, exercise.ID)
+	if got.PrimaryMuscles[0] != "quads" {
+		t.Errorf("stored primary muscles = %v, want [quads]", got.PrimaryMuscles)
+	}
+	if got.Equipment == nil || got.SecondaryMuscles == nil {
+		t.Error("GetExercise() returned nil slices")
+	}
+}
+
+func TestIdempotencyKeysAreReclaimed(t *testing.T) {
+	s := New()
+	ctx := context.Background()
+	user := store.User{Email: "user@example.com"}
+	if err := s.CreateUser(ctx, &user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	now := time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	reserve := func(name string) error {
+		return s.ReserveIdempotencyKey(ctx, &store.IdempotencyKey{UserID: user.ID, Key: name, ExpiresAt: now.Add(time.Hour)})
+	}
+	for _, name := range []string{"pending", "stored"} {
+		if err := reserve(name); err != nil {
+			t.Fatalf("ReserveIdempotencyKey(%s) error = %v", name, err)
+		}
+	}
+	stored := store.IdempotencyKey{UserID: user.ID, Key: "stored", StatusCode: 201}
+	if err := s.SaveIdempotentResponse(ctx, &stored); err != nil {
+		t.Fatalf("SaveIdempotentResponse() error = %v", err)
+	}
+
+	// Keys still in progress are only held for the lease, and stored
+	// responses until they expire
+	tests := []struct {
+		after   time.Duration
+		key     string
+		wantErr error
+	}{
+		{store.IdempotencyLease - time.Second, "pending", store.ErrConflict},
+		{store.IdempotencyLease, "pending", nil},
+		{store.IdempotencyLease, "stored", store.ErrConflict},
+		{time.Hour, "stored", nil},
+	}
+
+	start := now
+	for _, tt := range tests {
+		now = start.Add(tt.after)
+		if err := reserve(tt.key); !errors.Is(err, tt.wantErr) {
+			t.Errorf("ReserveIdempotencyKey(%s) after %v error = %v, want %v", tt.key, tt.after, err, tt.wantErr)
+		}
+	}
+}
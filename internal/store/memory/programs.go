@@ -0,0 +1,384 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) CreateProgram(ctx context.Context, program *store.Program) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[program.UserID]; !ok {
+		return errReferenced
+	}
+
+	program.ID = s.nextID("programs")
+	program.ForkedFromProgramID = nil
+	program.ForkCount = 0
+	program.CreatedAt = s.now()
+	program.UpdatedAt = program.CreatedAt
+	s.programs[program.ID] = *program
+	return nil
+}
+
+func (s *Store) ForkProgram(ctx context.Context, fork *store.Program) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if fork.ForkedFromProgramID == nil {
+		return store.ErrNotFound
+	}
+	original, ok := s.programs[*fork.ForkedFromProgramID]
+	if !ok || original.DeletedAt != nil {
+		return store.ErrNotFound
+	}
+	if _, ok := s.users[fork.UserID]; !ok {
+		return errReferenced
+	}
+
+	originalID := original.ID
+	fork.ID = s.nextID("programs")
+	fork.CycleDays = original.CycleDays
+	fork.ForkedFromProgramID = &originalID
+	fork.ForkCount = 0
+	fork.CreatedAt = s.now()
+	fork.UpdatedAt = fork.CreatedAt
+	s.programs[fork.ID] = *fork
+
+	original.ForkCount++
+	s.programs[original.ID] = original
+
+	for _, block := range byID(s.programBlocks) {
+		if block.ProgramID != original.ID {
+			continue
+		}
+		block.ID = s.nextID("program_blocks")
+		block.ProgramID = fork.ID
+		block.CreatedAt = s.now()
+		block.UpdatedAt = block.CreatedAt
+		s.programBlocks[block.ID] = block
+	}
+
+	// Custom exercises of other users are copied into the catalogue of
+	// the user forking the program, who could not see them otherwise
+	exerciseIDs := map[int]int{}
+	forkExercise := func(id int) int {
+		if copyID, ok := exerciseIDs[id]; ok {
+			return copyID
+		}
+		exercise := s.exercises[id]
+		if exercise.OwnerID == nil || *exercise.OwnerID == fork.UserID {
+			return id
+		}
+		exercise = copyExercise(exercise)
+		ownerID := fork.UserID
+		exercise.ID = s.nextID("exercises")
+		exercise.OwnerID = &ownerID
+		exercise.CreatedAt = s.now()
+		exercise.UpdatedAt = exercise.CreatedAt
+		exercise.DeletedAt = nil
+		s.exercises[exercise.ID] = exercise
+		exerciseIDs[id] = exercise.ID
+		return exercise.ID
+	}
+
+	for _, routine := range byID(s.routines) {
+		if routine.ProgramID != original.ID || routine.DeletedAt != nil {
+			continue
+		}
+		originalRoutineID := routine.ID
+		routine.ID = s.nextID("routines")
+		routine.ProgramID = fork.ID
+		routine.CreatedAt = s.now()
+		routine.UpdatedAt = routine.CreatedAt
+		s.routines[routine.ID] = routine
+
+		groupIDs := map[int]int{}
+		for _, group := range byID(s.routineGroups) {
+			if group.RoutineID != originalRoutineID {
+				continue
+			}
+			originalGroupID := group.ID
+			group.ID = s.nextID("routine_groups")
+			group.RoutineID = routine.ID
+			group.CreatedAt = s.now()
+			group.UpdatedAt = group.CreatedAt
+			s.routineGroups[group.ID] = group
+			groupIDs[originalGroupID] = group.ID
+		}
+
+		for _, routineExercise := range byID(s.routineExercises) {
+			if routineExercise.RoutineID != originalRoutineID {
+				continue
+			}
+			if routineExercise.GroupID != nil {
+				groupID := groupIDs[*routineExercise.GroupID]
+				routineExercise.GroupID = &groupID
+			}
+			routineExercise.ID = s.nextID("routine_exercises")
+			routineExercise.RoutineID = routine.ID
+			routineExercise.ExerciseID = forkExercise(routineExercise.ExerciseID)
+			routineExercise.ProgressionConfig = copyConfig(routineExercise.ProgressionConfig)
+			routineExercise.CreatedAt = s.now()
+			routineExercise.UpdatedAt = routineExercise.CreatedAt
+			s.routineExercises[routineExercise.ID] = routineExercise
+		}
+	}
+	return nil
+}
+
+func (s *Store) CreateProgramTree(ctx context.Context, tree *store.ProgramTree) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Check every reference up front so that nothing is written if the
+	// tree cannot be created, like a rolled back transaction
+	if _, ok := s.users[tree.UserID]; !ok {
+		return errReferenced
+	}
+	for _, routine := range tree.Routines {
+		for _, routineExercise := range routine.Exercises {
+			if _, ok := s.exercises[routineExercise.ExerciseID]; !ok {
+				return errReferenced
+			}
+		}
+	}
+
+	program := &tree.Program
+	program.ID = s.nextID("programs")
+	program.ForkedFromProgramID = nil
+	program.ForkCount = 0
+	program.CreatedAt = s.now()
+	program.UpdatedAt = program.CreatedAt
+	s.programs[program.ID] = *program
+
+	for i := range tree.Blocks {
+		block := &tree.Blocks[i]
+		block.ID = s.nextID("program_blocks")
+		block.ProgramID = program.ID
+		block.Position = i + 1
+		block.CreatedAt = s.now()
+		block.UpdatedAt = block.CreatedAt
+		s.programBlocks[block.ID] = *block
+	}
+
+	for i := range tree.Routines {
+		routine := &tree.Routines[i]
+		routine.ID = s.nextID("routines")
+		routine.ProgramID = program.ID
+		routine.CreatedAt = s.now()
+		routine.UpdatedAt = routine.CreatedAt
+		s.routines[routine.ID] = routine.Routine
+
+		for j := range routine.Groups {
+			group := &routine.Groups[j]
+			group.ID = s.nextID("routine_groups")
+			group.RoutineID = routine.ID
+			group.RoutineExerciseIDs = []int{}
+			group.CreatedAt = s.now()
+			group.UpdatedAt = group.CreatedAt
+			stored := *group
+			stored.RoutineExerciseIDs = nil
+			s.routineGroups[group.ID] = stored
+		}
+
+		for j := range routine.Exercises {
+			routineExercise := &routine.Exercises[j]
+			routineExercise.ID = s.nextID("routine_exercises")
+			routineExercise.RoutineID = routine.ID
+			routineExercise.Position = j + 1
+			routineExercise.CreatedAt = s.now()
+			routineExercise.UpdatedAt = routineExercise.CreatedAt
+			stored := *routineExercise
+			stored.ProgressionConfig = copyConfig(stored.ProgressionConfig)
+
+			// Exercises refer to their group by its index in the tree
+			if routineExercise.GroupID != nil {
+				group := &routine.Groups[*routineExercise.GroupID-1]
+				group.RoutineExerciseIDs = append(group.RoutineExerciseIDs, routineExercise.ID)
+				groupID := group.ID
+				stored.GroupID = &groupID
+			}
+			s.routineExercises[routineExercise.ID] = stored
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetProgram(ctx context.Context, id int) (store.Program, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	program, ok := s.programs[id]
+	if !ok {
+		return store.Program{}, store.ErrNotFound
+	}
+	return program, nil
+}
+
+// visible reports whether the program is owned by the user or public,
+// and has not been deleted.
+func (s *Store) visible(programID, userID int) bool {
+	program, ok := s.programs[programID]
+	return ok && program.DeletedAt == nil && (program.UserID == userID || program.IsPublic)
+}
+
+// programSorts are the fields programs may be sorted by.
+var programSorts = sortable[store.Program]{
+	fields: map[string]func(store.Program) interface{}{
+		"name":       func(p store.Program) interface{} { return p.Name },
+		"cycle_days": func(p store.Program) interface{} { return p.CycleDays },
+		"created_at": func(p store.Program) interface{} { return p.CreatedAt },
+		"updated_at": func(p store.Program) interface{} { return p.UpdatedAt },
+	},
+	id: func(p store.Program) int { return p.ID },
+}
+
+func (s *Store) ListPrograms(ctx context.Context, filter store.ProgramFilter) ([]store.Program, store.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	programs := []store.Program{}
+	for _, program := range byID(s.programs) {
+		if s.visible(program.ID, filter.VisibleTo) {
+			programs = append(programs, program)
+		}
+	}
+
+	return programSorts.page(programs, filter.Page, filter.Order(store.DefaultProgramSort))
+}
+
+func (s *Store) UpdateProgram(ctx context.Context, program *store.Program) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.programs[program.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	existing.Name = program.Name
+	existing.IsPublic = program.IsPublic
+	existing.CycleDays = program.CycleDays
+	existing.UpdatedAt = s.now()
+	s.programs[existing.ID] = existing
+	*program = existing
+	return nil
+}
+
+func (s *Store) DeleteProgram(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.programs[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	// Delete the routines of this program along with their exercises
+	for _, routine := range s.routines {
+		if routine.ProgramID == id {
+			s.deleteRoutine(routine.ID)
+		}
+	}
+
+	// Delete the blocks and enrollments of this program
+	for blockID, block := range s.programBlocks {
+		if block.ProgramID == id {
+			delete(s.programBlocks, blockID)
+		}
+	}
+	for enrollmentID, enrollment := range s.enrollments {
+		if enrollment.ProgramID == id {
+			delete(s.enrollments, enrollmentID)
+		}
+	}
+
+	// Forks outlive the program they were forked from
+	for forkID, fork := range s.programs {
+		if fork.ForkedFromProgramID != nil && *fork.ForkedFromProgramID == id {
+			fork.ForkedFromProgramID = nil
+			s.programs[forkID] = fork
+		}
+	}
+
+	delete(s.programs, id)
+	return nil
+}
+
+func (s *Store) ProgramOwner(ctx context.Context, id int) (store.Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	program, ok := s.programs[id]
+	if !ok {
+		return store.Owner{}, store.ErrNotFound
+	}
+	return store.Owner{UserID: program.UserID, Public: program.IsPublic, Deleted: program.DeletedAt != nil}, nil
+}
+
+func (s *Store) SetProgramDeleted(ctx context.Context, id int, deleted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	program, ok := s.programs[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	deletedAt, err := s.setDeleted(program.DeletedAt, deleted)
+	if err != nil {
+		return err
+	}
+	program.DeletedAt = deletedAt
+	program.UpdatedAt = s.now()
+	s.programs[id] = program
+	return nil
+}
+
+// -------------------- Program blocks --------------------
+
+func (s *Store) ListProgramBlocks(ctx context.Context, programID int) ([]store.ProgramBlock, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	blocks := []store.ProgramBlock{}
+	for _, block := range byID(s.programBlocks) {
+		if block.ProgramID == programID {
+			blocks = append(blocks, block)
+		}
+	}
+
+	sort.SliceStable(blocks, func(i, j int) bool {
+		return blocks[i].Position < blocks[j].Position
+	})
+	return blocks, nil
+}
+
+func (s *Store) SetProgramBlocks(ctx context.Context, programID int, blocks []store.ProgramBlock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.programs[programID]; !ok {
+		return errReferenced
+	}
+
+	for id, block := range s.programBlocks {
+		if block.ProgramID == programID {
+			delete(s.programBlocks, id)
+		}
+	}
+
+	for i := range blocks {
+		block := &blocks[i]
+		block.ID = s.nextID("program_blocks")
+		block.ProgramID = programID
+		block.Position = i + 1
+		block.CreatedAt = s.now()
+		block.UpdatedAt = block.CreatedAt
+		s.programBlocks[block.ID] = *block
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+func (s *Store) SetPersonalRecords(ctx context.Context, setID int) ([]store.PersonalRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Forget the records the set earned before it changed, and hand
+	// them to the best of the sets, which may still be this one
+	records := []store.PersonalRecord{}
+	for _, record := range s.restoreRecords(s.deleteSetRecords(setID)) {
+		if record.WorkoutSetID == setID {
+			records = append(records, record)
+		}
+	}
+
+	set, ok := s.workoutSets[setID]
+	if !ok {
+		return records, nil
+	}
+
+	for _, record := range training.RecordCandidates(set, s.exercises[set.ExerciseID].ExerciseType) {
+		if s.insertRecord(set, &record) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// insertRecord stores the record earned by a set unless its user
+// already holds a record at least as good, and reports whether it did.
+func (s *Store) insertRecord(set store.WorkoutSet, record *store.PersonalRecord) bool {
+	workout := s.workouts[set.WorkoutID]
+	record.UserID = workout.UserID
+	record.ExerciseID = set.ExerciseID
+	record.WorkoutSetID = set.ID
+
+	for _, existing := range s.personalRecords {
+		if s.recordDeleted(existing) {
+			continue
+		}
+		if existing.UserID == record.UserID && sameRecord(existing, *record) && !record.Beats(existing) {
+			return false
+		}
+	}
+
+	record.ID = s.nextID("personal_records")
+	record.AchievedAt = workout.PerformedAt
+	record.CreatedAt = s.now()
+	s.personalRecords[record.ID] = *record
+	return true
+}
+
+// restoreRecords hands records that were dropped along with their sets
+// to the best remaining sets of their users, and returns the records
+// it inserts. Sets performed first win ties.
+func (s *Store) restoreRecords(dropped []store.PersonalRecord) []store.PersonalRecord {
+	sets := byID(s.workoutSets)
+	sort.SliceStable(sets, func(i, j int) bool {
+		return s.workouts[sets[i].WorkoutID].PerformedAt.Before(s.workouts[sets[j].WorkoutID].PerformedAt)
+	})
+
+	var restored []store.PersonalRecord
+	for i, lost := range dropped {
+		// Each record is handed on once
+		handed := false
+		for _, earlier := range dropped[:i] {
+			if earlier.UserID == lost.UserID && sameRecord(earlier, lost) {
+				handed = true
+				break
+			}
+		}
+		if handed {
+			continue
+		}
+
+		var best store.PersonalRecord
+		var bestSet store.WorkoutSet
+		found := false
+		for _, set := range sets {
+			workout := s.workouts[set.WorkoutID]
+			if set.ExerciseID != lost.ExerciseID || workout.UserID != lost.UserID || workout.DeletedAt != nil {
+				continue
+			}
+			for _, candidate := range training.RecordCandidates(set, s.exercises[set.ExerciseID].ExerciseType) {
+				candidate.ExerciseID = set.ExerciseID
+				if sameRecord(candidate, lost) && (!found || candidate.Beats(best)) {
+					best, bestSet, found = candidate, set, true
+				}
+			}
+		}
+		if found && s.insertRecord(bestSet, &best) {
+			restored = append(restored, best)
+		}
+	}
+	return restored
+}
+
+// deleteSetRecords deletes the records earned by a workout set, as
+// with ON DELETE CASCADE in Postgres, and returns them.
+func (s *Store) deleteSetRecords(setID int) []store.PersonalRecord {
+	var dropped []store.PersonalRecord
+	for _, record := range byID(s.personalRecords) {
+		if record.WorkoutSetID == setID {
+			dropped = append(dropped, record)
+			delete(s.personalRecords, record.ID)
+		}
+	}
+	return dropped
+}
+
+// recordDeleted reports whether the record was earned in a deleted
+// workout.
+func (s *Store) recordDeleted(record store.PersonalRecord) bool {
+	set := s.workoutSets[record.WorkoutSetID]
+	return s.workouts[set.WorkoutID].DeletedAt != nil
+}
+
+func (s *Store) ListPersonalRecords(ctx context.Context, filter store.RecordFilter) ([]store.PersonalRecordWithDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := []store.PersonalRecordWithDetails{}
+	for _, record := range byID(s.personalRecords) {
+		if record.UserID != filter.UserID || s.recordDeleted(record) {
+			continue
+		}
+		if filter.ExerciseID != 0 && record.ExerciseID != filter.ExerciseID {
+			continue
+		}
+
+		exercise := s.exercises[record.ExerciseID]
+		records = append(records, store.PersonalRecordWithDetails{
+			PersonalRecord: record,
+			ExerciseName:   exercise.Name,
+			ExerciseType:   exercise.ExerciseType,
+		})
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		switch {
+		case a.ExerciseName != b.ExerciseName:
+			return a.ExerciseName < b.ExerciseName
+		case a.ExerciseID != b.ExerciseID:
+			return a.ExerciseID < b.ExerciseID
+		case a.Kind != b.Kind:
+			return a.Kind < b.Kind
+		case a.Weight != b.Weight:
+			return a.Weight < b.Weight
+		}
+		return a.ID > b.ID
+	})
+
+	// Records only ever improve, so the current record of each kind is
+	// the last one that was earned
+	if !filter.History {
+		current := records[:0]
+		for i, record := range records {
+			if i > 0 && sameRecord(records[i-1].PersonalRecord, record.PersonalRecord) {
+				continue
+			}
+			current = append(current, record)
+		}
+		records = current
+	}
+	return records, nil
+}
+
+// sameRecord reports whether both records are of the same kind on the
+// same exercise, so that one supersedes the other.
+func sameRecord(a, b store.PersonalRecord) bool {
+	return a.ExerciseID == b.ExerciseID && a.Kind == b.Kind && a.Weight == b.Weight
+}
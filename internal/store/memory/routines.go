@@ -0,0 +1,485 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) CreateRoutine(ctx context.Context, routine *store.Routine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.programs[routine.ProgramID]; !ok {
+		return errReferenced
+	}
+
+	routine.ID = s.nextID("routines")
+	routine.CreatedAt = s.now()
+	routine.UpdatedAt = routine.CreatedAt
+	s.routines[routine.ID] = *routine
+	return nil
+}
+
+func (s *Store) GetRoutine(ctx context.Context, id int) (store.Routine, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routine, ok := s.routines[id]
+	if !ok {
+		return store.Routine{}, store.ErrNotFound
+	}
+	return routine, nil
+}
+
+// routineSorts are the fields routines may be sorted by.
+var routineSorts = sortable[store.Routine]{
+	fields: map[string]func(store.Routine) interface{}{
+		"day_number": func(r store.Routine) interface{} { return r.DayNumber },
+		"name":       func(r store.Routine) interface{} { return r.Name },
+		"created_at": func(r store.Routine) interface{} { return r.CreatedAt },
+	},
+	id: func(r store.Routine) int { return r.ID },
+}
+
+func (s *Store) ListRoutines(ctx context.Context, filter store.RoutineFilter) ([]store.Routine, store.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routines := []store.Routine{}
+	for _, routine := range byID(s.routines) {
+		if routine.DeletedAt != nil || !s.visible(routine.ProgramID, filter.VisibleTo) {
+			continue
+		}
+		if filter.ProgramID != 0 && routine.ProgramID != filter.ProgramID {
+			continue
+		}
+		routines = append(routines, routine)
+	}
+
+	return routineSorts.page(routines, filter.Page, filter.Order(store.DefaultRoutineSort))
+}
+
+func (s *Store) UpdateRoutine(ctx context.Context, routine *store.Routine) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.routines[routine.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	existing.Name = routine.Name
+	existing.DayNumber = routine.DayNumber
+	existing.UpdatedAt = s.now()
+	s.routines[existing.ID] = existing
+	*routine = existing
+	return nil
+}
+
+func (s *Store) DeleteRoutine(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.routines[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	s.deleteRoutine(id)
+	return nil
+}
+
+// deleteRoutine deletes a routine along with its groups and exercises. Workouts
+// that followed it are kept, as with ON DELETE SET NULL in Postgres.
+func (s *Store) deleteRoutine(id int) {
+	for groupID, group := range s.routineGroups {
+		if group.RoutineID == id {
+			s.deleteRoutineGroup(groupID)
+		}
+	}
+	for reID, routineExercise := range s.routineExercises {
+		if routineExercise.RoutineID == id {
+			s.deleteRoutineExercise(reID)
+		}
+	}
+
+	for workoutID, workout := range s.workouts {
+		if workout.RoutineID == id {
+			workout.RoutineID = 0
+			s.workouts[workoutID] = workout
+		}
+	}
+
+	delete(s.routines, id)
+}
+
+func (s *Store) RoutineOwner(ctx context.Context, id int) (store.Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	routine, ok := s.routines[id]
+	if !ok {
+		return store.Owner{}, store.ErrNotFound
+	}
+
+	program, ok := s.programs[routine.ProgramID]
+	if !ok {
+		return store.Owner{}, store.ErrNotFound
+	}
+	return store.Owner{
+		UserID:  program.UserID,
+		Public:  program.IsPublic,
+		Deleted: routine.DeletedAt != nil || program.DeletedAt != nil,
+	}, nil
+}
+
+func (s *Store) SetRoutineDeleted(ctx context.Context, id int, deleted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routine, ok := s.routines[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	deletedAt, err := s.setDeleted(routine.DeletedAt, deleted)
+	if err != nil {
+		return err
+	}
+	routine.DeletedAt = deletedAt
+	routine.UpdatedAt = s.now()
+	s.routines[id] = routine
+	return nil
+}
+
+// -------------------- Routine exercises --------------------
+
+// routineExercisesOf returns the exercises of a routine by position.
+func (s *Store) routineExercisesOf(routineID int) []store.RoutineExercise {
+	exercises := []store.RoutineExercise{}
+	for _, routineExercise := range byID(s.routineExercises) {
+		if routineExercise.RoutineID == routineID {
+			exercises = append(exercises, routineExercise)
+		}
+	}
+
+	sort.SliceStable(exercises, func(i, j int) bool {
+		return exercises[i].Position < exercises[j].Position
+	})
+	return exercises
+}
+
+// renumber numbers the exercises from 1 in the given order.
+func (s *Store) renumber(exercises []store.RoutineExercise) {
+	for i, routineExercise := range exercises {
+		if routineExercise.Position != i+1 {
+			routineExercise.Position = i + 1
+			s.routineExercises[routineExercise.ID] = routineExercise
+		}
+	}
+}
+
+func (s *Store) AddRoutineExercise(ctx context.Context, routineExercise *store.RoutineExercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.routines[routineExercise.RoutineID]; !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := s.exercises[routineExercise.ExerciseID]; !ok {
+		return errReferenced
+	}
+
+	exercises := s.routineExercisesOf(routineExercise.RoutineID)
+	if routineExercise.Position <= 0 || routineExercise.Position > len(exercises) {
+		routineExercise.Position = len(exercises) + 1
+	}
+
+	routineExercise.ID = s.nextID("routine_exercises")
+	routineExercise.GroupID = nil
+	routineExercise.CreatedAt = s.now()
+	routineExercise.UpdatedAt = routineExercise.CreatedAt
+	stored := *routineExercise
+	stored.ProgressionConfig = copyConfig(stored.ProgressionConfig)
+	s.routineExercises[routineExercise.ID] = stored
+
+	position := routineExercise.Position - 1
+	exercises = append(exercises[:position], append([]store.RoutineExercise{stored}, exercises[position:]...)...)
+	s.renumber(exercises)
+	return nil
+}
+
+func (s *Store) ListRoutineExercises(ctx context.Context, routineID int) ([]store.RoutineExerciseWithDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	exercises := []store.RoutineExerciseWithDetails{}
+	for _, routineExercise := range s.routineExercisesOf(routineID) {
+		routineExercise.ProgressionConfig = copyConfig(routineExercise.ProgressionConfig)
+		exercise := s.exercises[routineExercise.ExerciseID]
+		exercises = append(exercises, store.RoutineExerciseWithDetails{
+			RoutineExercise: routineExercise,
+			ExerciseName:    exercise.Name,
+			ExerciseType:    exercise.ExerciseType,
+		})
+	}
+	return exercises, nil
+}
+
+func (s *Store) UpdateRoutineExercise(ctx context.Context, routineExercise *store.RoutineExercise) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.routineExercises[routineExercise.ID]
+	if !ok || existing.RoutineID != routineExercise.RoutineID {
+		return store.ErrNotFound
+	}
+
+	existing.RecommendedSets = routineExercise.RecommendedSets
+	existing.RecommendedReps = routineExercise.RecommendedReps
+	existing.RecommendedRPE = routineExercise.RecommendedRPE
+	existing.RecommendedDuration = routineExercise.RecommendedDuration
+	existing.RecommendedDistance = routineExercise.RecommendedDistance
+	existing.ProgressionScheme = routineExercise.ProgressionScheme
+	existing.ProgressionConfig = copyConfig(routineExercise.ProgressionConfig)
+	existing.UpdatedAt = s.now()
+	s.routineExercises[existing.ID] = existing
+	*routineExercise = existing
+	routineExercise.ProgressionConfig = copyConfig(existing.ProgressionConfig)
+	return nil
+}
+
+func (s *Store) RemoveRoutineExercise(ctx context.Context, routineID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	routineExercise, ok := s.routineExercises[id]
+	if !ok || routineExercise.RoutineID != routineID {
+		return store.ErrNotFound
+	}
+
+	s.deleteRoutineExercise(id)
+	s.renumber(s.routineExercisesOf(routineID))
+	s.dissolveGroups(routineID)
+	return nil
+}
+
+// deleteRoutineExercise deletes a routine exercise. Sets that were
+// performed for it are kept, as with ON DELETE SET NULL in Postgres.
+func (s *Store) deleteRoutineExercise(id int) {
+	for setID, set := range s.workoutSets {
+		if set.RoutineExerciseID != nil && *set.RoutineExerciseID == id {
+			set.RoutineExerciseID = nil
+			s.workoutSets[setID] = set
+		}
+	}
+	delete(s.routineExercises, id)
+}
+
+func (s *Store) ReorderRoutineExercises(ctx context.Context, routineID int, ids []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.routines[routineID]; !ok {
+		return store.ErrNotFound
+	}
+
+	// Every exercise must be listed exactly once
+	if len(ids) != len(s.routineExercisesOf(routineID)) {
+		return store.ErrPrecondition
+	}
+	exercises := make([]store.RoutineExercise, 0, len(ids))
+	seen := map[int]bool{}
+	for _, id := range ids {
+		routineExercise, ok := s.routineExercises[id]
+		if !ok || routineExercise.RoutineID != routineID || seen[id] {
+			return store.ErrPrecondition
+		}
+		seen[id] = true
+		routineExercise.UpdatedAt = s.now()
+		exercises = append(exercises, routineExercise)
+	}
+
+	for i := range exercises {
+		exercises[i].Position = i + 1
+		s.routineExercises[exercises[i].ID] = exercises[i]
+	}
+	return nil
+}
+
+// -------------------- Routine groups --------------------
+
+// groupExercises returns the IDs of the exercises of each group of a
+// routine, by position.
+func (s *Store) groupExercises(routineID int) map[int][]int {
+	members := map[int][]int{}
+	for _, routineExercise := range s.routineExercisesOf(routineID) {
+		if routineExercise.GroupID != nil {
+			members[*routineExercise.GroupID] = append(members[*routineExercise.GroupID], routineExercise.ID)
+		}
+	}
+	return members
+}
+
+// dissolveGroups deletes the groups of a routine that are left with
+// fewer than two exercises.
+func (s *Store) dissolveGroups(routineID int) {
+	members := s.groupExercises(routineID)
+	for id, group := range s.routineGroups {
+		if group.RoutineID == routineID && len(members[id]) < 2 {
+			s.deleteRoutineGroup(id)
+		}
+	}
+}
+
+// deleteRoutineGroup deletes a group. Its exercises and the sets that
+// were performed for it are kept, as with ON DELETE SET NULL in
+// Postgres.
+func (s *Store) deleteRoutineGroup(id int) {
+	for reID, routineExercise := range s.routineExercises {
+		if routineExercise.GroupID != nil && *routineExercise.GroupID == id {
+			routineExercise.GroupID = nil
+			s.routineExercises[reID] = routineExercise
+		}
+	}
+	for setID, set := range s.workoutSets {
+		if set.GroupID != nil && *set.GroupID == id {
+			set.GroupID = nil
+			s.workoutSets[setID] = set
+		}
+	}
+	delete(s.routineGroups, id)
+}
+
+// checkGroupExercises returns ErrPrecondition unless every exercise of
+// the group is in its routine.
+func (s *Store) checkGroupExercises(group *store.RoutineGroup) error {
+	for _, id := range group.RoutineExerciseIDs {
+		routineExercise, ok := s.routineExercises[id]
+		if !ok || routineExercise.RoutineID != group.RoutineID {
+			return store.ErrPrecondition
+		}
+	}
+	return nil
+}
+
+// setGroupExercises makes the routine exercises the exercises of the
+// group, and reads them back by position.
+func (s *Store) setGroupExercises(group *store.RoutineGroup) {
+	ids := map[int]bool{}
+	for _, id := range group.RoutineExerciseIDs {
+		ids[id] = true
+	}
+
+	groupID := group.ID
+	for id, routineExercise := range s.routineExercises {
+		member := routineExercise.GroupID != nil && *routineExercise.GroupID == groupID
+		if !member && !ids[id] {
+			continue
+		}
+		routineExercise.GroupID = nil
+		if ids[id] {
+			routineExercise.GroupID = &groupID
+		}
+		routineExercise.UpdatedAt = s.now()
+		s.routineExercises[id] = routineExercise
+	}
+
+	s.dissolveGroups(group.RoutineID)
+	group.RoutineExerciseIDs = append([]int{}, s.groupExercises(group.RoutineID)[group.ID]...)
+}
+
+func (s *Store) CreateRoutineGroup(ctx context.Context, group *store.RoutineGroup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.routines[group.RoutineID]; !ok {
+		return store.ErrNotFound
+	}
+	if err := s.checkGroupExercises(group); err != nil {
+		return err
+	}
+
+	group.ID = s.nextID("routine_groups")
+	group.CreatedAt = s.now()
+	group.UpdatedAt = group.CreatedAt
+	stored := *group
+	stored.RoutineExerciseIDs = nil
+	s.routineGroups[group.ID] = stored
+	s.setGroupExercises(group)
+	return nil
+}
+
+func (s *Store) ListRoutineGroups(ctx context.Context, routineID int) ([]store.RoutineGroup, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := s.groupExercises(routineID)
+	groups := []store.RoutineGroup{}
+	for _, group := range byID(s.routineGroups) {
+		if group.RoutineID == routineID {
+			group.RoutineExerciseIDs = append([]int{}, members[group.ID]...)
+			groups = append(groups, group)
+		}
+	}
+
+	// Groups come in the order of their first exercise
+	first := func(group store.RoutineGroup) int {
+		if len(group.RoutineExerciseIDs) == 0 {
+			return int(^uint(0) >> 1)
+		}
+		return s.routineExercises[group.RoutineExerciseIDs[0]].Position
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		return first(groups[i]) < first(groups[j])
+	})
+	return groups, nil
+}
+
+func (s *Store) UpdateRoutineGroup(ctx context.Context, group *store.RoutineGroup) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.routineGroups[group.ID]
+	if !ok || existing.RoutineID != group.RoutineID {
+		return store.ErrNotFound
+	}
+	if err := s.checkGroupExercises(group); err != nil {
+		return err
+	}
+
+	existing.Kind = group.Kind
+	existing.Rounds = group.Rounds
+	existing.RestSeconds = group.RestSeconds
+	existing.UpdatedAt = s.now()
+	s.routineGroups[existing.ID] = existing
+
+	ids := group.RoutineExerciseIDs
+	*group = existing
+	group.RoutineExerciseIDs = ids
+	s.setGroupExercises(group)
+	return nil
+}
+
+func (s *Store) DeleteRoutineGroup(ctx context.Context, routineID, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	group, ok := s.routineGroups[id]
+	if !ok || group.RoutineID != routineID {
+		return store.ErrNotFound
+	}
+
+	s.deleteRoutineGroup(id)
+	return nil
+}
+
+// copyConfig returns a copy of the config that shares no slices with
+// the original.
+func copyConfig(config store.ProgressionConfig) store.ProgressionConfig {
+	if config.Waves != nil {
+		config.Waves = append([]float64{}, config.Waves...)
+	}
+	return config
+}
@@ -0,0 +1,154 @@
+package memory
+
+import (
+	"context"
+	"reflect"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// syncKey identifies a row kept in sync with clients.
+type syncKey struct {
+	entity string
+	id     int
+}
+
+// syncedRow is a row as it was when changes were last numbered.
+type syncedRow struct {
+	seq    int64
+	userID int
+	row    interface{}
+}
+
+// tombstone is a row of the sync_tombstones table.
+type tombstone struct {
+	seq    int64
+	userID int
+	store.Tombstone
+}
+
+// numberChanges numbers the rows written and deleted since it last
+// ran. Postgres numbers rows as they are written instead, but as
+// changes are numbered before they are listed, clients cannot tell the
+// difference. The caller must hold the write lock.
+//
+// Writes are never concurrent, so each change is listed as a
+// transaction of its own.
+func (s *Store) numberChanges() {
+	seen := map[syncKey]bool{}
+	track := func(entity string, id, userID int, row interface{}) {
+		key := syncKey{entity: entity, id: id}
+		seen[key] = true
+		if synced, ok := s.synced[key]; ok && reflect.DeepEqual(synced.row, row) {
+			return
+		}
+		s.lastChange++
+		s.synced[key] = syncedRow{seq: s.lastChange, userID: userID, row: row}
+	}
+
+	for _, program := range byID(s.programs) {
+		track(store.EntityProgram, program.ID, program.UserID, program)
+	}
+	for _, routine := range byID(s.routines) {
+		track(store.EntityRoutine, routine.ID, s.programs[routine.ProgramID].UserID, routine)
+	}
+	for _, routineExercise := range byID(s.routineExercises) {
+		routine := s.routines[routineExercise.RoutineID]
+		track(store.EntityRoutineExercise, routineExercise.ID, s.programs[routine.ProgramID].UserID, routineExercise)
+	}
+	for _, workout := range byID(s.workouts) {
+		track(store.EntityWorkout, workout.ID, workout.UserID, workout)
+	}
+	for _, set := range byID(s.workoutSets) {
+		track(store.EntityWorkoutSet, set.ID, s.workouts[set.WorkoutID].UserID, set)
+	}
+
+	// Rows gone since they were last numbered leave a tombstone
+	deleted := []syncKey{}
+	for key := range s.synced {
+		if !seen[key] {
+			deleted = append(deleted, key)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return s.synced[deleted[i]].seq < s.synced[deleted[j]].seq })
+
+	now := s.now()
+	for _, key := range deleted {
+		s.lastChange++
+		s.tombstones = append(s.tombstones, tombstone{
+			seq:       s.lastChange,
+			userID:    s.synced[key].userID,
+			Tombstone: store.Tombstone{Entity: key.entity, ID: key.id, DeletedAt: now},
+		})
+		delete(s.synced, key)
+	}
+}
+
+// changeCursor is the cursor of the change numbered seq.
+func changeCursor(seq int64) store.ChangeCursor {
+	return store.ChangeCursor{Xact: seq, Seq: seq}
+}
+
+func (s *Store) ListChanges(ctx context.Context, userID int, since store.ChangeCursor, limit int) (store.Changes, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.numberChanges()
+
+	type change struct {
+		seq int64
+		add func(changes *store.Changes)
+	}
+	listed := []change{}
+	for _, synced := range s.synced {
+		if synced.userID != userID || !since.Before(changeCursor(synced.seq)) {
+			continue
+		}
+		row := synced.row
+		listed = append(listed, change{seq: synced.seq, add: func(changes *store.Changes) {
+			switch row := row.(type) {
+			case store.Program:
+				changes.Programs = append(changes.Programs, row)
+			case store.Routine:
+				changes.Routines = append(changes.Routines, row)
+			case store.RoutineExercise:
+				row.ProgressionConfig = copyConfig(row.ProgressionConfig)
+				changes.RoutineExercises = append(changes.RoutineExercises, row)
+			case store.Workout:
+				changes.Workouts = append(changes.Workouts, row)
+			case store.WorkoutSet:
+				changes.WorkoutSets = append(changes.WorkoutSets, row)
+			}
+		}})
+	}
+	for _, deleted := range s.tombstones {
+		if deleted.userID != userID || !since.Before(changeCursor(deleted.seq)) {
+			continue
+		}
+		tombstone := deleted.Tombstone
+		listed = append(listed, change{seq: deleted.seq, add: func(changes *store.Changes) {
+			changes.Tombstones = append(changes.Tombstones, tombstone)
+		}})
+	}
+
+	changes := store.Changes{
+		Programs:         []store.Program{},
+		Routines:         []store.Routine{},
+		RoutineExercises: []store.RoutineExercise{},
+		Workouts:         []store.Workout{},
+		WorkoutSets:      []store.WorkoutSet{},
+		Tombstones:       []store.Tombstone{},
+		Cursor:           since,
+	}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].seq < listed[j].seq })
+	if len(listed) > limit {
+		listed = listed[:limit]
+		changes.HasMore = true
+	}
+	for _, row := range listed {
+		row.add(&changes)
+		changes.Cursor = changeCursor(row.seq)
+	}
+	return changes, nil
+}
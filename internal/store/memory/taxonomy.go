@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// The taxonomy is seeded with the same rows as the exercise taxonomy
+// migration, from head to toe.
+var (
+	muscleGroups = []store.MuscleGroup{
+		{Slug: "chest", Name: "Chest"},
+		{Slug: "back", Name: "Back"},
+		{Slug: "shoulders", Name: "Shoulders"},
+		{Slug: "arms", Name: "Arms"},
+		{Slug: "core", Name: "Core"},
+		{Slug: "legs", Name: "Legs"},
+	}
+
+	muscles = []store.Muscle{
+		{Slug: "chest", Name: "Chest", MuscleGroup: "chest"},
+		{Slug: "lats", Name: "Lats", MuscleGroup: "back"},
+		{Slug: "traps", Name: "Traps", MuscleGroup: "back"},
+		{Slug: "upper_back", Name: "Upper back", MuscleGroup: "back"},
+		{Slug: "lower_back", Name: "Lower back", MuscleGroup: "back"},
+		{Slug: "front_delts", Name: "Front delts", MuscleGroup: "shoulders"},
+		{Slug: "side_delts", Name: "Side delts", MuscleGroup: "shoulders"},
+		{Slug: "rear_delts", Name: "Rear delts", MuscleGroup: "shoulders"},
+		{Slug: "biceps", Name: "Biceps", MuscleGroup: "arms"},
+		{Slug: "triceps", Name: "Triceps", MuscleGroup: "arms"},
+		{Slug: "forearms", Name: "Forearms", MuscleGroup: "arms"},
+		{Slug: "abs", Name: "Abs", MuscleGroup: "core"},
+		{Slug: "obliques", Name: "Obliques", MuscleGroup: "core"},
+		{Slug: "quads", Name: "Quads", MuscleGroup: "legs"},
+		{Slug: "hamstrings", Name: "Hamstrings", MuscleGroup: "legs"},
+		{Slug: "glutes", Name: "Glutes", MuscleGroup: "legs"},
+		{Slug: "calves", Name: "Calves", MuscleGroup: "legs"},
+		{Slug: "adductors", Name: "Adductors", MuscleGroup: "legs"},
+		{Slug: "abductors", Name: "Abductors", MuscleGroup: "legs"},
+		{Slug: "hip_flexors", Name: "Hip flexors", MuscleGroup: "legs"},
+	}
+
+	equipment = []store.Equipment{
+		{Slug: "barbell", Name: "Barbell"},
+		{Slug: "dumbbell", Name: "Dumbbell"},
+		{Slug: "kettlebell", Name: "Kettlebell"},
+		{Slug: "ez_bar", Name: "EZ bar"},
+		{Slug: "trap_bar", Name: "Trap bar"},
+		{Slug: "smith_machine", Name: "Smith machine"},
+		{Slug: "cable", Name: "Cable"},
+		{Slug: "machine", Name: "Machine"},
+		{Slug: "bench", Name: "Bench"},
+		{Slug: "pull_up_bar", Name: "Pull-up bar"},
+		{Slug: "resistance_band", Name: "Resistance band"},
+		{Slug: "medicine_ball", Name: "Medicine ball"},
+		{Slug: "bodyweight", Name: "Bodyweight"},
+		{Slug: "treadmill", Name: "Treadmill"},
+		{Slug: "stationary_bike", Name: "Stationary bike"},
+		{Slug: "rowing_machine", Name: "Rowing machine"},
+	}
+
+	exerciseTypes = []store.ExerciseType{
+		{Slug: "weight_reps", Name: "Weight and reps", Description: "Sets of repetitions with a weight"},
+		{Slug: "duration_only", Name: "Duration", Description: "Sets held or performed for a time"},
+		{Slug: "distance_time", Name: "Distance and time", Description: "Sets covering a distance in a time"},
+	}
+)
+
+func (s *Store) ListMuscleGroups(ctx context.Context) ([]store.MuscleGroup, error) {
+	return append([]store.MuscleGroup{}, muscleGroups...), nil
+}
+
+func (s *Store) ListMuscles(ctx context.Context) ([]store.Muscle, error) {
+	positions := map[string]int{}
+	for i, group := range muscleGroups {
+		positions[group.Slug] = i
+	}
+
+	list := append([]store.Muscle{}, muscles...)
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].MuscleGroup != list[j].MuscleGroup {
+			return positions[list[i].MuscleGroup] < positions[list[j].MuscleGroup]
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+func (s *Store) ListEquipment(ctx context.Context) ([]store.Equipment, error) {
+	list := append([]store.Equipment{}, equipment...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+func (s *Store) ListExerciseTypes(ctx context.Context) ([]store.ExerciseType, error) {
+	list := append([]store.ExerciseType{}, exerciseTypes...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) CreateTrack(ctx context.Context, track *store.Track, set *store.WorkoutSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workouts[track.WorkoutID]; !ok {
+		return errReferenced
+	}
+	set.WorkoutID = track.WorkoutID
+
+	// Everything is checked before the set is written, as the
+	// transaction in Postgres would roll it back
+	if set.ID > 0 {
+		existing, ok := s.workoutSets[set.ID]
+		if !ok || existing.WorkoutID != set.WorkoutID {
+			return store.ErrNotFound
+		}
+		for _, other := range s.tracks {
+			if other.WorkoutSetID == set.ID {
+				return store.ErrConflict
+			}
+		}
+		s.updateWorkoutSet(existing, set)
+	} else if err := s.addWorkoutSet(set); err != nil {
+		return err
+	}
+
+	track.WorkoutSetID = set.ID
+	track.ID = s.nextID("workout_tracks")
+	track.CreatedAt = s.now()
+	stored := *track
+	stored.Points = append([]store.TrackPoint{}, track.Points...)
+	s.tracks[track.ID] = stored
+	return nil
+}
+
+func (s *Store) GetTrack(ctx context.Context, workoutID, trackID int) (store.Track, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	track, ok := s.tracks[trackID]
+	if !ok || track.WorkoutID != workoutID {
+		return store.Track{}, store.ErrNotFound
+	}
+	track.Points = append([]store.TrackPoint{}, track.Points...)
+	return track, nil
+}
+
+func (s *Store) ListTracks(ctx context.Context, workoutID int) ([]store.Track, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tracks := []store.Track{}
+	for _, track := range byID(s.tracks) {
+		if track.WorkoutID == workoutID {
+			track.Points = nil
+			tracks = append(tracks, track)
+		}
+	}
+	return tracks, nil
+}
+
+// deleteSetTracks deletes the track of a workout set, as with ON DELETE
+// CASCADE in Postgres.
+func (s *Store) deleteSetTracks(setID int) {
+	for id, track := range s.tracks {
+		if track.WorkoutSetID == setID {
+			delete(s.tracks, id)
+		}
+	}
+}
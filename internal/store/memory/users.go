@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) CreateUser(ctx context.Context, user *store.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.users {
+		if existing.Email == user.Email {
+			return store.ErrConflict
+		}
+	}
+
+	user.ID = s.nextID("users")
+	user.CreatedAt = s.now()
+	user.UpdatedAt = user.CreatedAt
+	s.users[user.ID] = *user
+	return nil
+}
+
+func (s *Store) GetUser(ctx context.Context, id int) (store.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return store.User{}, store.ErrNotFound
+	}
+	return user, nil
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (store.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, user := range s.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return store.User{}, store.ErrNotFound
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.refreshTokens[tokenID]; ok {
+		return store.ErrConflict
+	}
+	s.refreshTokens[tokenID] = refreshToken{userID: userID, expiresAt: expiresAt}
+	return nil
+}
+
+func (s *Store) ConsumeRefreshToken(ctx context.Context, userID int, tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenID]
+	now := s.now()
+	if !ok || token.userID != userID || !token.revokedAt.IsZero() || !token.expiresAt.After(now) {
+		return store.ErrNotFound
+	}
+	token.revokedAt = now
+	s.refreshTokens[tokenID] = token
+	return nil
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, userID int, tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.refreshTokens[tokenID]
+	if ok && token.userID == userID && token.revokedAt.IsZero() {
+		token.revokedAt = s.now()
+		s.refreshTokens[tokenID] = token
+	}
+	return nil
+}
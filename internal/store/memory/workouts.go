@@ -0,0 +1,487 @@
+package memory
+
+import (
+	"context"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// checkWorkout checks that a workout can be created along with its
+// sets, which may be of the exercises in created that are not stored
+// yet. It numbers the sets up front so that nothing is written if one
+// of them cannot be added, like a rolled back transaction.
+func (s *Store) checkWorkout(workout store.Workout, sets []store.WorkoutSet, created map[int]bool) ([]store.WorkoutSet, error) {
+	if _, ok := s.users[workout.UserID]; !ok {
+		return nil, errReferenced
+	}
+	if _, ok := s.routines[workout.RoutineID]; workout.RoutineID != 0 && !ok {
+		return nil, errReferenced
+	}
+	if workout.Status == store.WorkoutInProgress && s.inProgress(workout.UserID, 0) {
+		return nil, store.ErrConflict
+	}
+
+	numbered := make([]store.WorkoutSet, len(sets))
+	last := map[int]int{}
+	taken := map[[2]int]bool{}
+	for i, set := range sets {
+		if _, ok := s.exercises[set.ExerciseID]; !ok && !created[set.ExerciseID] {
+			return nil, errReferenced
+		}
+		if set.SetIndex == 0 {
+			set.SetIndex = last[set.ExerciseID] + 1
+		}
+		key := [2]int{set.ExerciseID, set.SetIndex}
+		if taken[key] {
+			return nil, store.ErrConflict
+		}
+		taken[key] = true
+		last[set.ExerciseID] = max(last[set.ExerciseID], set.SetIndex)
+		numbered[i] = set
+	}
+	return numbered, nil
+}
+
+// insertWorkout stores a workout along with the sets numbered by
+// checkWorkout, and fills in their IDs.
+func (s *Store) insertWorkout(workout *store.Workout, numbered []store.WorkoutSet) {
+	workout.ID = s.nextID("workouts")
+	workout.CreatedAt = s.now()
+	workout.UpdatedAt = workout.CreatedAt
+	s.workouts[workout.ID] = *workout
+
+	for i := range numbered {
+		set := &numbered[i]
+		set.WorkoutID = workout.ID
+		set.ID = s.nextID("workout_sets")
+		set.CreatedAt = s.now()
+		set.UpdatedAt = set.CreatedAt
+		s.workoutSets[set.ID] = *set
+	}
+}
+
+func (s *Store) CreateWorkout(ctx context.Context, workout *store.Workout, sets []store.WorkoutSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	numbered, err := s.checkWorkout(*workout, sets, nil)
+	if err != nil {
+		return err
+	}
+	s.insertWorkout(workout, numbered)
+	copy(sets, numbered)
+	return nil
+}
+
+func (s *Store) ImportWorkouts(ctx context.Context, exercises []*store.Exercise, workouts []store.ImportedWorkout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Exercises are numbered first for the sets to refer to them, but
+	// only stored once every workout checks out
+	created := map[int]bool{}
+	for _, exercise := range exercises {
+		exercise.ID = s.nextID("exercises")
+		exercise.CreatedAt = s.now()
+		exercise.UpdatedAt = exercise.CreatedAt
+		created[exercise.ID] = true
+	}
+
+	numbered := make([][]store.WorkoutSet, len(workouts))
+	for i, workout := range workouts {
+		sets := make([]store.WorkoutSet, len(workout.Sets))
+		for j, set := range workout.Sets {
+			sets[j] = set.WorkoutSet
+			if set.NewExercise != nil {
+				sets[j].ExerciseID = set.NewExercise.ID
+			}
+		}
+
+		var err error
+		if numbered[i], err = s.checkWorkout(workout.Workout, sets, created); err != nil {
+			return err
+		}
+	}
+
+	for _, exercise := range exercises {
+		*exercise = copyExercise(*exercise)
+		s.exercises[exercise.ID] = copyExercise(*exercise)
+	}
+	for i := range workouts {
+		s.insertWorkout(&workouts[i].Workout, numbered[i])
+		for j := range numbered[i] {
+			workouts[i].Sets[j].WorkoutSet = numbered[i][j]
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetWorkout(ctx context.Context, id int) (store.Workout, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workout, ok := s.workouts[id]
+	if !ok {
+		return store.Workout{}, store.ErrNotFound
+	}
+	return workout, nil
+}
+
+// workoutSorts are the fields workouts may be sorted by.
+var workoutSorts = sortable[store.WorkoutWithRoutineName]{
+	fields: map[string]func(store.WorkoutWithRoutineName) interface{}{
+		"performed_at": func(w store.WorkoutWithRoutineName) interface{} { return w.PerformedAt },
+		"status":       func(w store.WorkoutWithRoutineName) interface{} { return w.Status },
+		"created_at":   func(w store.WorkoutWithRoutineName) interface{} { return w.CreatedAt },
+	},
+	id: func(w store.WorkoutWithRoutineName) int { return w.ID },
+}
+
+func (s *Store) ListWorkouts(ctx context.Context, filter store.WorkoutFilter) ([]store.WorkoutWithRoutineName, store.PageInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workouts := []store.WorkoutWithRoutineName{}
+	for _, workout := range byID(s.workouts) {
+		if workout.UserID != filter.UserID || workout.DeletedAt != nil {
+			continue
+		}
+		if filter.Status != "" && workout.Status != filter.Status {
+			continue
+		}
+		if !filter.From.IsZero() && workout.PerformedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !workout.PerformedAt.Before(filter.To) {
+			continue
+		}
+		workouts = append(workouts, store.WorkoutWithRoutineName{
+			Workout:     workout,
+			RoutineName: s.routines[workout.RoutineID].Name,
+		})
+	}
+
+	return workoutSorts.page(workouts, filter.Page, filter.Order(store.DefaultWorkoutSort))
+}
+
+func (s *Store) UpdateWorkout(ctx context.Context, workout *store.Workout) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.workouts[workout.ID]
+	if !ok {
+		return store.ErrNotFound
+	}
+	if _, ok := s.routines[workout.RoutineID]; workout.RoutineID != 0 && !ok {
+		return errReferenced
+	}
+
+	existing.RoutineID = workout.RoutineID
+	existing.PerformedAt = workout.PerformedAt
+	existing.UpdatedAt = s.now()
+	s.workouts[existing.ID] = existing
+	*workout = existing
+	return nil
+}
+
+// inProgress reports whether the user has a workout in progress other
+// than the one with the given ID. Deleted workouts do not count.
+func (s *Store) inProgress(userID, exceptID int) bool {
+	for _, workout := range s.workouts {
+		if workout.UserID == userID && workout.ID != exceptID && workout.Status == store.WorkoutInProgress && workout.DeletedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) SetWorkoutStatus(ctx context.Context, workout *store.Workout, from string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.workouts[workout.ID]
+	if !ok || existing.Status != from {
+		return store.ErrPrecondition
+	}
+	if workout.Status == store.WorkoutInProgress && existing.DeletedAt == nil && s.inProgress(existing.UserID, existing.ID) {
+		return store.ErrConflict
+	}
+
+	existing.Status = workout.Status
+	existing.StartedAt = workout.StartedAt
+	existing.FinishedAt = workout.FinishedAt
+	existing.PerformedAt = workout.PerformedAt
+	existing.UpdatedAt = s.now()
+	s.workouts[existing.ID] = existing
+	*workout = existing
+	return nil
+}
+
+func (s *Store) DeleteWorkout(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.workouts[id]; !ok {
+		return store.ErrNotFound
+	}
+
+	var dropped []store.PersonalRecord
+	for _, set := range byID(s.workoutSets) {
+		if set.WorkoutID == id {
+			dropped = append(dropped, s.deleteSetRecords(set.ID)...)
+			s.deleteSetTracks(set.ID)
+			delete(s.workoutSets, set.ID)
+		}
+	}
+
+	delete(s.workouts, id)
+	s.restoreRecords(dropped)
+	return nil
+}
+
+func (s *Store) WorkoutOwner(ctx context.Context, id int) (store.Owner, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	workout, ok := s.workouts[id]
+	if !ok {
+		return store.Owner{}, store.ErrNotFound
+	}
+	return store.Owner{UserID: workout.UserID, Deleted: workout.DeletedAt != nil}, nil
+}
+
+func (s *Store) SetWorkoutDeleted(ctx context.Context, id int, deleted bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workout, ok := s.workouts[id]
+	if !ok {
+		return store.ErrNotFound
+	}
+
+	deletedAt, err := s.setDeleted(workout.DeletedAt, deleted)
+	if err != nil {
+		return err
+	}
+	if !deleted && workout.Status == store.WorkoutInProgress && s.inProgress(workout.UserID, workout.ID) {
+		return store.ErrConflict
+	}
+	workout.DeletedAt = deletedAt
+	workout.UpdatedAt = s.now()
+	s.workouts[id] = workout
+	return nil
+}
+
+// -------------------- Workout sets --------------------
+
+func (s *Store) AddWorkoutSet(ctx context.Context, set *store.WorkoutSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.addWorkoutSet(set)
+}
+
+// addWorkoutSet stores a set, leaving the store untouched if it fails.
+func (s *Store) addWorkoutSet(set *store.WorkoutSet) error {
+	if _, ok := s.workouts[set.WorkoutID]; !ok {
+		return errReferenced
+	}
+	if _, ok := s.exercises[set.ExerciseID]; !ok {
+		return errReferenced
+	}
+
+	// Number the set after the last one of its exercise
+	last := 0
+	for _, existing := range s.workoutSets {
+		if existing.WorkoutID != set.WorkoutID || existing.ExerciseID != set.ExerciseID {
+			continue
+		}
+		if existing.SetIndex == set.SetIndex {
+			return store.ErrConflict
+		}
+		last = max(last, existing.SetIndex)
+	}
+	if set.SetIndex == 0 {
+		set.SetIndex = last + 1
+	}
+
+	set.ID = s.nextID("workout_sets")
+	set.CreatedAt = s.now()
+	set.UpdatedAt = set.CreatedAt
+	s.workoutSets[set.ID] = *set
+	return nil
+}
+
+func (s *Store) GetWorkoutSet(ctx context.Context, workoutID, setID int) (store.WorkoutSetWithDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set, ok := s.workoutSets[setID]
+	if !ok || set.WorkoutID != workoutID {
+		return store.WorkoutSetWithDetails{}, store.ErrNotFound
+	}
+
+	exercise := s.exercises[set.ExerciseID]
+	return store.WorkoutSetWithDetails{
+		WorkoutSet:   set,
+		ExerciseName: exercise.Name,
+		ExerciseType: exercise.ExerciseType,
+	}, nil
+}
+
+func (s *Store) ListWorkoutSets(ctx context.Context, workoutID int) ([]store.WorkoutSetWithDetails, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sets := []store.WorkoutSetWithDetails{}
+	for _, set := range byID(s.workoutSets) {
+		if set.WorkoutID != workoutID {
+			continue
+		}
+
+		exercise := s.exercises[set.ExerciseID]
+		sets = append(sets, store.WorkoutSetWithDetails{
+			WorkoutSet:   set,
+			ExerciseName: exercise.Name,
+			ExerciseType: exercise.ExerciseType,
+		})
+	}
+	return sets, nil
+}
+
+func (s *Store) UpdateWorkoutSet(ctx context.Context, set *store.WorkoutSet) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.workoutSets[set.ID]
+	if !ok || existing.WorkoutID != set.WorkoutID {
+		return store.ErrNotFound
+	}
+	s.updateWorkoutSet(existing, set)
+	return nil
+}
+
+// updateWorkoutSet copies the editable fields of set onto the stored
+// set and returns the result in set.
+func (s *Store) updateWorkoutSet(existing store.WorkoutSet, set *store.WorkoutSet) {
+	existing.SetType = set.SetType
+	existing.Completed = set.Completed
+	existing.Reps = set.Reps
+	existing.Weight = set.Weight
+	existing.RPE = set.RPE
+	existing.Duration = set.Duration
+	existing.Distance = set.Distance
+	existing.UpdatedAt = s.now()
+	s.workoutSets[existing.ID] = existing
+	*set = existing
+}
+
+func (s *Store) DeleteWorkoutSet(ctx context.Context, workoutID, setID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set, ok := s.workoutSets[setID]
+	if !ok || set.WorkoutID != workoutID {
+		return store.ErrNotFound
+	}
+
+	dropped := s.deleteSetRecords(setID)
+	s.deleteSetTracks(setID)
+	delete(s.workoutSets, setID)
+	s.restoreRecords(dropped)
+	return nil
+}
+
+func (s *Store) ListPerformedSets(ctx context.Context, filter store.PerformedSetFilter) ([]store.PerformedSet, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sets := []store.PerformedSet{}
+	for _, set := range byID(s.workoutSets) {
+		workout := s.workouts[set.WorkoutID]
+		if workout.UserID != filter.UserID || workout.DeletedAt != nil || !set.Completed {
+			continue
+		}
+		if filter.RoutineID != 0 && workout.RoutineID != filter.RoutineID {
+			continue
+		}
+		if filter.ExerciseID != 0 && set.ExerciseID != filter.ExerciseID {
+			continue
+		}
+		if !filter.From.IsZero() && workout.PerformedAt.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && !workout.PerformedAt.Before(filter.To) {
+			continue
+		}
+
+		exercise := s.exercises[set.ExerciseID]
+		sets = append(sets, store.PerformedSet{
+			WorkoutSetWithDetails: store.WorkoutSetWithDetails{
+				WorkoutSet:   set,
+				ExerciseName: exercise.Name,
+				ExerciseType: exercise.ExerciseType,
+			},
+			PerformedAt:      workout.PerformedAt,
+			PrimaryMuscles:   clone(exercise.PrimaryMuscles),
+			SecondaryMuscles: clone(exercise.SecondaryMuscles),
+		})
+	}
+
+	sort.SliceStable(sets, func(i, j int) bool {
+		return sets[i].PerformedAt.Before(sets[j].PerformedAt)
+	})
+	return sets, nil
+}
+
+func (s *Store) WalkWorkoutHistory(ctx context.Context, userID int, fn func(store.HistoryRow) error) error {
+	// Take a snapshot so that fn runs without holding the lock
+	s.mu.RLock()
+	var history []store.HistoryRow
+	workouts := byID(s.workouts)
+	sort.SliceStable(workouts, func(i, j int) bool {
+		return workouts[i].PerformedAt.Before(workouts[j].PerformedAt)
+	})
+	sets := byID(s.workoutSets)
+	for _, workout := range workouts {
+		if workout.UserID != userID || workout.DeletedAt != nil {
+			continue
+		}
+
+		row := store.HistoryRow{
+			Workout: store.WorkoutWithRoutineName{
+				Workout:     workout,
+				RoutineName: s.routines[workout.RoutineID].Name,
+			},
+		}
+
+		found := false
+		for _, set := range sets {
+			if set.WorkoutID != workout.ID {
+				continue
+			}
+			found = true
+
+			exercise := s.exercises[set.ExerciseID]
+			row.Set = &store.WorkoutSetWithDetails{
+				WorkoutSet:   set,
+				ExerciseName: exercise.Name,
+				ExerciseType: exercise.ExerciseType,
+			}
+			history = append(history, row)
+		}
+		if !found {
+			row.Set = nil
+			history = append(history, row)
+		}
+	}
+	s.mu.RUnlock()
+
+	for _, row := range history {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
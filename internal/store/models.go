@@ -0,0 +1,483 @@
+package store
+
+import "time"
+
+// Models based on DB schema
+type User struct {
+	ID           int       `json:"id"`
+	Email        string    `json:"email"`
+	PasswordHash string    `json:"-"`
+	IsAdmin      bool      `json:"is_admin"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Exercise is either part of the catalogue shared by every user, when
+// it has no OwnerID, or a custom exercise of its owner. Deleted
+// exercises are no longer listed but remain for the sets that
+// reference them until they are restored.
+type Exercise struct {
+	ID               int        `json:"id"`
+	OwnerID          *int       `json:"owner_id"`
+	Name             string     `json:"name"`
+	Aliases          []string   `json:"aliases"`
+	Equipment        []string   `json:"equipment"`
+	PrimaryMuscles   []string   `json:"primary_muscles"`
+	SecondaryMuscles []string   `json:"secondary_muscles"`
+	ExerciseType     string     `json:"exercise_type"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DeletedAt        *time.Time `json:"deleted_at"`
+}
+
+// ExerciseReferences counts the routine exercises and workout sets
+// that reference an exercise.
+type ExerciseReferences struct {
+	RoutineExercises int `json:"routine_exercises"`
+	WorkoutSets      int `json:"workout_sets"`
+}
+
+// MuscleGroup groups muscles by body part.
+type MuscleGroup struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Muscle is a muscle exercises can work, referenced by its slug.
+type Muscle struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	MuscleGroup string `json:"muscle_group"`
+}
+
+// Equipment is equipment exercises can use, referenced by its slug.
+type Equipment struct {
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// ExerciseType decides how the sets of an exercise are logged.
+type ExerciseType struct {
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Facet counts the exercises matching a search that have a value.
+type Facet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// ExerciseFacets break the exercises matching a search down by
+// equipment and by muscle, most common first. Exercises count once per
+// muscle whether it is a primary or a secondary one.
+type ExerciseFacets struct {
+	Equipment []Facet `json:"equipment"`
+	Muscles   []Facet `json:"muscles"`
+}
+
+// Program is a set of routines laid out over cycles of CycleDays days
+// by their day number. ForkedFromProgramID is set on copies of another
+// program, and ForkCount counts the copies made of this one.
+type Program struct {
+	ID                  int        `json:"id"`
+	UserID              int        `json:"user_id"`
+	Name                string     `json:"name"`
+	IsPublic            bool       `json:"is_public"`
+	CycleDays           int        `json:"cycle_days"`
+	ForkedFromProgramID *int       `json:"forked_from_program_id"`
+	ForkCount           int        `json:"fork_count"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	DeletedAt           *time.Time `json:"deleted_at"`
+}
+
+// ProgramTree is a program along with its blocks, routines and their
+// exercises.
+type ProgramTree struct {
+	Program
+	Blocks   []ProgramBlock
+	Routines []RoutineTree
+}
+
+// RoutineTree is a routine along with its exercises and groups. Within
+// a tree, the GroupID of an exercise is the 1-based index of its group
+// in Groups rather than an ID, so that trees can be built before they
+// are stored.
+type RoutineTree struct {
+	Routine
+	Exercises []RoutineExercise
+	Groups    []RoutineGroup
+}
+
+// ProgramBlock is a phase of a program lasting Weeks cycles. The sets
+// and RPE recommended by its routines are scaled by SetScale and
+// RPEScale, which is how deloads lighten the load; deloads that leave
+// them out get the scales of schedule.DefaultScales.
+type ProgramBlock struct {
+	ID        int       `json:"id"`
+	ProgramID int       `json:"program_id"`
+	Position  int       `json:"position"`
+	Name      string    `json:"name"`
+	Weeks     int       `json:"weeks"`
+	Deload    bool      `json:"deload"`
+	SetScale  float64   `json:"set_scale"`
+	RPEScale  float64   `json:"rpe_scale"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Enrollment records that a user follows a program from StartDate on,
+// until it is ended.
+type Enrollment struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	ProgramID int        `json:"program_id"`
+	StartDate time.Time  `json:"start_date"`
+	EndedAt   *time.Time `json:"ended_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type Routine struct {
+	ID        int        `json:"id"`
+	ProgramID int        `json:"program_id"`
+	Name      string     `json:"name"`
+	DayNumber int        `json:"day_number"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at"`
+}
+
+// RoutineExercise is an exercise of a routine. The recommendations are
+// the starting point of its ProgressionScheme, which computes the
+// targets of each session. A routine may contain an exercise several
+// times, ordered by their 1-based Position.
+type RoutineExercise struct {
+	ID                  int               `json:"id"`
+	RoutineID           int               `json:"routine_id"`
+	ExerciseID          int               `json:"exercise_id"`
+	Position            int               `json:"position"`
+	GroupID             *int              `json:"group_id"`
+	RecommendedSets     int               `json:"recommended_sets"`
+	RecommendedReps     int               `json:"recommended_reps"`
+	RecommendedRPE      float64           `json:"recommended_rpe"`
+	RecommendedDuration int               `json:"recommended_duration"`
+	RecommendedDistance float64           `json:"recommended_distance"`
+	ProgressionScheme   string            `json:"progression_scheme"`
+	ProgressionConfig   ProgressionConfig `json:"progression_config"`
+	CreatedAt           time.Time         `json:"created_at"`
+	UpdatedAt           time.Time         `json:"updated_at"`
+}
+
+// ProgressionConfig parameterises the progression scheme of a routine
+// exercise. Which fields apply depends on the scheme.
+type ProgressionConfig struct {
+	// StartWeight is lifted until there is a session to progress from.
+	StartWeight float64 `json:"start_weight,omitempty" yaml:"start_weight,omitempty"`
+	// Increment is the weight added when progressing, and the step
+	// weights are rounded to.
+	Increment float64 `json:"increment,omitempty" yaml:"increment,omitempty"`
+	// MinReps and MaxReps bound the rep range of double progression.
+	MinReps int `json:"min_reps,omitempty" yaml:"min_reps,omitempty"`
+	MaxReps int `json:"max_reps,omitempty" yaml:"max_reps,omitempty"`
+	// TargetRPE is the effort autoregulated sets aim for.
+	TargetRPE float64 `json:"target_rpe,omitempty" yaml:"target_rpe,omitempty"`
+	// Waves lists the fractions of the estimated one-rep max lifted in
+	// successive sessions, such as 0.7, 0.75 and 0.8.
+	Waves []float64 `json:"waves,omitempty" yaml:"waves,omitempty"`
+}
+
+// Kinds of routine groups.
+const (
+	GroupSuperset = "superset"
+	GroupCircuit  = "circuit"
+)
+
+// RoutineGroup links exercises of a routine that are performed back to
+// back: a superset alternates between its exercises and a circuit
+// cycles through them, resting RestSeconds after each round. Each
+// exercise gets one set per round; groups without Rounds take as many
+// rounds as their exercises have sets.
+type RoutineGroup struct {
+	ID          int    `json:"id"`
+	RoutineID   int    `json:"routine_id"`
+	Kind        string `json:"kind"`
+	Rounds      int    `json:"rounds"`
+	RestSeconds int    `json:"rest_seconds"`
+	// RoutineExerciseIDs lists the exercises of the group by position.
+	RoutineExerciseIDs []int     `json:"routine_exercise_ids"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+// RoutineExerciseWithDetails is a RoutineExercise along with the
+// name and type of its exercise.
+type RoutineExerciseWithDetails struct {
+	RoutineExercise
+	ExerciseName string `json:"exercise_name"`
+	ExerciseType string `json:"exercise_type"`
+}
+
+// Workout statuses. A planned workout becomes in progress when its
+// session starts, and then either completed or abandoned.
+const (
+	WorkoutPlanned    = "planned"
+	WorkoutInProgress = "in_progress"
+	WorkoutCompleted  = "completed"
+	WorkoutAbandoned  = "abandoned"
+)
+
+type Workout struct {
+	ID             int        `json:"id"`
+	UserID         int        `json:"user_id"`
+	RoutineID      int        `json:"routine_id"`
+	PerformedAt    time.Time  `json:"performed_at"`
+	Status         string     `json:"status"`
+	StartedAt      *time.Time `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at"`
+	ElapsedSeconds int        `json:"elapsed_seconds"` // not stored, see Elapsed
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	DeletedAt      *time.Time `json:"deleted_at"`
+}
+
+// Elapsed returns how long the session lasted, or has lasted so far if
+// it is still in progress. Sessions that never started last 0.
+func (w Workout) Elapsed(now time.Time) time.Duration {
+	if w.StartedAt == nil {
+		return 0
+	}
+
+	end := now
+	if w.FinishedAt != nil {
+		end = *w.FinishedAt
+	}
+	return end.Sub(*w.StartedAt)
+}
+
+// WorkoutWithSets is a Workout along with its sets, as it is created.
+type WorkoutWithSets struct {
+	Workout
+	Sets []WorkoutSet `json:"sets"`
+}
+
+// ImportedWorkout is a workout imported from another app along with its
+// sets.
+type ImportedWorkout struct {
+	Workout
+	Sets []ImportedSet
+}
+
+// ImportedSet is a set of an imported workout. Sets of exercises the
+// import creates point at them with NewExercise instead, as their
+// ExerciseID is only known once they are created.
+type ImportedSet struct {
+	WorkoutSet
+	NewExercise *Exercise
+}
+
+// WorkoutWithRoutineName is a Workout along with the name of the
+// routine it followed, if any.
+type WorkoutWithRoutineName struct {
+	Workout
+	RoutineName string `json:"routine_name,omitempty"`
+}
+
+// Workout set types
+const (
+	SetWarmup  = "warmup"
+	SetWorking = "working"
+	SetDropset = "dropset"
+	SetFailure = "failure"
+	SetAMRAP   = "amrap"
+)
+
+// WorkoutSet is a single set of an exercise. SetIndex numbers the sets
+// of an exercise within a workout, starting at 1. Sets that are planned
+// but not performed yet are not Completed.
+type WorkoutSet struct {
+	ID         int     `json:"id"`
+	WorkoutID  int     `json:"workout_id"`
+	ExerciseID int     `json:"exercise_id"`
+	SetIndex   int     `json:"set_index"`
+	SetType    string  `json:"set_type"`
+	Completed  bool    `json:"completed"`
+	Reps       int     `json:"reps"`
+	Weight     float64 `json:"weight"`
+	RPE        float64 `json:"rpe"`
+	Duration   int     `json:"duration"`
+	Distance   float64 `json:"distance"`
+	// RoutineExerciseID, GroupID and Round are set on sets planned from
+	// a routine: the routine exercise and group the set was planned for,
+	// and its 1-based round within the group.
+	RoutineExerciseID *int      `json:"routine_exercise_id"`
+	GroupID           *int      `json:"group_id"`
+	Round             int       `json:"round"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	// PersonalRecords lists the records the set just earned. It is
+	// not stored with the set.
+	PersonalRecords []PersonalRecord `json:"personal_records,omitempty"`
+}
+
+// WorkoutSetWithDetails is a WorkoutSet along with the name and type
+// of its exercise.
+type WorkoutSetWithDetails struct {
+	WorkoutSet
+	ExerciseName string `json:"exercise_name"`
+	ExerciseType string `json:"exercise_type"`
+}
+
+// PerformedSet is a WorkoutSet along with when it was performed and
+// what its exercise works.
+type PerformedSet struct {
+	WorkoutSetWithDetails
+	PerformedAt      time.Time `json:"performed_at"`
+	PrimaryMuscles   []string  `json:"primary_muscles"`
+	SecondaryMuscles []string  `json:"secondary_muscles"`
+}
+
+// TrackPoint is a point of a GPS track. Elevation, Time and HeartRate
+// are missing from some recordings.
+type TrackPoint struct {
+	Latitude  float64    `json:"lat"`
+	Longitude float64    `json:"lon"`
+	Elevation *float64   `json:"elevation,omitempty"`
+	Time      *time.Time `json:"time,omitempty"`
+	HeartRate *int       `json:"heart_rate,omitempty"`
+}
+
+// Track is a GPS recording of a distance_time set. Distance is in
+// kilometres and Duration in seconds, like those of the set, and
+// ElevationGain is in metres.
+type Track struct {
+	ID            int          `json:"id"`
+	WorkoutID     int          `json:"workout_id"`
+	WorkoutSetID  int          `json:"workout_set_id"`
+	Format        string       `json:"format"`
+	Name          string       `json:"name"`
+	Distance      float64      `json:"distance"`
+	Duration      int          `json:"duration"`
+	ElevationGain float64      `json:"elevation_gain"`
+	Points        []TrackPoint `json:"points,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+// HistoryRow is a row of the workout history of a user: a set along
+// with its workout. Workouts without sets have a single row without a
+// set.
+type HistoryRow struct {
+	Workout WorkoutWithRoutineName
+	Set     *WorkoutSetWithDetails
+}
+
+// Personal record kinds. Fastest pace records are the lowest value;
+// every other kind is the highest.
+const (
+	RecordMaxWeight       = "max_weight"
+	RecordEstimated1RM    = "estimated_1rm"
+	RecordRepsAtWeight    = "reps_at_weight"
+	RecordLongestDuration = "longest_duration"
+	RecordFastestPace     = "fastest_pace"
+)
+
+// PersonalRecord is a best performance of a user on an exercise, earned
+// by a workout set. Reps at weight records are kept per Weight.
+type PersonalRecord struct {
+	ID           int       `json:"id"`
+	UserID       int       `json:"user_id"`
+	ExerciseID   int       `json:"exercise_id"`
+	WorkoutSetID int       `json:"workout_set_id"`
+	Kind         string    `json:"kind"`
+	Value        float64   `json:"value"`
+	Weight       float64   `json:"weight"`
+	AchievedAt   time.Time `json:"achieved_at"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Beats reports whether r is better than other, a record of the same
+// kind.
+func (r PersonalRecord) Beats(other PersonalRecord) bool {
+	if r.Kind == RecordFastestPace {
+		return r.Value < other.Value
+	}
+	return r.Value > other.Value
+}
+
+// PersonalRecordWithDetails is a PersonalRecord along with the name
+// and type of its exercise.
+type PersonalRecordWithDetails struct {
+	PersonalRecord
+	ExerciseName string `json:"exercise_name"`
+	ExerciseType string `json:"exercise_type"`
+}
+
+// IdempotencyKey is a key a user made a request with, along with the
+// fingerprint of the request and, once it completed, its response.
+type IdempotencyKey struct {
+	UserID      int
+	Key         string
+	Fingerprint string
+	// StatusCode is 0 while the request has not completed.
+	StatusCode  int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Entities kept in sync with clients.
+const (
+	EntityProgram         = "program"
+	EntityRoutine         = "routine"
+	EntityRoutineExercise = "routine_exercise"
+	EntityWorkout         = "workout"
+	EntityWorkoutSet      = "workout_set"
+)
+
+// Changes lists the rows of a user that changed after a cursor, each
+// kind in the order they changed. Soft deleted rows are listed with
+// their DeletedAt; rows deleted for good leave a Tombstone.
+type Changes struct {
+	Programs         []Program         `json:"programs"`
+	Routines         []Routine         `json:"routines"`
+	RoutineExercises []RoutineExercise `json:"routine_exercises"`
+	Workouts         []Workout         `json:"workouts"`
+	WorkoutSets      []WorkoutSet      `json:"workout_sets"`
+	Tombstones       []Tombstone       `json:"tombstones"`
+	// Cursor is the last change listed, to list the changes after it
+	// next. It is the cursor listed from if nothing changed.
+	Cursor ChangeCursor `json:"-"`
+	// HasMore is set if changes were left out to respect the limit.
+	HasMore bool `json:"has_more"`
+}
+
+// ChangeCursor places a change among the others: changes are ordered
+// by the transaction that made them, and then by the sequence numbering
+// them as they were written.
+type ChangeCursor struct {
+	Xact int64
+	Seq  int64
+}
+
+// Before reports whether the change at c comes before the change at
+// other.
+func (c ChangeCursor) Before(other ChangeCursor) bool {
+	return c.Xact < other.Xact || c.Xact == other.Xact && c.Seq < other.Seq
+}
+
+// Tombstone records that a row was deleted for good.
+type Tombstone struct {
+	Entity    string    `json:"entity"`
+	ID        int       `json:"id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Owner describes who owns a resource and whether others may read it.
+// Deleted resources may still be read but only restored.
+type Owner struct {
+	UserID  int
+	Public  bool
+	Deleted bool
+}
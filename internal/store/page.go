@@ -0,0 +1,61 @@
+package store
+
+// Fields each list may be sorted by, and the order of the list when it
+// is not sorted.
+var (
+	ExerciseSortFields  = []string{"name", "exercise_type", "created_at"}
+	DefaultExerciseSort = []SortKey{{Field: "name"}}
+
+	ProgramSortFields  = []string{"name", "cycle_days", "created_at", "updated_at"}
+	DefaultProgramSort = []SortKey{{Field: "created_at", Desc: true}}
+
+	RoutineSortFields  = []string{"day_number", "name", "created_at"}
+	DefaultRoutineSort = []SortKey{{Field: "day_number"}, {Field: "name"}}
+
+	WorkoutSortFields  = []string{"performed_at", "status", "created_at"}
+	DefaultWorkoutSort = []SortKey{{Field: "performed_at", Desc: true}}
+)
+
+// Order returns the sort of the page, or defaults if it has none.
+func (p Page) Order(defaults []SortKey) []SortKey {
+	if len(p.Sort) == 0 {
+		return defaults
+	}
+	return p.Sort
+}
+
+// FinishPage is used by the backends to finish a page they fetched one
+// item past its Limit, in the order of the list or, when paging
+// backward, in reverse, along with the cursor of each item. It puts
+// the items in the order of the list, drops the extra item and
+// describes the page, leaving the total unknown.
+func FinishPage[T any](items []T, cursors []Cursor, page Page) ([]T, PageInfo) {
+	info := PageInfo{Total: -1}
+	backward := page.Cursor != nil && page.Cursor.Backward
+
+	more := len(items) > page.Limit
+	if more {
+		items, cursors = items[:page.Limit], cursors[:page.Limit]
+	}
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+			cursors[i], cursors[j] = cursors[j], cursors[i]
+		}
+	}
+	if len(items) == 0 {
+		return items, info
+	}
+
+	// Pages reached by a cursor have the item of the cursor on the side
+	// they came from
+	first, last := cursors[0], cursors[len(cursors)-1]
+	if more || backward {
+		info.Next = &last
+	}
+	if backward && more || !backward && (page.Cursor != nil || page.Offset > 0) {
+		first.Backward = true
+		info.Prev = &first
+	}
+	return items, info
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+// cursorOf returns the cursor of the item with the ID.
+func cursorOf(id int) Cursor {
+	return Cursor{Values: []string{"value"}, ID: id}
+}
+
+func TestFinishPage(t *testing.T) {
+	backwardOf := func(id int) *Cursor {
+		cursor := cursorOf(id)
+		cursor.Backward = true
+		return &cursor
+	}
+	forwardOf := func(id int) *Cursor {
+		cursor := cursorOf(id)
+		return &cursor
+	}
+
+	tests := []struct {
+		name     string
+		page     Page
+		fetched  []int
+		want     []int
+		wantNext *Cursor
+		wantPrev *Cursor
+	}{
+		{
+			name:    "empty list",
+			page:    Page{Limit: 2},
+			fetched: []int{},
+			want:    []int{},
+		},
+		{
+			name:    "single page",
+			page:    Page{Limit: 2},
+			fetched: []int{1, 2},
+			want:    []int{1, 2},
+		},
+		{
+			name:     "first page",
+			page:     Page{Limit: 2},
+			fetched:  []int{1, 2, 3},
+			want:     []int{1, 2},
+			wantNext: forwardOf(2),
+		},
+		{
+			name:     "last page by offset",
+			page:     Page{Limit: 2, Offset: 2},
+			fetched:  []int{3},
+			want:     []int{3},
+			wantPrev: backwardOf(3),
+		},
+		{
+			name:     "middle page by cursor",
+			page:     Page{Limit: 2, Cursor: forwardOf(2)},
+			fetched:  []int{3, 4, 5},
+			want:     []int{3, 4},
+			wantNext: forwardOf(4),
+			wantPrev: backwardOf(3),
+		},
+		{
+			name:     "last page by cursor",
+			page:     Page{Limit: 2, Cursor: forwardOf(4)},
+			fetched:  []int{5},
+			want:     []int{5},
+			wantPrev: backwardOf(5),
+		},
+		{
+			name:     "middle page backward",
+			page:     Page{Limit: 2, Cursor: backwardOf(5)},
+			fetched:  []int{4, 3, 2},
+			want:     []int{3, 4},
+			wantNext: forwardOf(4),
+			wantPrev: backwardOf(3),
+		},
+		{
+			name:     "first page backward",
+			page:     Page{Limit: 2, Cursor: backwardOf(3)},
+			fetched:  []int{2, 1},
+			want:     []int{1, 2},
+			wantNext: forwardOf(2),
+		},
+		{
+			name:    "nothing before the cursor",
+			page:    Page{Limit: 2, Cursor: backwardOf(1)},
+			fetched: []int{},
+			want:    []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cursors []Cursor
+			for _, id := range tt.fetched {
+				cursors = append(cursors, cursorOf(id))
+			}
+
+			items, info := FinishPage(tt.fetched, cursors, tt.page)
+			if !reflect.DeepEqual(items, tt.want) {
+				t.Errorf("FinishPage() items = %v, want %v", items, tt.want)
+			}
+			if info.Total != -1 {
+				t.Errorf("FinishPage() total = %d, want -1", info.Total)
+			}
+			if !reflect.DeepEqual(info.Next, tt.wantNext) {
+				t.Errorf("FinishPage() next = %+v, want %+v", info.Next, tt.wantNext)
+			}
+			if !reflect.DeepEqual(info.Prev, tt.wantPrev) {
+				t.Errorf("FinishPage() prev = %+v, want %+v", info.Prev, tt.wantPrev)
+			}
+		})
+	}
+}
+
+func TestPageOrder(t *testing.T) {
+	byName := []SortKey{{Field: "name"}}
+
+	if got := (Page{}).Order(DefaultProgramSort); !reflect.DeepEqual(got, DefaultProgramSort) {
+		t.Errorf("Order() = %v, want the defaults %v", got, DefaultProgramSort)
+	}
+	if got := (Page{Sort: byName}).Order(DefaultProgramSort); !reflect.DeepEqual(got, byName) {
+		t.Errorf("Order() = %v, want %v", got, byName)
+	}
+}
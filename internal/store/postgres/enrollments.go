@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const enrollmentColumns = "id, user_id, program_id, start_date, ended_at, created_at"
+
+func scanEnrollment(row scanner, enrollment *store.Enrollment) error {
+	return row.Scan(
+		&enrollment.ID,
+		&enrollment.UserID,
+		&enrollment.ProgramID,
+		&enrollment.StartDate,
+		&enrollment.EndedAt,
+		&enrollment.CreatedAt,
+	)
+}
+
+func (s *Store) CreateEnrollment(ctx context.Context, enrollment *store.Enrollment) error {
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO program_enrollments (user_id, program_id, start_date)
+		VALUES ($1, $2, $3)
+		RETURNING `+enrollmentColumns,
+		enrollment.UserID,
+		enrollment.ProgramID,
+		enrollment.StartDate,
+	)
+	return mapError(scanEnrollment(row, enrollment))
+}
+
+func (s *Store) GetEnrollment(ctx context.Context, id int) (store.Enrollment, error) {
+	var enrollment store.Enrollment
+	row := s.db.QueryRowContext(ctx, "SELECT "+enrollmentColumns+" FROM program_enrollments WHERE id = $1", id)
+	return enrollment, mapError(scanEnrollment(row, &enrollment))
+}
+
+func (s *Store) ListEnrollments(ctx context.Context, userID int, activeOnly bool) ([]store.Enrollment, error) {
+	var conds conditions
+	conds.where("user_id = " + conds.arg(userID))
+	if activeOnly {
+		conds.where("ended_at IS NULL")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+enrollmentColumns+`
+		FROM program_enrollments
+		`+conds.String()+`
+		ORDER BY start_date DESC, id DESC
+	`, conds.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	enrollments := []store.Enrollment{}
+	for rows.Next() {
+		var enrollment store.Enrollment
+		if err := scanEnrollment(rows, &enrollment); err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, enrollment)
+	}
+	return enrollments, rows.Err()
+}
+
+func (s *Store) EndEnrollment(ctx context.Context, enrollment *store.Enrollment) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE program_enrollments
+		SET ended_at = NOW()
+		WHERE id = $1 AND ended_at IS NULL
+		RETURNING `+enrollmentColumns,
+		enrollment.ID,
+	)
+	err := mapError(scanEnrollment(row, enrollment))
+	if errors.Is(err, store.ErrNotFound) {
+		return store.ErrPrecondition
+	}
+	return err
+}
@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const exerciseColumns = `
+	id, owner_id, name, aliases, equipment, primary_muscles, secondary_muscles, exercise_type,
+	created_at, updated_at, deleted_at
+`
+
+func scanExercise(row scanner, exercise *store.Exercise, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&exercise.ID,
+		&exercise.OwnerID,
+		&exercise.Name,
+		pq.Array(&exercise.Aliases),
+		pq.Array(&exercise.Equipment),
+		pq.Array(&exercise.PrimaryMuscles),
+		pq.Array(&exercise.SecondaryMuscles),
+		&exercise.ExerciseType,
+		&exercise.CreatedAt,
+		&exercise.UpdatedAt,
+		&exercise.DeletedAt,
+	}, extra...)...)
+}
+
+func insertExercise(ctx context.Context, q queryer, exercise *store.Exercise) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO exercises (owner_id, name, aliases, equipment, primary_muscles, secondary_muscles, exercise_type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at, updated_at
+	`,
+		exercise.OwnerID,
+		exercise.Name,
+		pq.Array(nonNil(exercise.Aliases)),
+		pq.Array(nonNil(exercise.Equipment)),
+		pq.Array(nonNil(exercise.PrimaryMuscles)),
+		pq.Array(nonNil(exercise.SecondaryMuscles)),
+		exercise.ExerciseType,
+	).Scan(&exercise.ID, &exercise.CreatedAt, &exercise.UpdatedAt)
+}
+
+func (s *Store) CreateExercise(ctx context.Context, exercise *store.Exercise) error {
+	return mapError(insertExercise(ctx, s.db, exercise))
+}
+
+func (s *Store) GetExercise(ctx context.Context, id int) (store.Exercise, error) {
+	var exercise store.Exercise
+	row := s.db.QueryRowContext(ctx, "SELECT "+exerciseColumns+" FROM exercises WHERE id = $1", id)
+	return exercise, mapError(scanExercise(row, &exercise))
+}
+
+// exerciseSorts are the fields exercises may be sorted by.
+var exerciseSorts = sortable{
+	fields: map[string]string{"name": "name", "exercise_type": "exercise_type", "created_at": "created_at"},
+	id:     "id",
+}
+
+func (s *Store) ListExercises(ctx context.Context, filter store.ExerciseFilter) ([]store.Exercise, store.PageInfo, error) {
+	var conds conditions
+	conds.where("deleted_at IS NULL")
+	conds.where("(owner_id IS NULL OR owner_id = " + conds.arg(filter.VisibleTo) + ")")
+	if filter.Type != "" {
+		conds.where("exercise_type = " + conds.arg(filter.Type))
+	}
+	if filter.Name != "" {
+		conds.where("LOWER(name) = LOWER(" + conds.arg(filter.Name) + ")")
+	}
+
+	keys := filter.Order(store.DefaultExerciseSort)
+	columns, clauses, total, err := exerciseSorts.page(ctx, s, "exercises", &conds, filter.Page, keys)
+	if err != nil {
+		return nil, store.PageInfo{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s%s
+		FROM exercises
+		%s
+		%s
+	`, exerciseColumns, columns, &conds, clauses)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+	defer rows.Close()
+
+	exercises := []store.Exercise{}
+	cursors := []store.Cursor{}
+	for rows.Next() {
+		var exercise store.Exercise
+		cursor, values := newCursor(keys)
+		if err := scanExercise(rows, &exercise, values...); err != nil {
+			return nil, store.PageInfo{}, err
+		}
+		cursor.ID = exercise.ID
+		exercises = append(exercises, exercise)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+
+	exercises, info := store.FinishPage(exercises, cursors, filter.Page)
+	info.Total = total
+	return exercises, info, nil
+}
+
+// searchText is the text searched by SearchExercises. It matches the
+// expression of the search indexes.
+const searchText = "exercise_search_text(name, aliases)"
+
+func (s *Store) SearchExercises(ctx context.Context, search store.ExerciseSearch) ([]store.Exercise, int, store.ExerciseFacets, error) {
+	var facets store.ExerciseFacets
+	var conds conditions
+	conds.where("deleted_at IS NULL")
+	conds.where("(owner_id IS NULL OR owner_id = " + conds.arg(search.VisibleTo) + ")")
+	rank := "0"
+	if search.Query != "" {
+		// Words match through the full-text index and typos through the
+		// trigram index, whose <% operator compares the query with the
+		// closest words of the text.
+		query := conds.arg(search.Query)
+		conds.where(fmt.Sprintf(
+			"(to_tsvector('simple', %[1]s) @@ plainto_tsquery('simple', %[2]s) OR %[2]s <%% %[1]s)",
+			searchText, query,
+		))
+		rank = fmt.Sprintf(
+			"ts_rank(to_tsvector('simple', %[1]s), plainto_tsquery('simple', %[2]s)) + word_similarity(%[2]s, %[1]s)",
+			searchText, query,
+		)
+	}
+	if search.Type != "" {
+		conds.where("exercise_type = " + conds.arg(search.Type))
+	}
+
+	operator := "&&"
+	if search.MatchAll {
+		operator = "@>"
+	}
+	if len(search.Equipment) > 0 {
+		conds.where("equipment " + operator + " " + conds.arg(pq.Array(search.Equipment)))
+	}
+	if len(search.Muscles) > 0 {
+		muscles := conds.arg(pq.Array(search.Muscles))
+		if search.MatchAll {
+			conds.where("(primary_muscles || secondary_muscles) @> " + muscles)
+		} else {
+			conds.where("(primary_muscles && " + muscles + " OR secondary_muscles && " + muscles + ")")
+		}
+	}
+
+	total, err := s.count(ctx, "exercises", &conds)
+	if err != nil {
+		return nil, 0, facets, err
+	}
+
+	if facets.Equipment, err = s.facets(ctx, "equipment", &conds); err != nil {
+		return nil, 0, facets, err
+	}
+	if facets.Muscles, err = s.facets(ctx, "primary_muscles || secondary_muscles", &conds); err != nil {
+		return nil, 0, facets, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM exercises
+		%s
+		ORDER BY %s DESC, name
+		LIMIT %s OFFSET %s
+	`, exerciseColumns, &conds, rank, conds.arg(search.Limit), conds.arg(search.Offset))
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, 0, facets, err
+	}
+	defer rows.Close()
+
+	exercises := []store.Exercise{}
+	for rows.Next() {
+		var exercise store.Exercise
+		if err := scanExercise(rows, &exercise); err != nil {
+			return nil, 0, facets, err
+		}
+		exercises = append(exercises, exercise)
+	}
+	return exercises, total, facets, rows.Err()
+}
+
+// facets counts the exercises matching the conditions by the values of
+// an array expression. Values repeated within an exercise count once.
+func (s *Store) facets(ctx context.Context, array string, conds *conditions) ([]store.Facet, error) {
+	query := fmt.Sprintf(`
+		SELECT facet.value, COUNT(DISTINCT exercises.id)
+		FROM exercises, unnest(%s) AS facet (value)
+		%s
+		GROUP BY facet.value
+		ORDER BY COUNT(DISTINCT exercises.id) DESC, facet.value
+	`, array, conds)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	facets := []store.Facet{}
+	for rows.Next() {
+		var facet store.Facet
+		if err := rows.Scan(&facet.Value, &facet.Count); err != nil {
+			return nil, err
+		}
+		facets = append(facets, facet)
+	}
+	return facets, rows.Err()
+}
+
+func (s *Store) UpdateExercise(ctx context.Context, exercise *store.Exercise) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE exercises
+		SET name = $1, aliases = $2, equipment = $3, primary_muscles = $4, secondary_muscles = $5, exercise_type = $6,
+			updated_at = NOW()
+		WHERE id = $7
+		RETURNING `+exerciseColumns,
+		exercise.Name,
+		pq.Array(nonNil(exercise.Aliases)),
+		pq.Array(nonNil(exercise.Equipment)),
+		pq.Array(nonNil(exercise.PrimaryMuscles)),
+		pq.Array(nonNil(exercise.SecondaryMuscles)),
+		exercise.ExerciseType,
+		exercise.ID,
+	)
+	return mapError(scanExercise(row, exercise))
+}
+
+func (s *Store) DeleteExercise(ctx context.Context, id int) error {
+	err := expectRow(s.db.ExecContext(ctx, "DELETE FROM exercises WHERE id = $1", id))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == foreignKeyViolation {
+		return store.ErrConflict
+	}
+	return err
+}
+
+func (s *Store) SetExerciseDeleted(ctx context.Context, id int, deleted bool) error {
+	return s.setDeleted(ctx, "exercises", id, deleted)
+}
+
+func (s *Store) ExerciseReferences(ctx context.Context, id int) (store.ExerciseReferences, error) {
+	var references store.ExerciseReferences
+	err := s.db.QueryRowContext(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM routine_exercises WHERE exercise_id = $1),
+			(SELECT COUNT(*) FROM workout_sets WHERE exercise_id = $1)
+	`, id).Scan(&references.RoutineExercises, &references.WorkoutSets)
+	return references, err
+}
+
+func (s *Store) ExerciseOwner(ctx context.Context, id int) (store.Owner, error) {
+	var ownerID sql.NullInt64
+	var owner store.Owner
+	err := s.db.QueryRowContext(ctx, `
+		SELECT owner_id, deleted_at IS NOT NULL
+		FROM exercises
+		WHERE id = $1
+	`, id).Scan(&ownerID, &owner.Deleted)
+	if err != nil {
+		return store.Owner{}, mapError(err)
+	}
+	owner.UserID = int(ownerID.Int64)
+	owner.Public = !ownerID.Valid
+	return owner, nil
+}
+
+func (s *Store) MergeExercise(ctx context.Context, fromID, intoID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "UPDATE routine_exercises SET exercise_id = $2 WHERE exercise_id = $1", fromID, intoID)
+		if err != nil {
+			return err
+		}
+
+		// Set indexes start at 1, so shifting the merged sets by the last
+		// index of the other exercise keeps them unique
+		_, err = tx.ExecContext(ctx, `
+			UPDATE workout_sets ws
+			SET exercise_id = $2, set_index = ws.set_index + COALESCE((
+				SELECT MAX(other.set_index)
+				FROM workout_sets other
+				WHERE other.workout_id = ws.workout_id AND other.exercise_id = $2
+			), 0)
+			WHERE ws.exercise_id = $1
+		`, fromID, intoID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE personal_records SET exercise_id = $2 WHERE exercise_id = $1", fromID, intoID)
+		if err != nil {
+			return err
+		}
+
+		// Records only ever improve, so drop those that an earlier record
+		// of the other exercise was already at least as good as
+		_, err = tx.ExecContext(ctx, `
+			DELETE FROM personal_records pr
+			WHERE pr.exercise_id = $1 AND EXISTS (
+				SELECT 1
+				FROM personal_records earlier
+				WHERE earlier.user_id = pr.user_id
+				AND earlier.exercise_id = pr.exercise_id
+				AND earlier.kind = pr.kind
+				AND earlier.weight = pr.weight
+				AND earlier.id < pr.id
+				AND CASE WHEN pr.kind = $2 THEN earlier.value <= pr.value ELSE earlier.value >= pr.value END
+			)
+		`, intoID, store.RecordFastestPace)
+		if err != nil {
+			return err
+		}
+
+		return expectRow(tx.ExecContext(ctx, "DELETE FROM exercises WHERE id = $1", fromID))
+	})
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func (s *Store) ReserveIdempotencyKey(ctx context.Context, key *store.IdempotencyKey) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM idempotency_keys
+			WHERE user_id = $1
+			AND (expires_at <= NOW() OR status_code = 0 AND created_at <= NOW() - make_interval(secs => $2))
+		`, key.UserID, store.IdempotencyLease.Seconds())
+		if err != nil {
+			return err
+		}
+
+		// Requests racing for the same key wait for the first one to
+		// commit its reservation
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO idempotency_keys (user_id, key, fingerprint, expires_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, key) DO NOTHING
+			RETURNING created_at
+		`, key.UserID, key.Key, key.Fingerprint, key.ExpiresAt).Scan(&key.CreatedAt)
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		err = tx.QueryRowContext(ctx, `
+			SELECT fingerprint, status_code, content_type, body, created_at, expires_at
+			FROM idempotency_keys
+			WHERE user_id = $1 AND key = $2
+		`, key.UserID, key.Key).Scan(
+			&key.Fingerprint,
+			&key.StatusCode,
+			&key.ContentType,
+			&key.Body,
+			&key.CreatedAt,
+			&key.ExpiresAt,
+		)
+		if err != nil {
+			return err
+		}
+		return store.ErrConflict
+	})
+}
+
+func (s *Store) SaveIdempotentResponse(ctx context.Context, key *store.IdempotencyKey) error {
+	return expectRow(s.db.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET status_code = $1, content_type = $2, body = $3
+		WHERE user_id = $4 AND key = $5
+	`, key.StatusCode, key.ContentType, key.Body, key.UserID, key.Key))
+}
+
+func (s *Store) ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error {
+	return expectRow(s.db.ExecContext(ctx,
+		"DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2",
+		userID,
+		key,
+	))
+}
@@ -0,0 +1,296 @@
+// Package postgres implements the store interfaces on top of Postgres.
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Store implements every store interface against a Postgres database
+// whose schema is managed by the migrations package.
+type Store struct {
+	db *sql.DB
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New creates a Store using the given database handle.
+func New(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// uniqueViolation is the Postgres error code for unique_violation.
+const uniqueViolation = "23505"
+
+// foreignKeyViolation is the Postgres error code for
+// foreign_key_violation.
+const foreignKeyViolation = "23503"
+
+// mapError translates driver errors into the store's sentinel errors.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return store.ErrConflict
+	}
+	return err
+}
+
+// expectRow returns ErrNotFound if the statement affected no rows.
+func expectRow(result sql.Result, err error) error {
+	if err != nil {
+		return mapError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing if it succeeds and rolling
+// back otherwise.
+func (s *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return mapError(err)
+	}
+	return tx.Commit()
+}
+
+// queryer is implemented by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// conditions accumulates the WHERE clause of a query along with its
+// positional arguments.
+type conditions struct {
+	clauses []string
+	args    []interface{}
+}
+
+// arg records an argument and returns its placeholder.
+func (c *conditions) arg(value interface{}) string {
+	c.args = append(c.args, value)
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// where adds a condition. Conditions are joined with AND.
+func (c *conditions) where(clause string) {
+	c.clauses = append(c.clauses, clause)
+}
+
+func (c *conditions) String() string {
+	if len(c.clauses) == 0 {
+		return ""
+	}
+	return "WHERE " + strings.Join(c.clauses, " AND ")
+}
+
+// count returns the number of rows of table matching the conditions.
+func (s *Store) count(ctx context.Context, table string, conds *conditions) (int, error) {
+	var total int
+	err := s.db.QueryRowContext(
+		ctx, "SELECT COUNT(*) FROM "+table+" "+conds.String(), conds.args...,
+	).Scan(&total)
+	return total, err
+}
+
+// dataException is the class of the Postgres errors raised by invalid
+// values, such as the sort values of a forged cursor.
+const dataException = "22"
+
+// sortable lists the SQL expressions of the fields a list may be sorted
+// by, and of the ID that breaks ties.
+type sortable struct {
+	fields map[string]string
+	id     string
+}
+
+// page prepares the query of a page of a list sorted by keys. It counts
+// the rows matching the conditions if the page asks for it, then adds
+// the condition selecting the rows past its cursor, if it has one. It
+// returns the columns holding the sort values of each row, to select
+// last, and the ORDER BY, LIMIT and OFFSET clauses, which fetch one row
+// past the limit for store.FinishPage.
+func (t sortable) page(ctx context.Context, s *Store, table string, conds *conditions, page store.Page, keys []store.SortKey) (columns, clauses string, total int, err error) {
+	total = -1
+	if page.Count {
+		if total, err = s.count(ctx, table, conds); err != nil {
+			return "", "", 0, err
+		}
+	}
+
+	// Paging backward reverses the order, to fetch the rows closest to
+	// the cursor first
+	backward := page.Cursor != nil && page.Cursor.Backward
+	direction := func(desc bool) string {
+		if desc != backward {
+			return "DESC"
+		}
+		return "ASC"
+	}
+
+	selected := []string{}
+	order := []string{}
+	for _, key := range keys {
+		selected = append(selected, "("+t.fields[key.Field]+")::text")
+		order = append(order, t.fields[key.Field]+" "+direction(key.Desc))
+	}
+	order = append(order, t.id+" "+direction(false))
+
+	// Rows past the cursor come after it on the first field they differ
+	// on
+	if page.Cursor != nil {
+		past := func(column string, desc bool, value interface{}) string {
+			if direction(desc) == "DESC" {
+				return column + " < " + conds.arg(value)
+			}
+			return column + " > " + conds.arg(value)
+		}
+
+		alternatives := []string{}
+		equal := ""
+		for i, key := range keys {
+			alternatives = append(alternatives, "("+equal+past(t.fields[key.Field], key.Desc, page.Cursor.Values[i])+")")
+			equal += t.fields[key.Field] + " = " + conds.arg(page.Cursor.Values[i]) + " AND "
+		}
+		alternatives = append(alternatives, "("+equal+past(t.id, false, page.Cursor.ID)+")")
+		conds.where("(" + strings.Join(alternatives, " OR ") + ")")
+	}
+
+	clauses = "ORDER BY " + strings.Join(order, ", ") + " LIMIT " + conds.arg(page.Limit+1)
+	if page.Cursor == nil {
+		clauses += " OFFSET " + conds.arg(page.Offset)
+	}
+	return ", " + strings.Join(selected, ", "), clauses, total, nil
+}
+
+// newCursor returns the cursor of a row of a list sorted by keys, along
+// with the destinations to scan its sort values into.
+func newCursor(keys []store.SortKey) (store.Cursor, []interface{}) {
+	cursor := store.Cursor{Values: make([]string, len(keys))}
+	dest := make([]interface{}, len(keys))
+	for i := range cursor.Values {
+		dest[i] = &cursor.Values[i]
+	}
+	return cursor, dest
+}
+
+// cursorError reports the invalid values of a forged cursor as
+// ErrPrecondition.
+func cursorError(err error, page store.Page) error {
+	var pqErr *pq.Error
+	if page.Cursor != nil && errors.As(err, &pqErr) && pqErr.Code.Class() == dataException {
+		return store.ErrPrecondition
+	}
+	return err
+}
+
+// setDeleted soft deletes or restores a row of a table with a
+// deleted_at column. It returns ErrPrecondition if the row is already
+// deleted or restored.
+func (s *Store) setDeleted(ctx context.Context, table string, id int, deleted bool) error {
+	err := expectRow(s.db.ExecContext(ctx, `
+		UPDATE `+table+`
+		SET deleted_at = CASE WHEN $2 THEN NOW() END, updated_at = NOW()
+		WHERE id = $1 AND (deleted_at IS NULL) = $2
+	`, id, deleted))
+	if !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return store.ErrPrecondition
+	}
+	return store.ErrNotFound
+}
+
+// nonNil returns an empty slice instead of nil, since the array columns
+// are NOT NULL.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// nonNilInts is nonNil for IDs.
+func nonNilInts(values []int) []int {
+	if values == nil {
+		return []int{}
+	}
+	return values
+}
+
+// int64s converts IDs for pq.Array, which only knows 64-bit integers.
+func int64s(values []int) []int64 {
+	converted := make([]int64, len(values))
+	for i, value := range values {
+		converted[i] = int64(value)
+	}
+	return converted
+}
+
+// jsonColumn stores the value it points to as JSON, for JSONB columns.
+type jsonColumn struct {
+	value interface{}
+}
+
+// jsonb wraps a pointer so that it can be scanned from or written to a
+// JSONB column.
+func jsonb(value interface{}) jsonColumn {
+	return jsonColumn{value}
+}
+
+func (j jsonColumn) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.value)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (j jsonColumn) Scan(src interface{}) error {
+	switch data := src.(type) {
+	case []byte:
+		return json.Unmarshal(data, j.value)
+	case string:
+		return json.Unmarshal([]byte(data), j.value)
+	case nil:
+		return nil
+	}
+	return fmt.Errorf("postgres: cannot scan %T into JSON", src)
+}
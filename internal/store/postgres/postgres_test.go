@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/migrations"
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/store/storetest"
+)
+
+// testDatabaseEnv names the connection string of a throwaway Postgres
+// database to run the store against. Tests that need it are skipped
+// without it.
+const testDatabaseEnv = "FIT_SOARS_TEST_POSTGRES_URL"
+
+func TestStore(t *testing.T) {
+	url := os.Getenv(testDatabaseEnv)
+	if url == "" {
+		t.Skipf("%s not set", testDatabaseEnv)
+	}
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		t.Fatalf("migrations.New() error = %v", err)
+	}
+
+	// Each check starts from an empty database
+	storetest.Run(t, func(t *testing.T) store.Store {
+		ctx := context.Background()
+		if err := migrator.To(ctx, 0); err != nil {
+			t.Fatalf("To(0) error = %v", err)
+		}
+		if err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+		return New(db)
+	})
+}
@@ -0,0 +1,546 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const programColumns = `
+	id, user_id, name, is_public, cycle_days, forked_from_program_id,
+	fork_count, created_at, updated_at, deleted_at
+`
+
+func scanProgram(row scanner, program *store.Program, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&program.ID,
+		&program.UserID,
+		&program.Name,
+		&program.IsPublic,
+		&program.CycleDays,
+		&program.ForkedFromProgramID,
+		&program.ForkCount,
+		&program.CreatedAt,
+		&program.UpdatedAt,
+		&program.DeletedAt,
+	}, extra...)...)
+}
+
+func (s *Store) CreateProgram(ctx context.Context, program *store.Program) error {
+	program.ForkedFromProgramID = nil
+	program.ForkCount = 0
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO programs (user_id, name, is_public, cycle_days)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`,
+		program.UserID,
+		program.Name,
+		program.IsPublic,
+		program.CycleDays,
+	).Scan(&program.ID, &program.CreatedAt, &program.UpdatedAt)
+	return mapError(err)
+}
+
+func (s *Store) ForkProgram(ctx context.Context, fork *store.Program) error {
+	if fork.ForkedFromProgramID == nil {
+		return store.ErrNotFound
+	}
+	originalID := *fork.ForkedFromProgramID
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// Count the fork first, which also locks the original until the
+		// copy is done
+		err := expectRow(tx.ExecContext(ctx, `
+			UPDATE programs SET fork_count = fork_count + 1 WHERE id = $1 AND deleted_at IS NULL
+		`, originalID))
+		if err != nil {
+			return err
+		}
+
+		row := tx.QueryRowContext(ctx, `
+			INSERT INTO programs (user_id, name, is_public, cycle_days, forked_from_program_id)
+			SELECT $1, $2, $3, cycle_days, id
+			FROM programs
+			WHERE id = $4
+			RETURNING `+programColumns,
+			fork.UserID,
+			fork.Name,
+			fork.IsPublic,
+			originalID,
+		)
+		if err := mapError(scanProgram(row, fork)); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO program_blocks (program_id, position, name, weeks, deload, set_scale, rpe_scale)
+			SELECT $1, position, name, weeks, deload, set_scale, rpe_scale
+			FROM program_blocks
+			WHERE program_id = $2
+		`, fork.ID, originalID)
+		if err != nil {
+			return err
+		}
+
+		exerciseIDs, exerciseCopyIDs, err := forkCustomExercises(ctx, tx, originalID, fork.UserID)
+		if err != nil {
+			return err
+		}
+
+		// Routines are copied one by one to pair each with its copy
+		rows, err := tx.QueryContext(ctx, "SELECT id FROM routines WHERE program_id = $1 AND deleted_at IS NULL ORDER BY id", originalID)
+		if err != nil {
+			return err
+		}
+		var routineIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			routineIDs = append(routineIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, routineID := range routineIDs {
+			var copyID int
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO routines (program_id, name, day_number)
+				SELECT $1, name, day_number
+				FROM routines
+				WHERE id = $2
+				RETURNING id
+			`, fork.ID, routineID).Scan(&copyID)
+			if err != nil {
+				return err
+			}
+
+			// Groups are copied one by one too, to point the copied
+			// exercises at the copies of their groups
+			groupIDs, copyIDs, err := forkRoutineGroups(ctx, tx, routineID, copyID)
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO routine_exercises (
+					routine_id, exercise_id, position, group_id,
+					recommended_sets, recommended_reps,
+					recommended_rpe, recommended_duration, recommended_distance,
+					progression_scheme, progression_config
+				)
+				SELECT
+					$1, COALESCE(exercise_copies.copy_id, re.exercise_id), re.position, group_copies.copy_id,
+					re.recommended_sets, re.recommended_reps,
+					re.recommended_rpe, re.recommended_duration, re.recommended_distance,
+					re.progression_scheme, re.progression_config
+				FROM routine_exercises re
+				LEFT JOIN unnest($3::INTEGER[], $4::INTEGER[]) AS group_copies (group_id, copy_id)
+					ON re.group_id = group_copies.group_id
+				LEFT JOIN unnest($5::INTEGER[], $6::INTEGER[]) AS exercise_copies (exercise_id, copy_id)
+					ON re.exercise_id = exercise_copies.exercise_id
+				WHERE re.routine_id = $2
+			`, copyID, routineID, pq.Array(groupIDs), pq.Array(copyIDs), pq.Array(exerciseIDs), pq.Array(exerciseCopyIDs))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// forkCustomExercises copies the custom exercises of other users that
+// the routines of a program use into the catalogue of the user forking
+// it, who could not see them otherwise. It returns the IDs of the
+// exercises along with the IDs of their copies.
+func forkCustomExercises(ctx context.Context, tx *sql.Tx, programID, userID int) ([]int64, []int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT DISTINCT e.id
+		FROM exercises e
+		JOIN routine_exercises re ON re.exercise_id = e.id
+		JOIN routines r ON r.id = re.routine_id
+		WHERE r.program_id = $1 AND r.deleted_at IS NULL
+		AND e.owner_id IS NOT NULL AND e.owner_id <> $2
+		ORDER BY e.id
+	`, programID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var exerciseIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		exerciseIDs = append(exerciseIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	copyIDs := make([]int64, len(exerciseIDs))
+	for i, exerciseID := range exerciseIDs {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO exercises (owner_id, name, aliases, equipment, primary_muscles, secondary_muscles, exercise_type)
+			SELECT $1, name, aliases, equipment, primary_muscles, secondary_muscles, exercise_type
+			FROM exercises
+			WHERE id = $2
+			RETURNING id
+		`, userID, exerciseID).Scan(&copyIDs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return exerciseIDs, copyIDs, nil
+}
+
+// forkRoutineGroups copies the groups of a routine to its copy and
+// returns the IDs of the groups along with the IDs of their copies.
+func forkRoutineGroups(ctx context.Context, tx *sql.Tx, routineID, copyID int) ([]int64, []int64, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM routine_groups WHERE routine_id = $1 ORDER BY id", routineID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var groupIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		groupIDs = append(groupIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	copyIDs := make([]int64, len(groupIDs))
+	for i, groupID := range groupIDs {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO routine_groups (routine_id, kind, rounds, rest_seconds)
+			SELECT $1, kind, rounds, rest_seconds
+			FROM routine_groups
+			WHERE id = $2
+			RETURNING id
+		`, copyID, groupID).Scan(&copyIDs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return groupIDs, copyIDs, nil
+}
+
+func (s *Store) CreateProgramTree(ctx context.Context, tree *store.ProgramTree) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		program := &tree.Program
+		program.ForkedFromProgramID = nil
+		program.ForkCount = 0
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO programs (user_id, name, is_public, cycle_days)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at
+		`,
+			program.UserID,
+			program.Name,
+			program.IsPublic,
+			program.CycleDays,
+		).Scan(&program.ID, &program.CreatedAt, &program.UpdatedAt)
+		if err != nil {
+			return mapError(err)
+		}
+
+		for i := range tree.Blocks {
+			block := &tree.Blocks[i]
+			block.ProgramID = program.ID
+			block.Position = i + 1
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO program_blocks (program_id, position, name, weeks, deload, set_scale, rpe_scale)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id, created_at, updated_at
+			`,
+				block.ProgramID,
+				block.Position,
+				block.Name,
+				block.Weeks,
+				block.Deload,
+				block.SetScale,
+				block.RPEScale,
+			).Scan(&block.ID, &block.CreatedAt, &block.UpdatedAt)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+
+		for i := range tree.Routines {
+			routine := &tree.Routines[i]
+			routine.ProgramID = program.ID
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO routines (program_id, name, day_number)
+				VALUES ($1, $2, $3)
+				RETURNING id, created_at, updated_at
+			`, routine.ProgramID, routine.Name, routine.DayNumber).Scan(&routine.ID, &routine.CreatedAt, &routine.UpdatedAt)
+			if err != nil {
+				return mapError(err)
+			}
+
+			for j := range routine.Groups {
+				group := &routine.Groups[j]
+				group.RoutineID = routine.ID
+				group.RoutineExerciseIDs = []int{}
+				err := tx.QueryRowContext(ctx, `
+					INSERT INTO routine_groups (routine_id, kind, rounds, rest_seconds)
+					VALUES ($1, $2, $3, $4)
+					RETURNING id, created_at, updated_at
+				`, group.RoutineID, group.Kind, group.Rounds, group.RestSeconds).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+				if err != nil {
+					return mapError(err)
+				}
+			}
+
+			for j := range routine.Exercises {
+				routineExercise := &routine.Exercises[j]
+				routineExercise.RoutineID = routine.ID
+				routineExercise.Position = j + 1
+
+				// Exercises refer to their group by its index in the tree
+				var groupID *int
+				var group *store.RoutineGroup
+				if routineExercise.GroupID != nil {
+					group = &routine.Groups[*routineExercise.GroupID-1]
+					groupID = &group.ID
+				}
+
+				err := tx.QueryRowContext(ctx, `
+					INSERT INTO routine_exercises (
+						routine_id, exercise_id, position, group_id,
+						recommended_sets, recommended_reps,
+						recommended_rpe, recommended_duration, recommended_distance,
+						progression_scheme, progression_config
+					)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+					RETURNING id, created_at, updated_at
+				`,
+					routineExercise.RoutineID,
+					routineExercise.ExerciseID,
+					routineExercise.Position,
+					groupID,
+					routineExercise.RecommendedSets,
+					routineExercise.RecommendedReps,
+					routineExercise.RecommendedRPE,
+					routineExercise.RecommendedDuration,
+					routineExercise.RecommendedDistance,
+					routineExercise.ProgressionScheme,
+					jsonb(routineExercise.ProgressionConfig),
+				).Scan(&routineExercise.ID, &routineExercise.CreatedAt, &routineExercise.UpdatedAt)
+				if err != nil {
+					return mapError(err)
+				}
+				if group != nil {
+					group.RoutineExerciseIDs = append(group.RoutineExerciseIDs, routineExercise.ID)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) GetProgram(ctx context.Context, id int) (store.Program, error) {
+	var program store.Program
+	row := s.db.QueryRowContext(ctx, "SELECT "+programColumns+" FROM programs WHERE id = $1", id)
+	return program, mapError(scanProgram(row, &program))
+}
+
+// programSorts are the fields programs may be sorted by.
+var programSorts = sortable{
+	fields: map[string]string{"name": "name", "cycle_days": "cycle_days", "created_at": "created_at", "updated_at": "updated_at"},
+	id:     "id",
+}
+
+func (s *Store) ListPrograms(ctx context.Context, filter store.ProgramFilter) ([]store.Program, store.PageInfo, error) {
+	var conds conditions
+	conds.where("deleted_at IS NULL")
+	conds.where("(user_id = " + conds.arg(filter.VisibleTo) + " OR is_public = true)")
+
+	keys := filter.Order(store.DefaultProgramSort)
+	columns, clauses, total, err := programSorts.page(ctx, s, "programs", &conds, filter.Page, keys)
+	if err != nil {
+		return nil, store.PageInfo{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s%s
+		FROM programs
+		%s
+		%s
+	`, programColumns, columns, &conds, clauses)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+	defer rows.Close()
+
+	programs := []store.Program{}
+	cursors := []store.Cursor{}
+	for rows.Next() {
+		var program store.Program
+		cursor, values := newCursor(keys)
+		if err := scanProgram(rows, &program, values...); err != nil {
+			return nil, store.PageInfo{}, err
+		}
+		cursor.ID = program.ID
+		programs = append(programs, program)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+
+	programs, info := store.FinishPage(programs, cursors, filter.Page)
+	info.Total = total
+	return programs, info, nil
+}
+
+func (s *Store) UpdateProgram(ctx context.Context, program *store.Program) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE programs
+		SET name = $1, is_public = $2, cycle_days = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING `+programColumns,
+		program.Name,
+		program.IsPublic,
+		program.CycleDays,
+		program.ID,
+	)
+	return mapError(scanProgram(row, program))
+}
+
+func (s *Store) DeleteProgram(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// Delete all routine_exercises for routines in this program
+		_, err := tx.ExecContext(ctx, `
+			DELETE FROM routine_exercises
+			WHERE routine_id IN (SELECT id FROM routines WHERE program_id = $1)
+		`, id)
+		if err != nil {
+			return err
+		}
+
+		// Delete all routines in this program
+		if _, err := tx.ExecContext(ctx, "DELETE FROM routines WHERE program_id = $1", id); err != nil {
+			return err
+		}
+
+		// Delete the blocks and enrollments of this program
+		if _, err := tx.ExecContext(ctx, "DELETE FROM program_blocks WHERE program_id = $1", id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM program_enrollments WHERE program_id = $1", id); err != nil {
+			return err
+		}
+
+		// Delete the program
+		return expectRow(tx.ExecContext(ctx, "DELETE FROM programs WHERE id = $1", id))
+	})
+}
+
+func (s *Store) ProgramOwner(ctx context.Context, id int) (store.Owner, error) {
+	var owner store.Owner
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, is_public, deleted_at IS NOT NULL
+		FROM programs
+		WHERE id = $1
+	`, id).Scan(&owner.UserID, &owner.Public, &owner.Deleted)
+	return owner, mapError(err)
+}
+
+func (s *Store) SetProgramDeleted(ctx context.Context, id int, deleted bool) error {
+	return s.setDeleted(ctx, "programs", id, deleted)
+}
+
+// -------------------- Program blocks --------------------
+
+const programBlockColumns = `
+	id, program_id, position, name, weeks, deload, set_scale, rpe_scale,
+	created_at, updated_at
+`
+
+func scanProgramBlock(row scanner, block *store.ProgramBlock) error {
+	return row.Scan(
+		&block.ID,
+		&block.ProgramID,
+		&block.Position,
+		&block.Name,
+		&block.Weeks,
+		&block.Deload,
+		&block.SetScale,
+		&block.RPEScale,
+		&block.CreatedAt,
+		&block.UpdatedAt,
+	)
+}
+
+func (s *Store) ListProgramBlocks(ctx context.Context, programID int) ([]store.ProgramBlock, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+programBlockColumns+`
+		FROM program_blocks
+		WHERE program_id = $1
+		ORDER BY position
+	`, programID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []store.ProgramBlock{}
+	for rows.Next() {
+		var block store.ProgramBlock
+		if err := scanProgramBlock(rows, &block); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, rows.Err()
+}
+
+func (s *Store) SetProgramBlocks(ctx context.Context, programID int, blocks []store.ProgramBlock) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM program_blocks WHERE program_id = $1", programID); err != nil {
+			return err
+		}
+
+		for i := range blocks {
+			block := &blocks[i]
+			block.ProgramID = programID
+			block.Position = i + 1
+			err := tx.QueryRowContext(ctx, `
+				INSERT INTO program_blocks (program_id, position, name, weeks, deload, set_scale, rpe_scale)
+				VALUES ($1, $2, $3, $4, $5, $6, $7)
+				RETURNING id, created_at, updated_at
+			`,
+				block.ProgramID,
+				block.Position,
+				block.Name,
+				block.Weeks,
+				block.Deload,
+				block.SetScale,
+				block.RPEScale,
+			).Scan(&block.ID, &block.CreatedAt, &block.UpdatedAt)
+			if err != nil {
+				return mapError(err)
+			}
+		}
+		return nil
+	})
+}
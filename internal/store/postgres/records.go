@@ -0,0 +1,282 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/soa-rs/fit/internal/store"
+	"github.com/soa-rs/fit/internal/training"
+)
+
+func (s *Store) SetPersonalRecords(ctx context.Context, setID int) ([]store.PersonalRecord, error) {
+	records := []store.PersonalRecord{}
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		// Forget the records the set earned before it changed, and hand
+		// them to the best of the sets, which may still be this one
+		dropped, err := deleteRecords(ctx, tx, "workout_set_id = $1", setID)
+		if err != nil {
+			return err
+		}
+		restored, err := restoreRecords(ctx, tx, dropped)
+		if err != nil {
+			return err
+		}
+		for _, record := range restored {
+			if record.WorkoutSetID == setID {
+				records = append(records, record)
+			}
+		}
+
+		var set store.WorkoutSet
+		var exerciseType string
+		err = tx.QueryRowContext(ctx, `
+			SELECT ws.id, ws.completed, ws.reps, ws.weight, ws.duration, ws.distance, e.exercise_type
+			FROM workout_sets ws
+			JOIN exercises e ON ws.exercise_id = e.id
+			WHERE ws.id = $1
+		`, setID).Scan(&set.ID, &set.Completed, &set.Reps, &set.Weight, &set.Duration, &set.Distance, &exerciseType)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		for _, record := range training.RecordCandidates(set, exerciseType) {
+			inserted, err := insertRecord(ctx, tx, setID, &record)
+			if err != nil {
+				return err
+			}
+			if inserted {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// insertRecord inserts the record earned by a set unless its user
+// already holds a record at least as good, and reports whether it did.
+// The record only needs a Kind, a Value and, for reps at weight
+// records, a Weight.
+func insertRecord(ctx context.Context, tx *sql.Tx, setID int, record *store.PersonalRecord) (bool, error) {
+	better := ">="
+	if record.Kind == store.RecordFastestPace {
+		better = "<="
+	}
+
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO personal_records (user_id, exercise_id, workout_set_id, kind, value, weight, achieved_at)
+		SELECT w.user_id, ws.exercise_id, ws.id, $2, $3, $4, w.performed_at
+		FROM workout_sets ws
+		JOIN workouts w ON ws.workout_id = w.id
+		WHERE ws.id = $1 AND NOT EXISTS (
+			SELECT 1
+			FROM personal_records pr
+			JOIN workout_sets prs ON pr.workout_set_id = prs.id
+			JOIN workouts prw ON prs.workout_id = prw.id
+			WHERE pr.user_id = w.user_id
+			AND prw.deleted_at IS NULL
+			AND pr.exercise_id = ws.exercise_id
+			AND pr.kind = $2
+			AND pr.weight = $4
+			AND pr.value `+better+` $3
+		)
+		RETURNING id, user_id, exercise_id, workout_set_id, achieved_at, created_at
+	`, setID, record.Kind, record.Value, record.Weight).Scan(
+		&record.ID,
+		&record.UserID,
+		&record.ExerciseID,
+		&record.WorkoutSetID,
+		&record.AchievedAt,
+		&record.CreatedAt,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// deleteRecords deletes the records earned by the sets matching the
+// condition, and returns them.
+func deleteRecords(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) ([]store.PersonalRecord, error) {
+	rows, err := tx.QueryContext(ctx, `
+		DELETE FROM personal_records
+		WHERE `+condition+`
+		RETURNING id, user_id, exercise_id, workout_set_id, kind, value, weight
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dropped []store.PersonalRecord
+	for rows.Next() {
+		var record store.PersonalRecord
+		if err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.ExerciseID,
+			&record.WorkoutSetID,
+			&record.Kind,
+			&record.Value,
+			&record.Weight,
+		); err != nil {
+			return nil, err
+		}
+		dropped = append(dropped, record)
+	}
+	return dropped, rows.Err()
+}
+
+// recordKey identifies the records of a user that supersede one
+// another.
+type recordKey struct {
+	userID     int
+	exerciseID int
+	kind       string
+	weight     float64
+}
+
+func keyOf(record store.PersonalRecord) recordKey {
+	return recordKey{record.UserID, record.ExerciseID, record.Kind, record.Weight}
+}
+
+// restoreRecords hands records that were dropped along with their sets
+// to the best remaining sets of their users, and returns the records
+// it inserts. Sets performed first win ties.
+func restoreRecords(ctx context.Context, tx *sql.Tx, dropped []store.PersonalRecord) ([]store.PersonalRecord, error) {
+	type bestSet struct {
+		setID  int
+		record store.PersonalRecord
+	}
+	var keys []recordKey
+	best := map[recordKey]*bestSet{}
+	for _, record := range dropped {
+		key := keyOf(record)
+		if _, ok := best[key]; !ok {
+			keys = append(keys, key)
+			best[key] = nil
+		}
+	}
+
+	// The sets of each exercise are read once for all its records
+	read := map[[2]int]bool{}
+	for _, key := range keys {
+		exercise := [2]int{key.userID, key.exerciseID}
+		if read[exercise] {
+			continue
+		}
+		read[exercise] = true
+
+		rows, err := tx.QueryContext(ctx, `
+			SELECT ws.id, ws.completed, ws.reps, ws.weight, ws.duration, ws.distance, e.exercise_type
+			FROM workout_sets ws
+			JOIN workouts w ON ws.workout_id = w.id
+			JOIN exercises e ON ws.exercise_id = e.id
+			WHERE w.user_id = $1 AND ws.exercise_id = $2 AND ws.completed AND w.deleted_at IS NULL
+			ORDER BY w.performed_at, ws.id
+		`, key.userID, key.exerciseID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var set store.WorkoutSet
+			var exerciseType string
+			if err := rows.Scan(&set.ID, &set.Completed, &set.Reps, &set.Weight, &set.Duration, &set.Distance, &exerciseType); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			for _, candidate := range training.RecordCandidates(set, exerciseType) {
+				candidate.UserID, candidate.ExerciseID = key.userID, key.exerciseID
+				current, ok := best[keyOf(candidate)]
+				if ok && (current == nil || candidate.Beats(current.record)) {
+					best[keyOf(candidate)] = &bestSet{setID: set.ID, record: candidate}
+				}
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	var restored []store.PersonalRecord
+	for _, key := range keys {
+		if best[key] == nil {
+			continue
+		}
+		record := best[key].record
+		inserted, err := insertRecord(ctx, tx, best[key].setID, &record)
+		if err != nil {
+			return nil, err
+		}
+		if inserted {
+			restored = append(restored, record)
+		}
+	}
+	return restored, nil
+}
+
+func (s *Store) ListPersonalRecords(ctx context.Context, filter store.RecordFilter) ([]store.PersonalRecordWithDetails, error) {
+	var conds conditions
+	conds.where("pr.user_id = " + conds.arg(filter.UserID))
+	conds.where("w.deleted_at IS NULL")
+	if filter.ExerciseID != 0 {
+		conds.where("pr.exercise_id = " + conds.arg(filter.ExerciseID))
+	}
+
+	// Records only ever improve, so the current record of each kind is
+	// the last one that was earned
+	distinct := ""
+	if !filter.History {
+		distinct = "DISTINCT ON (e.name, pr.exercise_id, pr.kind, pr.weight)"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s
+			pr.id, pr.user_id, pr.exercise_id, pr.workout_set_id,
+			pr.kind, pr.value, pr.weight, pr.achieved_at, pr.created_at,
+			e.name, e.exercise_type
+		FROM personal_records pr
+		JOIN exercises e ON pr.exercise_id = e.id
+		JOIN workout_sets ws ON pr.workout_set_id = ws.id
+		JOIN workouts w ON ws.workout_id = w.id
+		%s
+		ORDER BY e.name, pr.exercise_id, pr.kind, pr.weight, pr.id DESC
+	`, distinct, &conds)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []store.PersonalRecordWithDetails{}
+	for rows.Next() {
+		var record store.PersonalRecordWithDetails
+		if err := rows.Scan(
+			&record.ID,
+			&record.UserID,
+			&record.ExerciseID,
+			&record.WorkoutSetID,
+			&record.Kind,
+			&record.Value,
+			&record.Weight,
+			&record.AchievedAt,
+			&record.CreatedAt,
+			&record.ExerciseName,
+			&record.ExerciseType,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
@@ -0,0 +1,521 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const routineColumns = "id, program_id, name, day_number, created_at, updated_at, deleted_at"
+
+func scanRoutine(row scanner, routine *store.Routine, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&routine.ID,
+		&routine.ProgramID,
+		&routine.Name,
+		&routine.DayNumber,
+		&routine.CreatedAt,
+		&routine.UpdatedAt,
+		&routine.DeletedAt,
+	}, extra...)...)
+}
+
+func (s *Store) CreateRoutine(ctx context.Context, routine *store.Routine) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO routines (program_id, name, day_number)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`, routine.ProgramID, routine.Name, routine.DayNumber).Scan(&routine.ID, &routine.CreatedAt, &routine.UpdatedAt)
+	return mapError(err)
+}
+
+func (s *Store) GetRoutine(ctx context.Context, id int) (store.Routine, error) {
+	var routine store.Routine
+	row := s.db.QueryRowContext(ctx, "SELECT "+routineColumns+" FROM routines WHERE id = $1", id)
+	return routine, mapError(scanRoutine(row, &routine))
+}
+
+// routineSorts are the fields routines may be sorted by.
+var routineSorts = sortable{
+	fields: map[string]string{"day_number": "day_number", "name": "name", "created_at": "created_at"},
+	id:     "id",
+}
+
+func (s *Store) ListRoutines(ctx context.Context, filter store.RoutineFilter) ([]store.Routine, store.PageInfo, error) {
+	var conds conditions
+	conds.where("deleted_at IS NULL")
+	conds.where("program_id IN (SELECT id FROM programs WHERE deleted_at IS NULL AND (user_id = " + conds.arg(filter.VisibleTo) + " OR is_public = true))")
+	if filter.ProgramID != 0 {
+		conds.where("program_id = " + conds.arg(filter.ProgramID))
+	}
+
+	keys := filter.Order(store.DefaultRoutineSort)
+	columns, clauses, total, err := routineSorts.page(ctx, s, "routines", &conds, filter.Page, keys)
+	if err != nil {
+		return nil, store.PageInfo{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s%s
+		FROM routines
+		%s
+		%s
+	`, routineColumns, columns, &conds, clauses)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+	defer rows.Close()
+
+	routines := []store.Routine{}
+	cursors := []store.Cursor{}
+	for rows.Next() {
+		var routine store.Routine
+		cursor, values := newCursor(keys)
+		if err := scanRoutine(rows, &routine, values...); err != nil {
+			return nil, store.PageInfo{}, err
+		}
+		cursor.ID = routine.ID
+		routines = append(routines, routine)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+
+	routines, info := store.FinishPage(routines, cursors, filter.Page)
+	info.Total = total
+	return routines, info, nil
+}
+
+func (s *Store) UpdateRoutine(ctx context.Context, routine *store.Routine) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE routines
+		SET name = $1, day_number = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+routineColumns,
+		routine.Name,
+		routine.DayNumber,
+		routine.ID,
+	)
+	return mapError(scanRoutine(row, routine))
+}
+
+func (s *Store) DeleteRoutine(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		// Delete all routine_exercises for this routine
+		if _, err := tx.ExecContext(ctx, "DELETE FROM routine_exercises WHERE routine_id = $1", id); err != nil {
+			return err
+		}
+
+		// Delete the routine
+		return expectRow(tx.ExecContext(ctx, "DELETE FROM routines WHERE id = $1", id))
+	})
+}
+
+func (s *Store) RoutineOwner(ctx context.Context, id int) (store.Owner, error) {
+	var owner store.Owner
+	err := s.db.QueryRowContext(ctx, `
+		SELECT p.user_id, p.is_public, r.deleted_at IS NOT NULL OR p.deleted_at IS NOT NULL
+		FROM routines r
+		JOIN programs p ON r.program_id = p.id
+		WHERE r.id = $1
+	`, id).Scan(&owner.UserID, &owner.Public, &owner.Deleted)
+	return owner, mapError(err)
+}
+
+func (s *Store) SetRoutineDeleted(ctx context.Context, id int, deleted bool) error {
+	return s.setDeleted(ctx, "routines", id, deleted)
+}
+
+// -------------------- Routine exercises --------------------
+
+const routineExerciseColumns = `
+	id, routine_id, exercise_id, position, group_id,
+	recommended_sets, recommended_reps, recommended_rpe,
+	recommended_duration, recommended_distance,
+	progression_scheme, progression_config,
+	created_at, updated_at
+`
+
+func scanRoutineExercise(row scanner, routineExercise *store.RoutineExercise, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&routineExercise.ID,
+		&routineExercise.RoutineID,
+		&routineExercise.ExerciseID,
+		&routineExercise.Position,
+		&routineExercise.GroupID,
+		&routineExercise.RecommendedSets,
+		&routineExercise.RecommendedReps,
+		&routineExercise.RecommendedRPE,
+		&routineExercise.RecommendedDuration,
+		&routineExercise.RecommendedDistance,
+		&routineExercise.ProgressionScheme,
+		jsonb(&routineExercise.ProgressionConfig),
+		&routineExercise.CreatedAt,
+		&routineExercise.UpdatedAt,
+	}, extra...)...)
+}
+
+// lockRoutine locks a routine until the end of the transaction, so
+// that the positions of its exercises are renumbered one at a time.
+func lockRoutine(ctx context.Context, tx *sql.Tx, routineID int) error {
+	var id int
+	err := tx.QueryRowContext(ctx, "SELECT id FROM routines WHERE id = $1 FOR UPDATE", routineID).Scan(&id)
+	return mapError(err)
+}
+
+func (s *Store) AddRoutineExercise(ctx context.Context, routineExercise *store.RoutineExercise) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRoutine(ctx, tx, routineExercise.RoutineID); err != nil {
+			return err
+		}
+
+		var count int
+		err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM routine_exercises WHERE routine_id = $1", routineExercise.RoutineID,
+		).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if routineExercise.Position <= 0 || routineExercise.Position > count {
+			routineExercise.Position = count + 1
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE routine_exercises
+			SET position = position + 1
+			WHERE routine_id = $1 AND position >= $2
+		`, routineExercise.RoutineID, routineExercise.Position)
+		if err != nil {
+			return err
+		}
+
+		routineExercise.GroupID = nil
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO routine_exercises (
+				routine_id, exercise_id, position, recommended_sets, recommended_reps,
+				recommended_rpe, recommended_duration, recommended_distance,
+				progression_scheme, progression_config
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING id, created_at, updated_at
+		`,
+			routineExercise.RoutineID,
+			routineExercise.ExerciseID,
+			routineExercise.Position,
+			routineExercise.RecommendedSets,
+			routineExercise.RecommendedReps,
+			routineExercise.RecommendedRPE,
+			routineExercise.RecommendedDuration,
+			routineExercise.RecommendedDistance,
+			routineExercise.ProgressionScheme,
+			jsonb(routineExercise.ProgressionConfig),
+		).Scan(&routineExercise.ID, &routineExercise.CreatedAt, &routineExercise.UpdatedAt)
+	})
+}
+
+func (s *Store) ListRoutineExercises(ctx context.Context, routineID int) ([]store.RoutineExerciseWithDetails, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			re.id, re.routine_id, re.exercise_id, re.position, re.group_id,
+			re.recommended_sets, re.recommended_reps, re.recommended_rpe,
+			re.recommended_duration, re.recommended_distance,
+			re.progression_scheme, re.progression_config,
+			re.created_at, re.updated_at,
+			e.name, e.exercise_type
+		FROM routine_exercises re
+		JOIN exercises e ON re.exercise_id = e.id
+		WHERE re.routine_id = $1
+		ORDER BY re.position, re.id
+	`, routineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exercises := []store.RoutineExerciseWithDetails{}
+	for rows.Next() {
+		var exercise store.RoutineExerciseWithDetails
+		if err := scanRoutineExercise(
+			rows, &exercise.RoutineExercise, &exercise.ExerciseName, &exercise.ExerciseType,
+		); err != nil {
+			return nil, err
+		}
+		exercises = append(exercises, exercise)
+	}
+	return exercises, rows.Err()
+}
+
+func (s *Store) UpdateRoutineExercise(ctx context.Context, routineExercise *store.RoutineExercise) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE routine_exercises
+		SET
+			recommended_sets = $1,
+			recommended_reps = $2,
+			recommended_rpe = $3,
+			recommended_duration = $4,
+			recommended_distance = $5,
+			progression_scheme = $6,
+			progression_config = $7,
+			updated_at = NOW()
+		WHERE id = $8 AND routine_id = $9
+		RETURNING `+routineExerciseColumns,
+		routineExercise.RecommendedSets,
+		routineExercise.RecommendedReps,
+		routineExercise.RecommendedRPE,
+		routineExercise.RecommendedDuration,
+		routineExercise.RecommendedDistance,
+		routineExercise.ProgressionScheme,
+		jsonb(routineExercise.ProgressionConfig),
+		routineExercise.ID,
+		routineExercise.RoutineID,
+	)
+	return mapError(scanRoutineExercise(row, routineExercise))
+}
+
+func (s *Store) RemoveRoutineExercise(ctx context.Context, routineID, id int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRoutine(ctx, tx, routineID); err != nil {
+			return err
+		}
+
+		var position int
+		err := tx.QueryRowContext(ctx, `
+			DELETE FROM routine_exercises
+			WHERE id = $1 AND routine_id = $2
+			RETURNING position
+		`, id, routineID).Scan(&position)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE routine_exercises
+			SET position = position - 1
+			WHERE routine_id = $1 AND position > $2
+		`, routineID, position)
+		if err != nil {
+			return err
+		}
+		return dissolveGroups(ctx, tx, routineID)
+	})
+}
+
+func (s *Store) ReorderRoutineExercises(ctx context.Context, routineID int, ids []int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRoutine(ctx, tx, routineID); err != nil {
+			return err
+		}
+
+		var count int
+		err := tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM routine_exercises WHERE routine_id = $1", routineID,
+		).Scan(&count)
+		if err != nil {
+			return err
+		}
+		if count != len(ids) {
+			return store.ErrPrecondition
+		}
+
+		// Every exercise is listed once if every ID numbers one of them
+		result, err := tx.ExecContext(ctx, `
+			UPDATE routine_exercises re
+			SET position = numbered.position, updated_at = NOW()
+			FROM unnest($2::INTEGER[]) WITH ORDINALITY AS numbered (id, position)
+			WHERE re.id = numbered.id AND re.routine_id = $1
+		`, routineID, pq.Array(int64s(ids)))
+		if err != nil {
+			return err
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if int(updated) != count {
+			return store.ErrPrecondition
+		}
+		return nil
+	})
+}
+
+// -------------------- Routine groups --------------------
+
+const routineGroupColumns = "id, routine_id, kind, rounds, rest_seconds, created_at, updated_at"
+
+func scanRoutineGroup(row scanner, group *store.RoutineGroup) error {
+	return row.Scan(
+		&group.ID,
+		&group.RoutineID,
+		&group.Kind,
+		&group.Rounds,
+		&group.RestSeconds,
+		&group.CreatedAt,
+		&group.UpdatedAt,
+	)
+}
+
+// setGroupExercises makes the routine exercises the exercises of the
+// group, and reads them back by position.
+func setGroupExercises(ctx context.Context, tx *sql.Tx, group *store.RoutineGroup) error {
+	ids := pq.Array(int64s(group.RoutineExerciseIDs))
+
+	var count int
+	err := tx.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM routine_exercises
+		WHERE routine_id = $1 AND id = ANY($2)
+	`, group.RoutineID, ids).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count != len(group.RoutineExerciseIDs) {
+		return store.ErrPrecondition
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE routine_exercises
+		SET group_id = CASE WHEN id = ANY($2) THEN $1::INTEGER END, updated_at = NOW()
+		WHERE group_id = $1 OR id = ANY($2)
+	`, group.ID, ids)
+	if err != nil {
+		return err
+	}
+
+	if err := dissolveGroups(ctx, tx, group.RoutineID); err != nil {
+		return err
+	}
+
+	members, err := groupExercises(ctx, tx, group.RoutineID)
+	group.RoutineExerciseIDs = nonNilInts(members[group.ID])
+	return err
+}
+
+// groupExercises returns the IDs of the exercises of each group of a
+// routine, by position.
+func groupExercises(ctx context.Context, q queryer, routineID int) (map[int][]int, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, group_id
+		FROM routine_exercises
+		WHERE routine_id = $1 AND group_id IS NOT NULL
+		ORDER BY position, id
+	`, routineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := map[int][]int{}
+	for rows.Next() {
+		var id, groupID int
+		if err := rows.Scan(&id, &groupID); err != nil {
+			return nil, err
+		}
+		members[groupID] = append(members[groupID], id)
+	}
+	return members, rows.Err()
+}
+
+// dissolveGroups deletes the groups of a routine that are left with
+// fewer than two exercises.
+func dissolveGroups(ctx context.Context, tx *sql.Tx, routineID int) error {
+	_, err := tx.ExecContext(ctx, `
+		DELETE FROM routine_groups g
+		WHERE g.routine_id = $1 AND (
+			SELECT COUNT(*) FROM routine_exercises re WHERE re.group_id = g.id
+		) < 2
+	`, routineID)
+	return err
+}
+
+func (s *Store) CreateRoutineGroup(ctx context.Context, group *store.RoutineGroup) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRoutine(ctx, tx, group.RoutineID); err != nil {
+			return err
+		}
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO routine_groups (routine_id, kind, rounds, rest_seconds)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at, updated_at
+		`, group.RoutineID, group.Kind, group.Rounds, group.RestSeconds).Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		return setGroupExercises(ctx, tx, group)
+	})
+}
+
+func (s *Store) ListRoutineGroups(ctx context.Context, routineID int) ([]store.RoutineGroup, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+routineGroupColumns+`
+		FROM routine_groups g
+		WHERE routine_id = $1
+		ORDER BY (SELECT MIN(position) FROM routine_exercises re WHERE re.group_id = g.id), id
+	`, routineID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := []store.RoutineGroup{}
+	for rows.Next() {
+		var group store.RoutineGroup
+		if err := scanRoutineGroup(rows, &group); err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := groupExercises(ctx, s.db, routineID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		groups[i].RoutineExerciseIDs = nonNilInts(members[groups[i].ID])
+	}
+	return groups, nil
+}
+
+func (s *Store) UpdateRoutineGroup(ctx context.Context, group *store.RoutineGroup) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := lockRoutine(ctx, tx, group.RoutineID); err != nil {
+			return err
+		}
+
+		ids := group.RoutineExerciseIDs
+		row := tx.QueryRowContext(ctx, `
+			UPDATE routine_groups
+			SET kind = $1, rounds = $2, rest_seconds = $3, updated_at = NOW()
+			WHERE id = $4 AND routine_id = $5
+			RETURNING `+routineGroupColumns,
+			group.Kind,
+			group.Rounds,
+			group.RestSeconds,
+			group.ID,
+			group.RoutineID,
+		)
+		if err := scanRoutineGroup(row, group); err != nil {
+			return err
+		}
+
+		group.RoutineExerciseIDs = ids
+		return setGroupExercises(ctx, tx, group)
+	})
+}
+
+func (s *Store) DeleteRoutineGroup(ctx context.Context, routineID, id int) error {
+	return expectRow(s.db.ExecContext(ctx,
+		"DELETE FROM routine_groups WHERE id = $1 AND routine_id = $2",
+		id,
+		routineID,
+	))
+}
@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// change is a row listed by ListChanges, placed by its change_xact and
+// change_seq.
+type change struct {
+	cursor store.ChangeCursor
+	// add appends the row to the changes
+	add func(changes *store.Changes)
+}
+
+// changeColumns are the columns placing a change, which the queries of
+// ListChanges select last.
+const changeColumns = "change_xact::TEXT::BIGINT, change_seq"
+
+// changedAfter lists the changes after the cursor in $2 and $3, up to
+// $4 of them. Changes of transactions that started after one still
+// running are left out, as that one may yet commit changes before them.
+const changedAfter = `
+	AND (change_xact, change_seq) > ($2::TEXT::xid8, $3)
+	AND change_xact < pg_snapshot_xmin(pg_current_snapshot())
+	ORDER BY change_xact, change_seq
+	LIMIT $4
+`
+
+// listChanges runs a query listing changed rows and scans them with
+// scan, which must scan the changeColumns last.
+func listChanges(ctx context.Context, q queryer, query string, args []interface{}, scan func(row scanner) (change, error)) ([]change, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []change{}
+	for rows.Next() {
+		listed, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, listed)
+	}
+	return changes, rows.Err()
+}
+
+func (s *Store) ListChanges(ctx context.Context, userID int, since store.ChangeCursor, limit int) (store.Changes, error) {
+	changes := store.Changes{
+		Programs:         []store.Program{},
+		Routines:         []store.Routine{},
+		RoutineExercises: []store.RoutineExercise{},
+		Workouts:         []store.Workout{},
+		WorkoutSets:      []store.WorkoutSet{},
+		Tombstones:       []store.Tombstone{},
+		Cursor:           since,
+	}
+
+	// Each table lists one change more than the limit, which is enough
+	// to tell whether the merged list goes on
+	args := []interface{}{userID, since.Xact, since.Seq, limit + 1}
+	queries := []struct {
+		query string
+		scan  func(row scanner) (change, error)
+	}{
+		{`
+			SELECT ` + programColumns + `, ` + changeColumns + `
+			FROM programs
+			WHERE user_id = $1
+		` + changedAfter, func(row scanner) (change, error) {
+			var program store.Program
+			listed := change{add: func(changes *store.Changes) {
+				changes.Programs = append(changes.Programs, program)
+			}}
+			return listed, scanProgram(row, &program, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+		{`
+			SELECT ` + routineColumns + `, ` + changeColumns + `
+			FROM routines
+			WHERE program_id IN (SELECT id FROM programs WHERE user_id = $1)
+		` + changedAfter, func(row scanner) (change, error) {
+			var routine store.Routine
+			listed := change{add: func(changes *store.Changes) {
+				changes.Routines = append(changes.Routines, routine)
+			}}
+			return listed, scanRoutine(row, &routine, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+		{`
+			SELECT ` + routineExerciseColumns + `, ` + changeColumns + `
+			FROM routine_exercises
+			WHERE routine_id IN (
+				SELECT r.id
+				FROM routines r
+				JOIN programs p ON p.id = r.program_id
+				WHERE p.user_id = $1
+			)
+		` + changedAfter, func(row scanner) (change, error) {
+			var routineExercise store.RoutineExercise
+			listed := change{add: func(changes *store.Changes) {
+				changes.RoutineExercises = append(changes.RoutineExercises, routineExercise)
+			}}
+			return listed, scanRoutineExercise(row, &routineExercise, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+		{`
+			SELECT ` + workoutColumns + `, ` + changeColumns + `
+			FROM workouts
+			WHERE user_id = $1
+		` + changedAfter, func(row scanner) (change, error) {
+			var workout store.Workout
+			listed := change{add: func(changes *store.Changes) {
+				changes.Workouts = append(changes.Workouts, workout)
+			}}
+			return listed, scanWorkout(row, &workout, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+		{`
+			SELECT ` + workoutSetColumns + `, ` + changeColumns + `
+			FROM workout_sets
+			WHERE workout_id IN (SELECT id FROM workouts WHERE user_id = $1)
+		` + changedAfter, func(row scanner) (change, error) {
+			var set store.WorkoutSet
+			listed := change{add: func(changes *store.Changes) {
+				changes.WorkoutSets = append(changes.WorkoutSets, set)
+			}}
+			return listed, scanWorkoutSet(row, &set, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+		{`
+			SELECT entity, entity_id, deleted_at, ` + changeColumns + `
+			FROM sync_tombstones
+			WHERE user_id = $1
+		` + changedAfter, func(row scanner) (change, error) {
+			var tombstone store.Tombstone
+			listed := change{add: func(changes *store.Changes) {
+				changes.Tombstones = append(changes.Tombstones, tombstone)
+			}}
+			return listed, row.Scan(&tombstone.Entity, &tombstone.ID, &tombstone.DeletedAt, &listed.cursor.Xact, &listed.cursor.Seq)
+		}},
+	}
+
+	// The tables are read from a single snapshot, so that a row moving
+	// past the cursor between two queries cannot be missed
+	listed := []change{}
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return store.Changes{}, err
+	}
+	defer tx.Rollback()
+	for _, query := range queries {
+		rows, err := listChanges(ctx, tx, query.query, args, query.scan)
+		if err != nil {
+			return store.Changes{}, err
+		}
+		listed = append(listed, rows...)
+	}
+
+	sort.Slice(listed, func(i, j int) bool { return listed[i].cursor.Before(listed[j].cursor) })
+	if len(listed) > limit {
+		listed = listed[:limit]
+		changes.HasMore = true
+	}
+	for _, row := range listed {
+		row.add(&changes)
+		changes.Cursor = row.cursor
+	}
+	return changes, nil
+}
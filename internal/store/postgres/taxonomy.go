@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// listTaxonomy runs a query listing taxonomy rows and scans each of
+// them with scan.
+func listTaxonomy[T any](ctx context.Context, s *Store, query string, scan func(scanner, *T) error) ([]T, error) {
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := []T{}
+	for rows.Next() {
+		var value T
+		if err := scan(rows, &value); err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, rows.Err()
+}
+
+func (s *Store) ListMuscleGroups(ctx context.Context) ([]store.MuscleGroup, error) {
+	return listTaxonomy(ctx, s, `
+		SELECT slug, name FROM muscle_groups ORDER BY position
+	`, func(row scanner, group *store.MuscleGroup) error {
+		return row.Scan(&group.Slug, &group.Name)
+	})
+}
+
+func (s *Store) ListMuscles(ctx context.Context) ([]store.Muscle, error) {
+	return listTaxonomy(ctx, s, `
+		SELECT m.slug, m.name, m.muscle_group
+		FROM muscles m
+		JOIN muscle_groups g ON g.slug = m.muscle_group
+		ORDER BY g.position, m.name
+	`, func(row scanner, muscle *store.Muscle) error {
+		return row.Scan(&muscle.Slug, &muscle.Name, &muscle.MuscleGroup)
+	})
+}
+
+func (s *Store) ListEquipment(ctx context.Context) ([]store.Equipment, error) {
+	return listTaxonomy(ctx, s, `
+		SELECT slug, name FROM equipment ORDER BY name
+	`, func(row scanner, equipment *store.Equipment) error {
+		return row.Scan(&equipment.Slug, &equipment.Name)
+	})
+}
+
+func (s *Store) ListExerciseTypes(ctx context.Context) ([]store.ExerciseType, error) {
+	return listTaxonomy(ctx, s, `
+		SELECT slug, name, description FROM exercise_types ORDER BY name
+	`, func(row scanner, exerciseType *store.ExerciseType) error {
+		return row.Scan(&exerciseType.Slug, &exerciseType.Name, &exerciseType.Description)
+	})
+}
@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const trackColumns = `
+	id, workout_id, workout_set_id, format, name,
+	distance, duration, elevation_gain, created_at
+`
+
+func scanTrack(row scanner, track *store.Track) error {
+	return row.Scan(
+		&track.ID,
+		&track.WorkoutID,
+		&track.WorkoutSetID,
+		&track.Format,
+		&track.Name,
+		&track.Distance,
+		&track.Duration,
+		&track.ElevationGain,
+		&track.CreatedAt,
+	)
+}
+
+func (s *Store) CreateTrack(ctx context.Context, track *store.Track, set *store.WorkoutSet) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		set.WorkoutID = track.WorkoutID
+		var err error
+		if set.ID > 0 {
+			err = updateWorkoutSet(ctx, tx, set)
+		} else {
+			err = insertWorkoutSet(ctx, tx, set)
+		}
+		if err != nil {
+			return err
+		}
+
+		// The unique set of a track refuses a second one
+		track.WorkoutSetID = set.ID
+		err = tx.QueryRowContext(ctx, `
+			INSERT INTO workout_tracks (workout_id, workout_set_id, format, name, distance, duration, elevation_gain)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING id, created_at
+		`,
+			track.WorkoutID,
+			track.WorkoutSetID,
+			track.Format,
+			track.Name,
+			track.Distance,
+			track.Duration,
+			track.ElevationGain,
+		).Scan(&track.ID, &track.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		// Tracks run to thousands of points, which are copied in bulk
+		stmt, err := tx.PrepareContext(ctx, pq.CopyIn(
+			"track_points",
+			"track_id", "seq", "latitude", "longitude", "elevation", "recorded_at", "heart_rate",
+		))
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i, point := range track.Points {
+			_, err := stmt.ExecContext(ctx,
+				track.ID,
+				i,
+				point.Latitude,
+				point.Longitude,
+				point.Elevation,
+				point.Time,
+				point.HeartRate,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = stmt.ExecContext(ctx)
+		return err
+	})
+}
+
+func (s *Store) GetTrack(ctx context.Context, workoutID, trackID int) (store.Track, error) {
+	var track store.Track
+	row := s.db.QueryRowContext(ctx,
+		"SELECT "+trackColumns+" FROM workout_tracks WHERE id = $1 AND workout_id = $2",
+		trackID,
+		workoutID,
+	)
+	if err := mapError(scanTrack(row, &track)); err != nil {
+		return track, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT latitude, longitude, elevation, recorded_at, heart_rate
+		FROM track_points
+		WHERE track_id = $1
+		ORDER BY seq
+	`, trackID)
+	if err != nil {
+		return track, err
+	}
+	defer rows.Close()
+
+	track.Points = []store.TrackPoint{}
+	for rows.Next() {
+		var point store.TrackPoint
+		err := rows.Scan(
+			&point.Latitude,
+			&point.Longitude,
+			&point.Elevation,
+			&point.Time,
+			&point.HeartRate,
+		)
+		if err != nil {
+			return track, err
+		}
+		track.Points = append(track.Points, point)
+	}
+	return track, rows.Err()
+}
+
+func (s *Store) ListTracks(ctx context.Context, workoutID int) ([]store.Track, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+trackColumns+`
+		FROM workout_tracks
+		WHERE workout_id = $1
+		ORDER BY id
+	`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tracks := []store.Track{}
+	for rows.Next() {
+		var track store.Track
+		if err := scanTrack(rows, &track); err != nil {
+			return nil, err
+		}
+		tracks = append(tracks, track)
+	}
+	return tracks, rows.Err()
+}
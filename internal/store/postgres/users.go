@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+const userColumns = "id, email, password_hash, is_admin, created_at, updated_at"
+
+func scanUser(row scanner, user *store.User) error {
+	return row.Scan(
+		&user.ID,
+		&user.Email,
+		&user.PasswordHash,
+		&user.IsAdmin,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+}
+
+func (s *Store) CreateUser(ctx context.Context, user *store.User) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO users (email, password_hash)
+		VALUES ($1, $2)
+		RETURNING id, created_at, updated_at
+	`, user.Email, user.PasswordHash).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	return mapError(err)
+}
+
+func (s *Store) GetUser(ctx context.Context, id int) (store.User, error) {
+	var user store.User
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1", id)
+	return user, mapError(scanUser(row, &user))
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (store.User, error) {
+	var user store.User
+	row := s.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	return user, mapError(scanUser(row, &user))
+}
+
+func (s *Store) CreateRefreshToken(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (user_id, token_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenID, expiresAt)
+	return mapError(err)
+}
+
+func (s *Store) ConsumeRefreshToken(ctx context.Context, userID int, tokenID string) error {
+	// Checking that the token is live and revoking it happens in one
+	// statement, so concurrent refreshes cannot both succeed.
+	return expectRow(s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE token_id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`, tokenID, userID))
+}
+
+func (s *Store) RevokeRefreshToken(ctx context.Context, userID int, tokenID string) error {
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE token_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, tokenID, userID)
+	return mapError(err)
+}
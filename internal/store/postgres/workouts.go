@@ -0,0 +1,528 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Workouts that did not follow a routine have a NULL routine_id, which
+// the models represent as 0.
+const workoutColumns = `
+	id, user_id, COALESCE(routine_id, 0), performed_at,
+	status, started_at, finished_at,
+	created_at, updated_at, deleted_at
+`
+
+func scanWorkout(row scanner, workout *store.Workout, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&workout.ID,
+		&workout.UserID,
+		&workout.RoutineID,
+		&workout.PerformedAt,
+		&workout.Status,
+		&workout.StartedAt,
+		&workout.FinishedAt,
+		&workout.CreatedAt,
+		&workout.UpdatedAt,
+		&workout.DeletedAt,
+	}, extra...)...)
+}
+
+func insertWorkout(ctx context.Context, q queryer, workout *store.Workout) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO workouts (user_id, routine_id, performed_at, status, started_at, finished_at)
+		VALUES ($1, NULLIF($2, 0), $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`,
+		workout.UserID,
+		workout.RoutineID,
+		workout.PerformedAt,
+		workout.Status,
+		workout.StartedAt,
+		workout.FinishedAt,
+	).Scan(&workout.ID, &workout.CreatedAt, &workout.UpdatedAt)
+}
+
+func (s *Store) CreateWorkout(ctx context.Context, workout *store.Workout, sets []store.WorkoutSet) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		if err := insertWorkout(ctx, tx, workout); err != nil {
+			return err
+		}
+
+		for i := range sets {
+			sets[i].WorkoutID = workout.ID
+			if err := insertWorkoutSet(ctx, tx, &sets[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) ImportWorkouts(ctx context.Context, exercises []*store.Exercise, workouts []store.ImportedWorkout) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, exercise := range exercises {
+			if err := insertExercise(ctx, tx, exercise); err != nil {
+				return err
+			}
+		}
+
+		for i := range workouts {
+			workout := &workouts[i]
+			if err := insertWorkout(ctx, tx, &workout.Workout); err != nil {
+				return err
+			}
+
+			for j := range workout.Sets {
+				set := &workout.Sets[j]
+				if set.NewExercise != nil {
+					set.ExerciseID = set.NewExercise.ID
+				}
+				set.WorkoutID = workout.ID
+				if err := insertWorkoutSet(ctx, tx, &set.WorkoutSet); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func (s *Store) GetWorkout(ctx context.Context, id int) (store.Workout, error) {
+	var workout store.Workout
+	row := s.db.QueryRowContext(ctx, "SELECT "+workoutColumns+" FROM workouts WHERE id = $1", id)
+	return workout, mapError(scanWorkout(row, &workout))
+}
+
+// workoutSorts are the fields workouts may be sorted by.
+var workoutSorts = sortable{
+	fields: map[string]string{"performed_at": "w.performed_at", "status": "w.status", "created_at": "w.created_at"},
+	id:     "w.id",
+}
+
+func (s *Store) ListWorkouts(ctx context.Context, filter store.WorkoutFilter) ([]store.WorkoutWithRoutineName, store.PageInfo, error) {
+	var conds conditions
+	conds.where("w.user_id = " + conds.arg(filter.UserID))
+	conds.where("w.deleted_at IS NULL")
+	if filter.Status != "" {
+		conds.where("w.status = " + conds.arg(filter.Status))
+	}
+	if !filter.From.IsZero() {
+		conds.where("w.performed_at >= " + conds.arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds.where("w.performed_at < " + conds.arg(filter.To))
+	}
+
+	keys := filter.Order(store.DefaultWorkoutSort)
+	columns, clauses, total, err := workoutSorts.page(ctx, s, "workouts w", &conds, filter.Page, keys)
+	if err != nil {
+		return nil, store.PageInfo{}, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT w.id, w.user_id, COALESCE(w.routine_id, 0), w.performed_at,
+		w.status, w.started_at, w.finished_at, w.created_at, w.updated_at, w.deleted_at,
+		r.name as routine_name%s
+		FROM workouts w
+		LEFT JOIN routines r ON w.routine_id = r.id
+		%s
+		%s
+	`, columns, &conds, clauses)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+	defer rows.Close()
+
+	workouts := []store.WorkoutWithRoutineName{}
+	cursors := []store.Cursor{}
+	for rows.Next() {
+		var workout store.WorkoutWithRoutineName
+		var routineName sql.NullString
+		cursor, values := newCursor(keys)
+		if err := scanWorkout(rows, &workout.Workout, append([]interface{}{&routineName}, values...)...); err != nil {
+			return nil, store.PageInfo{}, err
+		}
+		workout.RoutineName = routineName.String
+		cursor.ID = workout.ID
+		workouts = append(workouts, workout)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, store.PageInfo{}, cursorError(err, filter.Page)
+	}
+
+	workouts, info := store.FinishPage(workouts, cursors, filter.Page)
+	info.Total = total
+	return workouts, info, nil
+}
+
+func (s *Store) UpdateWorkout(ctx context.Context, workout *store.Workout) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE workouts
+		SET routine_id = NULLIF($1, 0), performed_at = $2, updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+workoutColumns,
+		workout.RoutineID,
+		workout.PerformedAt,
+		workout.ID,
+	)
+	return mapError(scanWorkout(row, workout))
+}
+
+func (s *Store) SetWorkoutStatus(ctx context.Context, workout *store.Workout, from string) error {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE workouts
+		SET status = $1, started_at = $2, finished_at = $3, performed_at = $4, updated_at = NOW()
+		WHERE id = $5 AND status = $6
+		RETURNING `+workoutColumns,
+		workout.Status,
+		workout.StartedAt,
+		workout.FinishedAt,
+		workout.PerformedAt,
+		workout.ID,
+		from,
+	)
+	err := mapError(scanWorkout(row, workout))
+	if errors.Is(err, store.ErrNotFound) {
+		return store.ErrPrecondition
+	}
+	return err
+}
+
+func (s *Store) DeleteWorkout(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		dropped, err := deleteRecords(ctx, tx, "workout_set_id IN (SELECT id FROM workout_sets WHERE workout_id = $1)", id)
+		if err != nil {
+			return err
+		}
+
+		// Delete all sets of this workout
+		if _, err := tx.ExecContext(ctx, "DELETE FROM workout_sets WHERE workout_id = $1", id); err != nil {
+			return err
+		}
+
+		// Delete the workout
+		if err := expectRow(tx.ExecContext(ctx, "DELETE FROM workouts WHERE id = $1", id)); err != nil {
+			return err
+		}
+
+		// Hand the records of the sets to the best remaining sets
+		_, err = restoreRecords(ctx, tx, dropped)
+		return err
+	})
+}
+
+func (s *Store) WorkoutOwner(ctx context.Context, id int) (store.Owner, error) {
+	var owner store.Owner
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id, deleted_at IS NOT NULL
+		FROM workouts
+		WHERE id = $1
+	`, id).Scan(&owner.UserID, &owner.Deleted)
+	return owner, mapError(err)
+}
+
+func (s *Store) SetWorkoutDeleted(ctx context.Context, id int, deleted bool) error {
+	return s.setDeleted(ctx, "workouts", id, deleted)
+}
+
+// -------------------- Workout sets --------------------
+
+const workoutSetColumns = `
+	id, workout_id, exercise_id, set_index, set_type, completed,
+	reps, weight, rpe, duration, distance,
+	routine_exercise_id, group_id, round,
+	created_at, updated_at
+`
+
+func scanWorkoutSet(row scanner, set *store.WorkoutSet, extra ...interface{}) error {
+	return row.Scan(append([]interface{}{
+		&set.ID,
+		&set.WorkoutID,
+		&set.ExerciseID,
+		&set.SetIndex,
+		&set.SetType,
+		&set.Completed,
+		&set.Reps,
+		&set.Weight,
+		&set.RPE,
+		&set.Duration,
+		&set.Distance,
+		&set.RoutineExerciseID,
+		&set.GroupID,
+		&set.Round,
+		&set.CreatedAt,
+		&set.UpdatedAt,
+	}, extra...)...)
+}
+
+func (s *Store) AddWorkoutSet(ctx context.Context, set *store.WorkoutSet) error {
+	return mapError(insertWorkoutSet(ctx, s.db, set))
+}
+
+// insertWorkoutSet inserts a set, numbering it after the last set of
+// its exercise in the workout unless SetIndex is given.
+func insertWorkoutSet(ctx context.Context, q queryer, set *store.WorkoutSet) error {
+	return q.QueryRowContext(ctx, `
+		INSERT INTO workout_sets (
+			workout_id, exercise_id, set_index, set_type, completed,
+			reps, weight, rpe, duration, distance,
+			routine_exercise_id, group_id, round
+		)
+		VALUES (
+			$1, $2,
+			COALESCE(NULLIF($3, 0), (
+				SELECT COALESCE(MAX(set_index), 0) + 1
+				FROM workout_sets
+				WHERE workout_id = $1 AND exercise_id = $2
+			)),
+			$4, $5, $6, $7, $8, $9, $10, $11, $12, $13
+		)
+		RETURNING id, set_index, created_at, updated_at
+	`,
+		set.WorkoutID,
+		set.ExerciseID,
+		set.SetIndex,
+		set.SetType,
+		set.Completed,
+		set.Reps,
+		set.Weight,
+		set.RPE,
+		set.Duration,
+		set.Distance,
+		set.RoutineExerciseID,
+		set.GroupID,
+		set.Round,
+	).Scan(&set.ID, &set.SetIndex, &set.CreatedAt, &set.UpdatedAt)
+}
+
+func (s *Store) GetWorkoutSet(ctx context.Context, workoutID, setID int) (store.WorkoutSetWithDetails, error) {
+	var set store.WorkoutSetWithDetails
+	row := s.db.QueryRowContext(ctx, `
+		SELECT
+			ws.id, ws.workout_id, ws.exercise_id,
+			ws.set_index, ws.set_type, ws.completed,
+			ws.reps, ws.weight, ws.rpe, ws.duration, ws.distance,
+			ws.routine_exercise_id, ws.group_id, ws.round,
+			ws.created_at, ws.updated_at,
+			e.name as exercise_name, e.exercise_type
+		FROM workout_sets ws
+		JOIN exercises e ON ws.exercise_id = e.id
+		WHERE ws.id = $1 AND ws.workout_id = $2
+	`, setID, workoutID)
+	return set, mapError(scanWorkoutSet(row, &set.WorkoutSet, &set.ExerciseName, &set.ExerciseType))
+}
+
+func (s *Store) ListWorkoutSets(ctx context.Context, workoutID int) ([]store.WorkoutSetWithDetails, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT
+			ws.id, ws.workout_id, ws.exercise_id,
+			ws.set_index, ws.set_type, ws.completed,
+			ws.reps, ws.weight, ws.rpe, ws.duration, ws.distance,
+			ws.routine_exercise_id, ws.group_id, ws.round,
+			ws.created_at, ws.updated_at,
+			e.name as exercise_name, e.exercise_type
+		FROM workout_sets ws
+		JOIN exercises e ON ws.exercise_id = e.id
+		WHERE ws.workout_id = $1
+		ORDER BY ws.id
+	`, workoutID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []store.WorkoutSetWithDetails{}
+	for rows.Next() {
+		var set store.WorkoutSetWithDetails
+		if err := scanWorkoutSet(rows, &set.WorkoutSet, &set.ExerciseName, &set.ExerciseType); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+func (s *Store) UpdateWorkoutSet(ctx context.Context, set *store.WorkoutSet) error {
+	return mapError(updateWorkoutSet(ctx, s.db, set))
+}
+
+func updateWorkoutSet(ctx context.Context, q queryer, set *store.WorkoutSet) error {
+	row := q.QueryRowContext(ctx, `
+		UPDATE workout_sets
+		SET
+			set_type = $1,
+			completed = $2,
+			reps = $3,
+			weight = $4,
+			rpe = $5,
+			duration = $6,
+			distance = $7,
+			updated_at = NOW()
+		WHERE id = $8 AND workout_id = $9
+		RETURNING `+workoutSetColumns,
+		set.SetType,
+		set.Completed,
+		set.Reps,
+		set.Weight,
+		set.RPE,
+		set.Duration,
+		set.Distance,
+		set.ID,
+		set.WorkoutID,
+	)
+	return scanWorkoutSet(row, set)
+}
+
+func (s *Store) DeleteWorkoutSet(ctx context.Context, workoutID, setID int) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		dropped, err := deleteRecords(ctx, tx,
+			"workout_set_id = (SELECT id FROM workout_sets WHERE id = $1 AND workout_id = $2)",
+			setID,
+			workoutID,
+		)
+		if err != nil {
+			return err
+		}
+
+		err = expectRow(tx.ExecContext(ctx,
+			"DELETE FROM workout_sets WHERE id = $1 AND workout_id = $2",
+			setID,
+			workoutID,
+		))
+		if err != nil {
+			return err
+		}
+
+		// Hand the records of the set to the best remaining sets
+		_, err = restoreRecords(ctx, tx, dropped)
+		return err
+	})
+}
+
+func (s *Store) ListPerformedSets(ctx context.Context, filter store.PerformedSetFilter) ([]store.PerformedSet, error) {
+	var conds conditions
+	conds.where("w.user_id = " + conds.arg(filter.UserID))
+	conds.where("w.deleted_at IS NULL")
+	conds.where("ws.completed = true")
+	if filter.RoutineID != 0 {
+		conds.where("w.routine_id = " + conds.arg(filter.RoutineID))
+	}
+	if filter.ExerciseID != 0 {
+		conds.where("ws.exercise_id = " + conds.arg(filter.ExerciseID))
+	}
+	if !filter.From.IsZero() {
+		conds.where("w.performed_at >= " + conds.arg(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conds.where("w.performed_at < " + conds.arg(filter.To))
+	}
+
+	query := fmt.Sprintf(`
+		SELECT
+			ws.id, ws.workout_id, ws.exercise_id,
+			ws.set_index, ws.set_type, ws.completed,
+			ws.reps, ws.weight, ws.rpe, ws.duration, ws.distance,
+			ws.routine_exercise_id, ws.group_id, ws.round,
+			ws.created_at, ws.updated_at,
+			e.name as exercise_name, e.exercise_type,
+			w.performed_at, e.primary_muscles, e.secondary_muscles
+		FROM workout_sets ws
+		JOIN workouts w ON ws.workout_id = w.id
+		JOIN exercises e ON ws.exercise_id = e.id
+		%s
+		ORDER BY w.performed_at, ws.id
+	`, &conds)
+
+	rows, err := s.db.QueryContext(ctx, query, conds.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []store.PerformedSet{}
+	for rows.Next() {
+		var set store.PerformedSet
+		if err := scanWorkoutSet(
+			rows, &set.WorkoutSet,
+			&set.ExerciseName,
+			&set.ExerciseType,
+			&set.PerformedAt,
+			pq.Array(&set.PrimaryMuscles),
+			pq.Array(&set.SecondaryMuscles),
+		); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+func (s *Store) WalkWorkoutHistory(ctx context.Context, userID int, fn func(store.HistoryRow) error) error {
+	// Workouts without sets come out of the join with NULL sets, which
+	// are read as zero values and told apart by their ID
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT w.id, w.user_id, COALESCE(w.routine_id, 0), w.performed_at,
+		w.status, w.started_at, w.finished_at, w.created_at, w.updated_at, w.deleted_at,
+		COALESCE(r.name, ''),
+		COALESCE(ws.id, 0), COALESCE(ws.exercise_id, 0),
+		COALESCE(ws.set_index, 0), COALESCE(ws.set_type, ''), COALESCE(ws.completed, false),
+		COALESCE(ws.reps, 0), COALESCE(ws.weight, 0), COALESCE(ws.rpe, 0),
+		COALESCE(ws.duration, 0), COALESCE(ws.distance, 0),
+		COALESCE(ws.created_at, w.created_at), COALESCE(ws.updated_at, w.updated_at),
+		COALESCE(e.name, ''), COALESCE(e.exercise_type, '')
+		FROM workouts w
+		LEFT JOIN routines r ON w.routine_id = r.id
+		LEFT JOIN workout_sets ws ON ws.workout_id = w.id
+		LEFT JOIN exercises e ON ws.exercise_id = e.id
+		WHERE w.user_id = $1 AND w.deleted_at IS NULL
+		ORDER BY w.performed_at, w.id, ws.id
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row store.HistoryRow
+		var set store.WorkoutSetWithDetails
+		err := scanWorkout(
+			rows, &row.Workout.Workout,
+			&row.Workout.RoutineName,
+			&set.ID,
+			&set.ExerciseID,
+			&set.SetIndex,
+			&set.SetType,
+			&set.Completed,
+			&set.Reps,
+			&set.Weight,
+			&set.RPE,
+			&set.Duration,
+			&set.Distance,
+			&set.CreatedAt,
+			&set.UpdatedAt,
+			&set.ExerciseName,
+			&set.ExerciseType,
+		)
+		if err != nil {
+			return err
+		}
+
+		if set.ID != 0 {
+			set.WorkoutID = row.Workout.ID
+			row.Set = &set
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
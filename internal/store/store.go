@@ -0,0 +1,459 @@
+// Package store defines the persistence interfaces used by the HTTP
+// handlers, along with the models they exchange.
+//
+// Implementations live in subpackages. Every method takes a
+// context.Context and reports missing rows as ErrNotFound, so handlers
+// never need to know which backend they are talking to.
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when the requested row does not exist.
+	ErrNotFound = errors.New("store: not found")
+	// ErrConflict is returned when a write would violate a uniqueness
+	// constraint.
+	ErrConflict = errors.New("store: conflict")
+	// ErrPrecondition is returned when a write is rejected because the
+	// row is no longer in the state the caller expected.
+	ErrPrecondition = errors.New("store: precondition failed")
+)
+
+// Page selects a window of a list, either by skipping Offset items or
+// by continuing from a Cursor.
+type Page struct {
+	Limit  int
+	Offset int
+	// Sort, if set, orders the list by fields it may be sorted by,
+	// instead of its default order. IDs break ties.
+	Sort []SortKey
+	// Cursor, if set, lists the items following it in the order of the
+	// list, or preceding it, and Offset is ignored.
+	Cursor *Cursor
+	// Count asks for the total number of items matching the filter.
+	Count bool
+}
+
+// SortKey orders a list by one of its fields.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// Cursor marks the item of a list that a page continues from, by its
+// sort fields and ID. Cursors are only valid for the order of the list
+// that they were made for.
+type Cursor struct {
+	Values []string
+	ID     int
+	// Backward lists the items preceding the cursor.
+	Backward bool
+}
+
+// PageInfo describes a page of a list.
+type PageInfo struct {
+	// Total is the number of items matching the filter if the page asked
+	// for it, and -1 otherwise.
+	Total int
+	// Next continues the list after the page and Prev before it. They
+	// are nil at the ends of the list.
+	Next *Cursor
+	Prev *Cursor
+}
+
+// ExerciseFilter selects exercises for ListExercises.
+type ExerciseFilter struct {
+	Page
+	// VisibleTo lists the catalogue along with the custom exercises of
+	// that user.
+	VisibleTo int
+	// Type, if set, only lists exercises of that type.
+	Type string
+	// Name, if set, only lists exercises of that name, ignoring case.
+	Name string
+}
+
+// ExerciseSearch selects exercises for SearchExercises. Equipment and
+// muscles match exercises that have any of the values given, or all of
+// them with MatchAll.
+type ExerciseSearch struct {
+	Page
+	// VisibleTo searches the catalogue along with the custom exercises
+	// of that user.
+	VisibleTo int
+	// Query, if set, only lists exercises whose name or aliases contain
+	// its words or, to forgive typos, look like it.
+	Query string
+	// Type, if set, only lists exercises of that type.
+	Type string
+	// Equipment, if set, only lists exercises using that equipment.
+	Equipment []string
+	// Muscles, if set, only lists exercises working those muscles,
+	// either as primary or as secondary muscles.
+	Muscles  []string
+	MatchAll bool
+}
+
+// ProgramFilter selects programs for ListPrograms.
+type ProgramFilter struct {
+	Page
+	// VisibleTo lists the programs owned by that user along with all
+	// public programs.
+	VisibleTo int
+}
+
+// RoutineFilter selects routines for ListRoutines.
+type RoutineFilter struct {
+	Page
+	// VisibleTo lists the routines of programs owned by that user
+	// along with those of public programs.
+	VisibleTo int
+	// ProgramID, if set, only lists routines of that program.
+	ProgramID int
+}
+
+// WorkoutFilter selects workouts for ListWorkouts.
+type WorkoutFilter struct {
+	Page
+	// UserID lists the workouts of that user.
+	UserID int
+	// Status, if set, only lists workouts in that status.
+	Status string
+	// From and To, if set, only list workouts performed in [From, To).
+	From time.Time
+	To   time.Time
+}
+
+// PerformedSetFilter selects sets for ListPerformedSets.
+type PerformedSetFilter struct {
+	// UserID lists the sets of that user.
+	UserID int
+	// RoutineID, if set, only lists sets of workouts that followed
+	// that routine.
+	RoutineID int
+	// ExerciseID, if set, only lists sets of that exercise.
+	ExerciseID int
+	// From and To, if set, only list sets of workouts performed in
+	// [From, To).
+	From time.Time
+	To   time.Time
+}
+
+// RecordFilter selects personal records for ListPersonalRecords.
+type RecordFilter struct {
+	// UserID lists the records of that user.
+	UserID int
+	// ExerciseID, if set, only lists records on that exercise.
+	ExerciseID int
+	// History lists every record ever earned rather than only the
+	// current ones.
+	History bool
+}
+
+// Store is implemented by backends that provide every store.
+type Store interface {
+	UserStore
+	ExerciseStore
+	ProgramStore
+	RoutineStore
+	WorkoutStore
+	RecordStore
+	EnrollmentStore
+	TrackStore
+	TaxonomyStore
+	IdempotencyStore
+	SyncStore
+}
+
+// UserStore persists users and their refresh tokens.
+type UserStore interface {
+	// CreateUser returns ErrConflict if the email is already taken.
+	CreateUser(ctx context.Context, user *User) error
+	GetUser(ctx context.Context, id int) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+
+	CreateRefreshToken(ctx context.Context, userID int, tokenID string, expiresAt time.Time) error
+	// ConsumeRefreshToken revokes a live refresh token. It returns
+	// ErrNotFound if the token is unknown, expired or already revoked,
+	// so that each refresh token can only be used once.
+	ConsumeRefreshToken(ctx context.Context, userID int, tokenID string) error
+	RevokeRefreshToken(ctx context.Context, userID int, tokenID string) error
+}
+
+// ExerciseStore persists exercises.
+type ExerciseStore interface {
+	CreateExercise(ctx context.Context, exercise *Exercise) error
+	GetExercise(ctx context.Context, id int) (Exercise, error)
+	// ListExercises returns a page of exercises, by name unless sorted
+	// by one of ExerciseSortFields.
+	ListExercises(ctx context.Context, filter ExerciseFilter) ([]Exercise, PageInfo, error)
+	// SearchExercises returns a page of exercises, best matches first,
+	// the total number of exercises matching the search and how many of
+	// them use each equipment and work each muscle. Searches are only
+	// paged by offset.
+	SearchExercises(ctx context.Context, search ExerciseSearch) ([]Exercise, int, ExerciseFacets, error)
+	// UpdateExercise updates everything but the owner of an exercise.
+	UpdateExercise(ctx context.Context, exercise *Exercise) error
+	// DeleteExercise deletes an exercise for good. It returns
+	// ErrConflict if routines or workout sets still reference it.
+	DeleteExercise(ctx context.Context, id int) error
+	// SetExerciseDeleted soft deletes or restores an exercise. It
+	// returns ErrPrecondition if the exercise is already in that state.
+	SetExerciseDeleted(ctx context.Context, id int, deleted bool) error
+	// ExerciseReferences counts the rows that keep an exercise from
+	// being deleted for good.
+	ExerciseReferences(ctx context.Context, id int) (ExerciseReferences, error)
+	// ExerciseOwner resolves the owner of a custom exercise. Catalogue
+	// exercises are public and owned by no user.
+	ExerciseOwner(ctx context.Context, id int) (Owner, error)
+	// MergeExercise points the routine exercises, workout sets and
+	// personal records of an exercise at another one, then deletes it.
+	// Merged sets are numbered after the sets of the other exercise in
+	// the same workout, and merged records that do not beat an earlier
+	// record are dropped.
+	MergeExercise(ctx context.Context, fromID, intoID int) error
+}
+
+// ProgramStore persists programs.
+type ProgramStore interface {
+	CreateProgram(ctx context.Context, program *Program) error
+	// ForkProgram creates the program as a copy of the program
+	// identified by its ForkedFromProgramID, along with its blocks,
+	// routines, their groups and exercises, and counts the fork on the
+	// original.
+	// The fork keeps its UserID, Name and IsPublic and takes the rest
+	// from the original. Custom exercises of other users are copied into
+	// the catalogue of the fork's user, and the copied routines use the
+	// copies. It returns ErrNotFound if the original does not exist.
+	ForkProgram(ctx context.Context, fork *Program) error
+	// CreateProgramTree creates a program along with its blocks,
+	// routines, their groups and exercises in a single transaction,
+	// filling in the IDs of everything it creates. Exercises are
+	// positioned in the order of the tree.
+	CreateProgramTree(ctx context.Context, tree *ProgramTree) error
+	GetProgram(ctx context.Context, id int) (Program, error)
+	// ListPrograms returns a page of programs, most recent first unless
+	// sorted by one of ProgramSortFields.
+	ListPrograms(ctx context.Context, filter ProgramFilter) ([]Program, PageInfo, error)
+	// UpdateProgram updates the name, visibility and cycle length of a
+	// program.
+	UpdateProgram(ctx context.Context, program *Program) error
+	// DeleteProgram deletes a program along with its routines and
+	// their exercises, its blocks and its enrollments.
+	DeleteProgram(ctx context.Context, id int) error
+	// SetProgramDeleted soft deletes or restores a program, which hides
+	// or shows its routines along with it. It returns ErrPrecondition if
+	// the program is already in that state.
+	SetProgramDeleted(ctx context.Context, id int, deleted bool) error
+	ProgramOwner(ctx context.Context, id int) (Owner, error)
+
+	// ListProgramBlocks returns the blocks of a program by position.
+	ListProgramBlocks(ctx context.Context, programID int) ([]ProgramBlock, error)
+	// SetProgramBlocks replaces the blocks of a program, numbering
+	// their positions in order.
+	SetProgramBlocks(ctx context.Context, programID int, blocks []ProgramBlock) error
+}
+
+// EnrollmentStore persists program enrollments.
+type EnrollmentStore interface {
+	// CreateEnrollment returns ErrConflict if the user already follows
+	// the program.
+	CreateEnrollment(ctx context.Context, enrollment *Enrollment) error
+	GetEnrollment(ctx context.Context, id int) (Enrollment, error)
+	// ListEnrollments returns the enrollments of a user, most recent
+	// first, optionally only those that have not ended.
+	ListEnrollments(ctx context.Context, userID int, activeOnly bool) ([]Enrollment, error)
+	// EndEnrollment ends an enrollment. It returns ErrPrecondition if
+	// it has already ended.
+	EndEnrollment(ctx context.Context, enrollment *Enrollment) error
+}
+
+// RoutineStore persists routines and the exercises they contain.
+type RoutineStore interface {
+	CreateRoutine(ctx context.Context, routine *Routine) error
+	GetRoutine(ctx context.Context, id int) (Routine, error)
+	// ListRoutines returns a page of routines, by day number and name
+	// unless sorted by one of RoutineSortFields.
+	ListRoutines(ctx context.Context, filter RoutineFilter) ([]Routine, PageInfo, error)
+	// UpdateRoutine updates the name and day number of a routine.
+	UpdateRoutine(ctx context.Context, routine *Routine) error
+	// DeleteRoutine deletes a routine along with its exercises.
+	DeleteRoutine(ctx context.Context, id int) error
+	// SetRoutineDeleted soft deletes or restores a routine. It returns
+	// ErrPrecondition if the routine is already in that state.
+	SetRoutineDeleted(ctx context.Context, id int, deleted bool) error
+	// RoutineOwner resolves the owner of a routine through its
+	// program. The routine counts as deleted if its program is.
+	RoutineOwner(ctx context.Context, id int) (Owner, error)
+
+	// AddRoutineExercise inserts the exercise at its Position, moving
+	// the exercises from there down, or appends it when Position is 0
+	// or past the end. It does not join a group.
+	AddRoutineExercise(ctx context.Context, routineExercise *RoutineExercise) error
+	// ListRoutineExercises returns the exercises of a routine by
+	// position.
+	ListRoutineExercises(ctx context.Context, routineID int) ([]RoutineExerciseWithDetails, error)
+	// UpdateRoutineExercise updates the recommendations and
+	// progression of the routine exercise identified by ID and
+	// RoutineID.
+	UpdateRoutineExercise(ctx context.Context, routineExercise *RoutineExercise) error
+	// RemoveRoutineExercise removes a routine exercise and closes the
+	// gap in the positions. Its group is deleted once left with fewer
+	// than two exercises.
+	RemoveRoutineExercise(ctx context.Context, routineID, id int) error
+	// ReorderRoutineExercises numbers the exercises of a routine in the
+	// order of the IDs. It returns ErrPrecondition unless the IDs list
+	// every exercise of the routine exactly once.
+	ReorderRoutineExercises(ctx context.Context, routineID int, ids []int) error
+
+	// CreateRoutineGroup creates a group of the routine exercises in
+	// its RoutineExerciseIDs, taking them out of their former groups,
+	// which are deleted once left with fewer than two exercises. It
+	// returns ErrPrecondition if one of them is not in the routine.
+	CreateRoutineGroup(ctx context.Context, group *RoutineGroup) error
+	// ListRoutineGroups returns the groups of a routine in the order of
+	// their first exercise.
+	ListRoutineGroups(ctx context.Context, routineID int) ([]RoutineGroup, error)
+	// UpdateRoutineGroup updates the group identified by ID and
+	// RoutineID and replaces its exercises, like CreateRoutineGroup.
+	UpdateRoutineGroup(ctx context.Context, group *RoutineGroup) error
+	// DeleteRoutineGroup deletes a group, leaving its exercises in the
+	// routine on their own.
+	DeleteRoutineGroup(ctx context.Context, routineID, id int) error
+}
+
+// WorkoutStore persists workouts and their sets.
+type WorkoutStore interface {
+	// CreateWorkout creates a workout along with its sets in a single
+	// transaction, filling in their IDs and numbering the sets like
+	// AddWorkoutSet. It returns ErrConflict if the workout is in
+	// progress and the user already has another workout in progress,
+	// or if a set index is taken.
+	CreateWorkout(ctx context.Context, workout *Workout, sets []WorkoutSet) error
+	// ImportWorkouts creates the exercises, and then the workouts along
+	// with their sets, in a single transaction, filling in their IDs and
+	// numbering the sets like CreateWorkout.
+	ImportWorkouts(ctx context.Context, exercises []*Exercise, workouts []ImportedWorkout) error
+	GetWorkout(ctx context.Context, id int) (Workout, error)
+	// ListWorkouts returns a page of workouts, most recent first unless
+	// sorted by one of WorkoutSortFields.
+	ListWorkouts(ctx context.Context, filter WorkoutFilter) ([]WorkoutWithRoutineName, PageInfo, error)
+	// UpdateWorkout updates the routine and time of a workout.
+	UpdateWorkout(ctx context.Context, workout *Workout) error
+	// SetWorkoutStatus stores the Status, StartedAt, FinishedAt and
+	// PerformedAt of a workout that is still in status from. It returns
+	// ErrPrecondition if the workout is in another status, and
+	// ErrConflict if the user already has another workout in progress.
+	SetWorkoutStatus(ctx context.Context, workout *Workout, from string) error
+	// DeleteWorkout deletes a workout along with its sets. The records
+	// of the sets go to the best remaining sets of the user, like with
+	// SetPersonalRecords.
+	DeleteWorkout(ctx context.Context, id int) error
+	// SetWorkoutDeleted soft deletes or restores a workout, which hides
+	// or shows its sets and records along with it. It returns
+	// ErrPrecondition if the workout is already in that state, and
+	// ErrConflict if restoring it would leave the user with two workouts
+	// in progress.
+	SetWorkoutDeleted(ctx context.Context, id int, deleted bool) error
+	WorkoutOwner(ctx context.Context, id int) (Owner, error)
+
+	// AddWorkoutSet numbers the set after the last set of its exercise
+	// in the workout unless SetIndex is given. It returns ErrConflict
+	// if that index is taken.
+	AddWorkoutSet(ctx context.Context, set *WorkoutSet) error
+	GetWorkoutSet(ctx context.Context, workoutID, setID int) (WorkoutSetWithDetails, error)
+	ListWorkoutSets(ctx context.Context, workoutID int) ([]WorkoutSetWithDetails, error)
+	// ListPerformedSets returns the completed sets matching the filter,
+	// in the order they were performed.
+	ListPerformedSets(ctx context.Context, filter PerformedSetFilter) ([]PerformedSet, error)
+	// WalkWorkoutHistory calls fn with every row of the workout history
+	// of a user, oldest workout first, without loading it all at once.
+	// It stops at the first error fn returns and returns it.
+	WalkWorkoutHistory(ctx context.Context, userID int, fn func(HistoryRow) error) error
+	// UpdateWorkoutSet updates the set identified by ID and WorkoutID.
+	UpdateWorkoutSet(ctx context.Context, set *WorkoutSet) error
+	// DeleteWorkoutSet deletes a set along with its track. Its records
+	// go to the best remaining sets of the user, like with
+	// SetPersonalRecords.
+	DeleteWorkoutSet(ctx context.Context, workoutID, setID int) error
+}
+
+// RecordStore persists personal records.
+type RecordStore interface {
+	// SetPersonalRecords replaces the records earned by a workout set
+	// with those of training.RecordCandidates that beat the current
+	// records of its user, and returns them. The records the set no
+	// longer holds go to the best remaining sets of the user.
+	SetPersonalRecords(ctx context.Context, setID int) ([]PersonalRecord, error)
+	// ListPersonalRecords returns records ordered by exercise name,
+	// kind and weight, with the most recent record first.
+	ListPersonalRecords(ctx context.Context, filter RecordFilter) ([]PersonalRecordWithDetails, error)
+}
+
+// TrackStore persists the GPS tracks of workout sets. Tracks are
+// deleted along with their set.
+type TrackStore interface {
+	// CreateTrack stores a track along with its points and the set it
+	// records in a single transaction. The set is updated if it has an
+	// ID and added to the workout of the track otherwise. It returns
+	// ErrNotFound if the set is not in the workout and ErrConflict if it
+	// already has a track.
+	CreateTrack(ctx context.Context, track *Track, set *WorkoutSet) error
+	// GetTrack returns the track of a workout along with its points.
+	GetTrack(ctx context.Context, workoutID, trackID int) (Track, error)
+	// ListTracks returns the tracks of a workout without their points.
+	ListTracks(ctx context.Context, workoutID int) ([]Track, error)
+}
+
+// TaxonomyStore lists the canonical muscles, equipment and exercise
+// types that exercises are described with.
+type TaxonomyStore interface {
+	// ListMuscleGroups returns the muscle groups from head to toe.
+	ListMuscleGroups(ctx context.Context) ([]MuscleGroup, error)
+	// ListMuscles returns the muscles by group, then by name.
+	ListMuscles(ctx context.Context) ([]Muscle, error)
+	ListEquipment(ctx context.Context) ([]Equipment, error)
+	ListExerciseTypes(ctx context.Context) ([]ExerciseType, error)
+}
+
+// IdempotencyLease is how long a request may hold the key it reserved
+// without storing its response. Keys held longer are reclaimed, so that
+// a request that never finished, as when the server stopped, does not
+// leave its key in progress until it expires.
+const IdempotencyLease = 5 * time.Minute
+
+// IdempotencyStore persists the responses to requests made with an
+// idempotency key, so that retries can replay them.
+type IdempotencyStore interface {
+	// ReserveIdempotencyKey claims the Key of the user for a request
+	// until ExpiresAt, deleting the expired keys of the user along with
+	// those held past IdempotencyLease. If the key is already claimed,
+	// it fills in the key as stored and returns ErrConflict.
+	ReserveIdempotencyKey(ctx context.Context, key *IdempotencyKey) error
+	// SaveIdempotentResponse stores the StatusCode, ContentType and Body
+	// of the response to the request that claimed the key.
+	SaveIdempotentResponse(ctx context.Context, key *IdempotencyKey) error
+	// ReleaseIdempotencyKey deletes a key, so that the request can be
+	// made again with it.
+	ReleaseIdempotencyKey(ctx context.Context, userID int, key string) error
+}
+
+// SyncStore lists what changed in the programs, routines, routine
+// exercises, workouts and workout sets of a user, for clients that keep
+// a copy of them.
+//
+// Changes are listed in the order their transactions started, and only
+// once every transaction that started before theirs has finished. A
+// transaction still running may thus hold back the changes of later
+// ones, but can never commit a change before a cursor already handed
+// out: listing from the last cursor never misses a change.
+type SyncStore interface {
+	// ListChanges returns the first limit changes made after the cursor
+	// since, or every change when since is the zero cursor. A row
+	// changed several times is listed once, as it is now.
+	ListChanges(ctx context.Context, userID int, since ChangeCursor, limit int) (Changes, error)
+}
@@ -0,0 +1,695 @@
+// Package storetest checks the behaviour that the handlers rely on from
+// every store backend: the ordering and totals of lists, uniqueness
+// conflicts and what deletes take along with them. Each backend runs
+// the same checks, which keeps the memory store in line with Postgres.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Run runs every check against the stores returned by open, which must
+// be empty.
+func Run(t *testing.T, open func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s store.Store)
+	}{
+		{"ListPrograms", testListPrograms},
+		{"ListExercises", testListExercises},
+		{"ListRoutines", testListRoutines},
+		{"ListWorkouts", testListWorkouts},
+		{"Conflicts", testConflicts},
+		{"DeleteProgram", testDeleteProgram},
+		{"DeleteWorkout", testDeleteWorkout},
+		{"DeleteExercise", testDeleteExercise},
+		{"SoftDelete", testSoftDelete},
+		{"PersonalRecords", testPersonalRecords},
+		{"RecordsAreHandedOn", testRecordsAreHandedOn},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, open(t))
+		})
+	}
+}
+
+// day is when the test workouts start being performed.
+var day = time.Date(2024, 1, 1, 7, 0, 0, 0, time.UTC)
+
+func createUser(t *testing.T, s store.Store, email string) int {
+	t.Helper()
+	user := store.User{Email: email, PasswordHash: "hash"}
+	if err := s.CreateUser(context.Background(), &user); err != nil {
+		t.Fatalf("CreateUser() error = %v", err)
+	}
+	return user.ID
+}
+
+func createExercise(t *testing.T, s store.Store, ownerID *int, name string) int {
+	t.Helper()
+	exercise := store.Exercise{OwnerID: ownerID, Name: name, ExerciseType: "weight_reps"}
+	if err := s.CreateExercise(context.Background(), &exercise); err != nil {
+		t.Fatalf("CreateExercise() error = %v", err)
+	}
+	return exercise.ID
+}
+
+func createProgram(t *testing.T, s store.Store, program store.Program) int {
+	t.Helper()
+	if program.CycleDays == 0 {
+		program.CycleDays = 7
+	}
+	if err := s.CreateProgram(context.Background(), &program); err != nil {
+		t.Fatalf("CreateProgram() error = %v", err)
+	}
+	return program.ID
+}
+
+func createRoutine(t *testing.T, s store.Store, programID int, name string, dayNumber int) int {
+	t.Helper()
+	routine := store.Routine{ProgramID: programID, Name: name, DayNumber: dayNumber}
+	if err := s.CreateRoutine(context.Background(), &routine); err != nil {
+		t.Fatalf("CreateRoutine() error = %v", err)
+	}
+	return routine.ID
+}
+
+// createWorkout creates a completed workout performed days after day,
+// along with the sets, and returns it with the IDs of the sets.
+func createWorkout(t *testing.T, s store.Store, userID, routineID, days int, sets ...store.WorkoutSet) (store.Workout, []int) {
+	t.Helper()
+	workout := store.Workout{
+		UserID:      userID,
+		RoutineID:   routineID,
+		PerformedAt: day.AddDate(0, 0, days),
+		Status:      store.WorkoutCompleted,
+	}
+	if err := s.CreateWorkout(context.Background(), &workout, sets); err != nil {
+		t.Fatalf("CreateWorkout() error = %v", err)
+	}
+	ids := make([]int, len(sets))
+	for i, set := range sets {
+		ids[i] = set.ID
+	}
+	return workout, ids
+}
+
+// lister lists the names of a page of items.
+type lister func(page store.Page) ([]string, store.PageInfo, error)
+
+// checkPages lists every item sorted by keys, limit at a time, forward
+// from the first page and then backward from the last one, and checks
+// that both ways list want and that the first page counts them.
+func checkPages(t *testing.T, list lister, keys []store.SortKey, limit int, want []string) {
+	t.Helper()
+	page := store.Page{Limit: limit, Sort: keys, Count: true}
+	names, info, err := list(page)
+	if err != nil {
+		t.Fatalf("list %v error = %v", keys, err)
+	}
+	if info.Total != len(want) {
+		t.Errorf("list %v total = %d, want %d", keys, info.Total, len(want))
+	}
+	if info.Prev != nil {
+		t.Errorf("list %v first page has a previous page", keys)
+	}
+
+	forward := names
+	for pages := 1; info.Next != nil; pages++ {
+		if pages > len(want) {
+			t.Fatalf("list %v never ends", keys)
+		}
+		page = store.Page{Limit: limit, Sort: keys, Cursor: info.Next}
+		if names, info, err = list(page); err != nil {
+			t.Fatalf("list %v error = %v", keys, err)
+		}
+		if info.Total != -1 {
+			t.Errorf("list %v total = %d without counting", keys, info.Total)
+		}
+		forward = append(forward, names...)
+	}
+	if !reflect.DeepEqual(forward, want) {
+		t.Errorf("list %v forward = %q, want %q", keys, forward, want)
+	}
+
+	backward := names
+	for pages := 1; info.Prev != nil; pages++ {
+		if pages > len(want) {
+			t.Fatalf("list %v never ends backward", keys)
+		}
+		page = store.Page{Limit: limit, Sort: keys, Cursor: info.Prev}
+		if names, info, err = list(page); err != nil {
+			t.Fatalf("list %v error = %v", keys, err)
+		}
+		backward = append(names, backward...)
+	}
+	if !reflect.DeepEqual(backward, want) {
+		t.Errorf("list %v backward = %q, want %q", keys, backward, want)
+	}
+}
+
+func testListPrograms(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	other := createUser(t, s, "other@example.com")
+
+	createProgram(t, s, store.Program{UserID: user, Name: "Bravo", CycleDays: 7})
+	createProgram(t, s, store.Program{UserID: user, Name: "Alpha", CycleDays: 4})
+	createProgram(t, s, store.Program{UserID: user, Name: "Delta", CycleDays: 7})
+	createProgram(t, s, store.Program{UserID: user, Name: "Charlie", CycleDays: 4})
+	deleted := createProgram(t, s, store.Program{UserID: user, Name: "Deleted"})
+	createProgram(t, s, store.Program{UserID: user, Name: "Echo", CycleDays: 3})
+	createProgram(t, s, store.Program{UserID: other, Name: "Private"})
+	createProgram(t, s, store.Program{UserID: other, Name: "Public", IsPublic: true, CycleDays: 7})
+	if err := s.SetProgramDeleted(ctx, deleted, true); err != nil {
+		t.Fatalf("SetProgramDeleted() error = %v", err)
+	}
+
+	list := func(page store.Page) ([]string, store.PageInfo, error) {
+		programs, info, err := s.ListPrograms(ctx, store.ProgramFilter{Page: page, VisibleTo: user})
+		names := []string{}
+		for _, program := range programs {
+			names = append(names, program.Name)
+		}
+		return names, info, err
+	}
+
+	// Ties are broken by ID, in ascending order whatever the direction
+	checkPages(t, list, []store.SortKey{{Field: "name"}}, 2, []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo", "Public"})
+	checkPages(t, list, []store.SortKey{{Field: "name", Desc: true}}, 4, []string{"Public", "Echo", "Delta", "Charlie", "Bravo", "Alpha"})
+	checkPages(t, list, []store.SortKey{{Field: "cycle_days", Desc: true}}, 2, []string{"Bravo", "Delta", "Public", "Alpha", "Charlie", "Echo"})
+	checkPages(t, list, []store.SortKey{{Field: "cycle_days"}, {Field: "name", Desc: true}}, 5, []string{"Echo", "Charlie", "Alpha", "Public", "Delta", "Bravo"})
+
+	// Offsets skip items, and leave a way back
+	names, info, err := list(store.Page{Limit: 2, Offset: 3, Sort: []store.SortKey{{Field: "name"}}})
+	if err != nil {
+		t.Fatalf("ListPrograms() error = %v", err)
+	}
+	if want := []string{"Delta", "Echo"}; !reflect.DeepEqual(names, want) || info.Prev == nil || info.Next == nil {
+		t.Errorf("ListPrograms() at offset 3 = %q, %+v, want %q with both cursors", names, info, want)
+	}
+}
+
+func testListExercises(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	other := createUser(t, s, "other@example.com")
+
+	createExercise(t, s, nil, "Squat")
+	createExercise(t, s, nil, "Bench Press")
+	deleted := createExercise(t, s, nil, "Good Morning")
+	createExercise(t, s, nil, "Deadlift")
+	createExercise(t, s, &user, "Zercher Squat")
+	createExercise(t, s, &other, "Jefferson Curl")
+	plank := store.Exercise{Name: "Plank", ExerciseType: "duration_only"}
+	if err := s.CreateExercise(ctx, &plank); err != nil {
+		t.Fatalf("CreateExercise() error = %v", err)
+	}
+	if err := s.SetExerciseDeleted(ctx, deleted, true); err != nil {
+		t.Fatalf("SetExerciseDeleted() error = %v", err)
+	}
+
+	list := func(filter store.ExerciseFilter) lister {
+		return func(page store.Page) ([]string, store.PageInfo, error) {
+			filter.Page = page
+			exercises, info, err := s.ListExercises(ctx, filter)
+			names := []string{}
+			for _, exercise := range exercises {
+				names = append(names, exercise.Name)
+			}
+			return names, info, err
+		}
+	}
+
+	// The catalogue is listed along with the custom exercises of the
+	// user only
+	checkPages(t, list(store.ExerciseFilter{VisibleTo: user}), nil, 2, []string{"Bench Press", "Deadlift", "Plank", "Squat", "Zercher Squat"})
+	checkPages(t, list(store.ExerciseFilter{VisibleTo: other}), nil, 10, []string{"Bench Press", "Deadlift", "Jefferson Curl", "Plank", "Squat"})
+	checkPages(t, list(store.ExerciseFilter{VisibleTo: user, Type: "weight_reps"}), []store.SortKey{{Field: "name", Desc: true}}, 3, []string{"Zercher Squat", "Squat", "Deadlift", "Bench Press"})
+	checkPages(t, list(store.ExerciseFilter{VisibleTo: user, Name: "squat"}), nil, 3, []string{"Squat"})
+}
+
+func testListRoutines(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	program := createProgram(t, s, store.Program{UserID: user, Name: "Strength"})
+	other := createProgram(t, s, store.Program{UserID: user, Name: "Hypertrophy"})
+
+	createRoutine(t, s, program, "Upper", 2)
+	createRoutine(t, s, program, "Lower", 1)
+	createRoutine(t, s, program, "Arms", 2)
+	deleted := createRoutine(t, s, program, "Deleted", 1)
+	createRoutine(t, s, program, "Rest", 0)
+	createRoutine(t, s, other, "Legs", 1)
+	if err := s.SetRoutineDeleted(ctx, deleted, true); err != nil {
+		t.Fatalf("SetRoutineDeleted() error = %v", err)
+	}
+
+	list := func(page store.Page) ([]string, store.PageInfo, error) {
+		routines, info, err := s.ListRoutines(ctx, store.RoutineFilter{Page: page, VisibleTo: user, ProgramID: program})
+		names := []string{}
+		for _, routine := range routines {
+			names = append(names, routine.Name)
+		}
+		return names, info, err
+	}
+
+	checkPages(t, list, nil, 3, []string{"Rest", "Lower", "Arms", "Upper"})
+	checkPages(t, list, []store.SortKey{{Field: "name"}}, 1, []string{"Arms", "Lower", "Rest", "Upper"})
+}
+
+func testListWorkouts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	other := createUser(t, s, "other@example.com")
+	program := createProgram(t, s, store.Program{UserID: user, Name: "Strength"})
+	routine := createRoutine(t, s, program, "Upper", 1)
+
+	// Workouts are named after the day they were performed
+	labels := map[int]string{}
+	for _, days := range []int{3, 1, 4, 1, 5} {
+		workout, _ := createWorkout(t, s, user, routine, days)
+		labels[workout.ID] = workout.PerformedAt.Format("Jan 2")
+	}
+	planned := store.Workout{UserID: user, PerformedAt: day.AddDate(0, 0, 9), Status: store.WorkoutPlanned}
+	if err := s.CreateWorkout(ctx, &planned, nil); err != nil {
+		t.Fatalf("CreateWorkout() error = %v", err)
+	}
+	labels[planned.ID] = "planned"
+	deleted, _ := createWorkout(t, s, user, routine, 2)
+	if err := s.SetWorkoutDeleted(ctx, deleted.ID, true); err != nil {
+		t.Fatalf("SetWorkoutDeleted() error = %v", err)
+	}
+	createWorkout(t, s, other, 0, 2)
+
+	list := func(filter store.WorkoutFilter) lister {
+		return func(page store.Page) ([]string, store.PageInfo, error) {
+			filter.Page = page
+			filter.UserID = user
+			workouts, info, err := s.ListWorkouts(ctx, filter)
+			names := []string{}
+			for _, workout := range workouts {
+				names = append(names, labels[workout.ID])
+				if workout.ID == planned.ID && workout.RoutineName != "" || workout.ID != planned.ID && workout.RoutineName != "Upper" {
+					t.Errorf("ListWorkouts() routine name of workout %d = %q", workout.ID, workout.RoutineName)
+				}
+			}
+			return names, info, err
+		}
+	}
+
+	checkPages(t, list(store.WorkoutFilter{}), nil, 2, []string{"planned", "Jan 6", "Jan 5", "Jan 4", "Jan 2", "Jan 2"})
+	checkPages(t, list(store.WorkoutFilter{Status: store.WorkoutCompleted, From: day.AddDate(0, 0, 1), To: day.AddDate(0, 0, 5)}), []store.SortKey{{Field: "performed_at"}}, 2, []string{"Jan 2", "Jan 2", "Jan 4", "Jan 5"})
+	checkPages(t, list(store.WorkoutFilter{}), []store.SortKey{{Field: "status", Desc: true}}, 10, []string{"planned", "Jan 4", "Jan 2", "Jan 5", "Jan 2", "Jan 6"})
+}
+
+func testConflicts(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	program := createProgram(t, s, store.Program{UserID: user, Name: "Strength"})
+	squat := createExercise(t, s, nil, "Squat")
+	workout, sets := createWorkout(t, s, user, 0, 0, store.WorkoutSet{ExerciseID: squat, Reps: 5})
+
+	if err := s.CreateUser(ctx, &store.User{Email: "user@example.com", PasswordHash: "hash"}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateUser() with a taken email error = %v, want %v", err, store.ErrConflict)
+	}
+
+	enrollment := store.Enrollment{UserID: user, ProgramID: program, StartDate: day}
+	if err := s.CreateEnrollment(ctx, &enrollment); err != nil {
+		t.Fatalf("CreateEnrollment() error = %v", err)
+	}
+	if err := s.CreateEnrollment(ctx, &store.Enrollment{UserID: user, ProgramID: program, StartDate: day}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateEnrollment() twice error = %v, want %v", err, store.ErrConflict)
+	}
+	if err := s.EndEnrollment(ctx, &enrollment); err != nil {
+		t.Fatalf("EndEnrollment() error = %v", err)
+	}
+	if err := s.CreateEnrollment(ctx, &store.Enrollment{UserID: user, ProgramID: program, StartDate: day}); err != nil {
+		t.Errorf("CreateEnrollment() after the last one ended error = %v", err)
+	}
+
+	if err := s.AddWorkoutSet(ctx, &store.WorkoutSet{WorkoutID: workout.ID, ExerciseID: squat, SetIndex: 1}); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("AddWorkoutSet() at a taken index error = %v, want %v", err, store.ErrConflict)
+	}
+	set := store.WorkoutSet{WorkoutID: workout.ID, ExerciseID: squat}
+	if err := s.AddWorkoutSet(ctx, &set); err != nil || set.SetIndex != 2 {
+		t.Errorf("AddWorkoutSet() = index %d, %v, want index 2", set.SetIndex, err)
+	}
+
+	// A track fills in its set, and a second one leaves the set as it was
+	run := store.WorkoutSet{ID: sets[0], Completed: true, Distance: 5, Duration: 1500}
+	track := store.Track{WorkoutID: workout.ID, Format: "gpx"}
+	if err := s.CreateTrack(ctx, &track, &run); err != nil || track.WorkoutSetID != sets[0] {
+		t.Fatalf("CreateTrack() = set %d, %v, want set %d", track.WorkoutSetID, err, sets[0])
+	}
+	rerun := store.WorkoutSet{ID: sets[0], Completed: true, Distance: 10, Duration: 3000}
+	if err := s.CreateTrack(ctx, &store.Track{WorkoutID: workout.ID, Format: "gpx"}, &rerun); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateTrack() twice error = %v, want %v", err, store.ErrConflict)
+	}
+	if got, err := s.GetWorkoutSet(ctx, workout.ID, sets[0]); err != nil || got.Distance != 5 {
+		t.Errorf("GetWorkoutSet() after a refused track = distance %v, %v, want 5", got.Distance, err)
+	}
+	missing := store.WorkoutSet{ID: sets[0] + 100, Completed: true, Distance: 5, Duration: 1500}
+	if err := s.CreateTrack(ctx, &store.Track{WorkoutID: workout.ID, Format: "gpx"}, &missing); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("CreateTrack() for a missing set error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	// A track without a set logs a new one, or nothing at all
+	added := store.WorkoutSet{ExerciseID: squat, Completed: true, Distance: 3, Duration: 900}
+	if err := s.CreateTrack(ctx, &store.Track{WorkoutID: workout.ID, Format: "tcx"}, &added); err != nil || added.ID == 0 || added.SetIndex != 3 {
+		t.Errorf("CreateTrack() with a new set = set %d at index %d, %v, want a set at index 3", added.ID, added.SetIndex, err)
+	}
+	taken := store.WorkoutSet{ExerciseID: squat, SetIndex: 1, Completed: true, Distance: 3, Duration: 900}
+	if err := s.CreateTrack(ctx, &store.Track{WorkoutID: workout.ID, Format: "tcx"}, &taken); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateTrack() with a new set at a taken index error = %v, want %v", err, store.ErrConflict)
+	}
+	if tracks, err := s.ListTracks(ctx, workout.ID); err != nil || len(tracks) != 2 {
+		t.Errorf("ListTracks() = %d tracks, %v, want 2", len(tracks), err)
+	}
+
+	started := store.Workout{UserID: user, PerformedAt: day, Status: store.WorkoutInProgress}
+	if err := s.CreateWorkout(ctx, &started, nil); err != nil {
+		t.Fatalf("CreateWorkout() error = %v", err)
+	}
+	if err := s.CreateWorkout(ctx, &store.Workout{UserID: user, PerformedAt: day, Status: store.WorkoutInProgress}, nil); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateWorkout() with a workout in progress error = %v, want %v", err, store.ErrConflict)
+	}
+}
+
+func testDeleteProgram(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	squat := createExercise(t, s, nil, "Squat")
+	original := createProgram(t, s, store.Program{UserID: user, Name: "Strength"})
+	routine := createRoutine(t, s, original, "Lower", 1)
+	routineExercise := store.RoutineExercise{RoutineID: routine, ExerciseID: squat, RecommendedSets: 3, RecommendedReps: 5, ProgressionScheme: "none"}
+	if err := s.AddRoutineExercise(ctx, &routineExercise); err != nil {
+		t.Fatalf("AddRoutineExercise() error = %v", err)
+	}
+	if err := s.SetProgramBlocks(ctx, original, []store.ProgramBlock{{Name: "Base", Weeks: 4, SetScale: 1, RPEScale: 1}}); err != nil {
+		t.Fatalf("SetProgramBlocks() error = %v", err)
+	}
+	enrollment := store.Enrollment{UserID: user, ProgramID: original, StartDate: day}
+	if err := s.CreateEnrollment(ctx, &enrollment); err != nil {
+		t.Fatalf("CreateEnrollment() error = %v", err)
+	}
+	fork := store.Program{UserID: user, Name: "Fork", ForkedFromProgramID: &original}
+	if err := s.ForkProgram(ctx, &fork); err != nil {
+		t.Fatalf("ForkProgram() error = %v", err)
+	}
+	workout, sets := createWorkout(t, s, user, routine, 0, store.WorkoutSet{ExerciseID: squat, Reps: 5})
+
+	if err := s.DeleteProgram(ctx, original); err != nil {
+		t.Fatalf("DeleteProgram() error = %v", err)
+	}
+	if err := s.DeleteProgram(ctx, original); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteProgram() twice error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	// The routines, blocks and enrollments go along with the program
+	if _, err := s.GetRoutine(ctx, routine); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetRoutine() error = %v, want %v", err, store.ErrNotFound)
+	}
+	if references, err := s.ExerciseReferences(ctx, squat); err != nil || references.RoutineExercises != 1 {
+		t.Errorf("ExerciseReferences() = %+v, %v, want only the routine exercise of the fork", references, err)
+	}
+	if blocks, err := s.ListProgramBlocks(ctx, original); err != nil || len(blocks) != 0 {
+		t.Errorf("ListProgramBlocks() = %v, %v, want none", blocks, err)
+	}
+	if _, err := s.GetEnrollment(ctx, enrollment.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetEnrollment() error = %v, want %v", err, store.ErrNotFound)
+	}
+
+	// The fork and the workouts that followed the routines remain
+	if got, err := s.GetProgram(ctx, fork.ID); err != nil || got.ForkedFromProgramID != nil {
+		t.Errorf("GetProgram() of the fork = %+v, %v, want it forked from nothing", got, err)
+	}
+	if got, err := s.GetWorkout(ctx, workout.ID); err != nil || got.RoutineID != 0 {
+		t.Errorf("GetWorkout() = %+v, %v, want it without a routine", got, err)
+	}
+	if _, err := s.GetWorkoutSet(ctx, workout.ID, sets[0]); err != nil {
+		t.Errorf("GetWorkoutSet() error = %v", err)
+	}
+}
+
+func testDeleteWorkout(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	squat := createExercise(t, s, nil, "Squat")
+	workout, sets := createWorkout(t, s, user, 0, 0,
+		store.WorkoutSet{ExerciseID: squat, Completed: true, Reps: 5, Weight: 100},
+		store.WorkoutSet{ExerciseID: squat, Completed: true, Reps: 5, Weight: 100},
+	)
+	if _, err := s.SetPersonalRecords(ctx, sets[0]); err != nil {
+		t.Fatalf("SetPersonalRecords() error = %v", err)
+	}
+	run := store.WorkoutSet{ID: sets[1], Completed: true, Reps: 5, Weight: 100}
+	if err := s.CreateTrack(ctx, &store.Track{WorkoutID: workout.ID, Format: "gpx"}, &run); err != nil {
+		t.Fatalf("CreateTrack() error = %v", err)
+	}
+
+	if err := s.DeleteWorkout(ctx, workout.ID); err != nil {
+		t.Fatalf("DeleteWorkout() error = %v", err)
+	}
+
+	// The sets go along with the workout, and their records and tracks
+	// along with them
+	if _, err := s.GetWorkout(ctx, workout.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("GetWorkout() error = %v, want %v", err, store.ErrNotFound)
+	}
+	if got, err := s.ListWorkoutSets(ctx, workout.ID); err != nil || len(got) != 0 {
+		t.Errorf("ListWorkoutSets() = %v, %v, want none", got, err)
+	}
+	if got, err := s.ListPersonalRecords(ctx, store.RecordFilter{UserID: user, History: true}); err != nil || len(got) != 0 {
+		t.Errorf("ListPersonalRecords() = %v, %v, want none", got, err)
+	}
+	if got, err := s.ListTracks(ctx, workout.ID); err != nil || len(got) != 0 {
+		t.Errorf("ListTracks() = %v, %v, want none", got, err)
+	}
+	if references, err := s.ExerciseReferences(ctx, squat); err != nil || references != (store.ExerciseReferences{}) {
+		t.Errorf("ExerciseReferences() = %+v, %v, want none", references, err)
+	}
+}
+
+func testDeleteExercise(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	squat := createExercise(t, s, nil, "Squat")
+	curl := createExercise(t, s, nil, "Curl")
+	workout, sets := createWorkout(t, s, user, 0, 0, store.WorkoutSet{ExerciseID: squat, Reps: 5})
+
+	// Exercises still in use cannot be deleted for good
+	if err := s.DeleteExercise(ctx, squat); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("DeleteExercise() of a used exercise error = %v, want %v", err, store.ErrConflict)
+	}
+	if err := s.DeleteWorkoutSet(ctx, workout.ID, sets[0]); err != nil {
+		t.Fatalf("DeleteWorkoutSet() error = %v", err)
+	}
+	if err := s.DeleteExercise(ctx, squat); err != nil {
+		t.Errorf("DeleteExercise() of an exercise no longer used error = %v", err)
+	}
+
+	if err := s.DeleteExercise(ctx, curl); err != nil {
+		t.Errorf("DeleteExercise() error = %v", err)
+	}
+	if err := s.DeleteExercise(ctx, curl); !errors.Is(err, store.ErrNotFound) {
+		t.Errorf("DeleteExercise() twice error = %v, want %v", err, store.ErrNotFound)
+	}
+}
+
+func testSoftDelete(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	program := createProgram(t, s, store.Program{UserID: user, Name: "Strength"})
+	routine := createRoutine(t, s, program, "Lower", 1)
+
+	if err := s.SetProgramDeleted(ctx, program, true); err != nil {
+		t.Fatalf("SetProgramDeleted() error = %v", err)
+	}
+	if err := s.SetProgramDeleted(ctx, program, true); !errors.Is(err, store.ErrPrecondition) {
+		t.Errorf("SetProgramDeleted() twice error = %v, want %v", err, store.ErrPrecondition)
+	}
+
+	// The routines are hidden along with their program
+	if owner, err := s.RoutineOwner(ctx, routine); err != nil || !owner.Deleted {
+		t.Errorf("RoutineOwner() = %+v, %v, want it deleted", owner, err)
+	}
+	routines, _, err := s.ListRoutines(ctx, store.RoutineFilter{Page: store.Page{Limit: 10}, VisibleTo: user})
+	if err != nil || len(routines) != 0 {
+		t.Errorf("ListRoutines() = %v, %v, want none", routines, err)
+	}
+
+	if err := s.SetProgramDeleted(ctx, program, false); err != nil {
+		t.Fatalf("SetProgramDeleted() restoring error = %v", err)
+	}
+	if owner, err := s.RoutineOwner(ctx, routine); err != nil || owner.Deleted {
+		t.Errorf("RoutineOwner() after restoring = %+v, %v, want it restored", owner, err)
+	}
+
+	// A workout in progress cannot be restored while another one is
+	first := store.Workout{UserID: user, PerformedAt: day, Status: store.WorkoutInProgress}
+	if err := s.CreateWorkout(ctx, &first, nil); err != nil {
+		t.Fatalf("CreateWorkout() error = %v", err)
+	}
+	if err := s.SetWorkoutDeleted(ctx, first.ID, true); err != nil {
+		t.Fatalf("SetWorkoutDeleted() error = %v", err)
+	}
+	second := store.Workout{UserID: user, PerformedAt: day, Status: store.WorkoutInProgress}
+	if err := s.CreateWorkout(ctx, &second, nil); err != nil {
+		t.Fatalf("CreateWorkout() with the other workout deleted error = %v", err)
+	}
+	if err := s.SetWorkoutDeleted(ctx, first.ID, false); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("SetWorkoutDeleted() restoring error = %v, want %v", err, store.ErrConflict)
+	}
+}
+
+// earnsMaxWeight sets the records of a set and reports whether it
+// earned the max weight record.
+func earnsMaxWeight(t *testing.T, s store.Store, setID int) bool {
+	t.Helper()
+	records, err := s.SetPersonalRecords(context.Background(), setID)
+	if err != nil {
+		t.Fatalf("SetPersonalRecords() error = %v", err)
+	}
+	for _, record := range records {
+		if record.Kind == store.RecordMaxWeight {
+			return true
+		}
+	}
+	return false
+}
+
+// maxWeights returns the max weight records of the user matching the
+// filter.
+func maxWeights(t *testing.T, s store.Store, userID int, filter store.RecordFilter) []float64 {
+	t.Helper()
+	filter.UserID = userID
+	records, err := s.ListPersonalRecords(context.Background(), filter)
+	if err != nil {
+		t.Fatalf("ListPersonalRecords() error = %v", err)
+	}
+	values := []float64{}
+	for _, record := range records {
+		if record.Kind == store.RecordMaxWeight {
+			values = append(values, record.Value)
+		}
+	}
+	return values
+}
+
+// liftSet returns a completed set of five reps.
+func liftSet(exerciseID int, weight float64) store.WorkoutSet {
+	return store.WorkoutSet{ExerciseID: exerciseID, Completed: true, Reps: 5, Weight: weight}
+}
+
+func testPersonalRecords(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	squat := createExercise(t, s, nil, "Squat")
+	bench := createExercise(t, s, nil, "Bench Press")
+
+	_, first := createWorkout(t, s, user, 0, 0, liftSet(squat, 100), liftSet(bench, 80))
+	earnsMaxWeight(t, s, first[0])
+	earnsMaxWeight(t, s, first[1])
+	second, sets := createWorkout(t, s, user, 0, 1, liftSet(squat, 110), liftSet(squat, 90))
+	if !earnsMaxWeight(t, s, sets[0]) {
+		t.Error("SetPersonalRecords() of a heavier set earned no record")
+	}
+	if earnsMaxWeight(t, s, sets[1]) {
+		t.Error("SetPersonalRecords() of a lighter set earned a record")
+	}
+
+	// Records are ordered by exercise name, the most recent first
+	if got, want := maxWeights(t, s, user, store.RecordFilter{}), []float64{80, 110}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPersonalRecords() = %v, want %v", got, want)
+	}
+	if got, want := maxWeights(t, s, user, store.RecordFilter{History: true}), []float64{80, 110, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPersonalRecords() history = %v, want %v", got, want)
+	}
+	if got, want := maxWeights(t, s, user, store.RecordFilter{ExerciseID: squat}), []float64{110}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPersonalRecords() of squat = %v, want %v", got, want)
+	}
+
+	// Records of deleted workouts are hidden along with them
+	if err := s.SetWorkoutDeleted(ctx, second.ID, true); err != nil {
+		t.Fatalf("SetWorkoutDeleted() error = %v", err)
+	}
+	if got, want := maxWeights(t, s, user, store.RecordFilter{}), []float64{80, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ListPersonalRecords() with the workout deleted = %v, want %v", got, want)
+	}
+}
+
+// testRecordsAreHandedOn checks that the records a set loses go to the
+// best remaining set, even one that never earned a record because it
+// was performed after a better one.
+func testRecordsAreHandedOn(t *testing.T, s store.Store) {
+	ctx := context.Background()
+	user := createUser(t, s, "user@example.com")
+	squat := createExercise(t, s, nil, "Squat")
+
+	first, a := createWorkout(t, s, user, 0, 0, liftSet(squat, 100))
+	second, b := createWorkout(t, s, user, 0, 1, liftSet(squat, 110))
+	third, c := createWorkout(t, s, user, 0, 2, liftSet(squat, 105))
+	for _, sets := range [][]int{a, b, c} {
+		earnsMaxWeight(t, s, sets[0])
+	}
+
+	current := func(want float64, setID int) {
+		t.Helper()
+		records, err := s.ListPersonalRecords(ctx, store.RecordFilter{UserID: user})
+		if err != nil {
+			t.Fatalf("ListPersonalRecords() error = %v", err)
+		}
+		found := false
+		for _, record := range records {
+			if record.Kind != store.RecordMaxWeight {
+				continue
+			}
+			found = true
+			if record.Value != want || record.WorkoutSetID != setID {
+				t.Errorf("max weight record = %g on set %d, want %g on set %d", record.Value, record.WorkoutSetID, want, setID)
+			}
+		}
+		if !found {
+			t.Errorf("no max weight record, want %g on set %d", want, setID)
+		}
+	}
+
+	// Setting the records of a set that did not change changes nothing
+	if !earnsMaxWeight(t, s, b[0]) {
+		t.Error("SetPersonalRecords() of the unchanged record set earned no record")
+	}
+	current(110, b[0])
+	if got, want := maxWeights(t, s, user, store.RecordFilter{History: true}), []float64{110, 100}; !reflect.DeepEqual(got, want) {
+		t.Errorf("history after setting the records again = %v, want %v", got, want)
+	}
+
+	// Editing the record down hands it to the next best set
+	edited := liftSet(squat, 95)
+	edited.ID, edited.WorkoutID = b[0], second.ID
+	if err := s.UpdateWorkoutSet(ctx, &edited); err != nil {
+		t.Fatalf("UpdateWorkoutSet() error = %v", err)
+	}
+	if earnsMaxWeight(t, s, b[0]) {
+		t.Error("SetPersonalRecords() of the edited set kept the record")
+	}
+	current(105, c[0])
+
+	// So does deleting the set or the workout holding it
+	if err := s.DeleteWorkoutSet(ctx, third.ID, c[0]); err != nil {
+		t.Fatalf("DeleteWorkoutSet() error = %v", err)
+	}
+	current(100, a[0])
+	if err := s.DeleteWorkout(ctx, first.ID); err != nil {
+		t.Fatalf("DeleteWorkout() error = %v", err)
+	}
+	current(95, b[0])
+}
@@ -0,0 +1,199 @@
+package training
+
+import (
+	"sort"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// Bucket is the period over which analytics are aggregated.
+type Bucket string
+
+// Supported buckets. Weeks start on Monday.
+const (
+	Day   Bucket = "day"
+	Week  Bucket = "week"
+	Month Bucket = "month"
+)
+
+// Start returns the start of the bucket containing t, in t's location.
+func (b Bucket) Start(t time.Time) time.Time {
+	year, month, day := t.Date()
+	switch b {
+	case Week:
+		// Go weeks start on Sunday
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(year, month, day-offset, 0, 0, 0, 0, t.Location())
+	case Month:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// Valid reports whether b is a supported bucket.
+func (b Bucket) Valid() bool {
+	return b == Day || b == Week || b == Month
+}
+
+// counts reports whether a set counts towards analytics. Warm-up sets
+// are left out so that they do not inflate volume.
+func counts(set store.PerformedSet) bool {
+	return set.Completed && set.SetType != store.SetWarmup
+}
+
+// OneRepMaxPoint is the best estimated one-rep max over a bucket.
+type OneRepMaxPoint struct {
+	Start     time.Time `json:"start"`
+	OneRepMax float64   `json:"e1rm"`
+}
+
+// OneRepMaxTrend is the evolution of the estimated one-rep max on an
+// exercise.
+type OneRepMaxTrend struct {
+	ExerciseID   int              `json:"exercise_id"`
+	ExerciseName string           `json:"exercise_name"`
+	Points       []OneRepMaxPoint `json:"points"`
+}
+
+// OneRepMaxTrends returns the best estimated one-rep max of each
+// weight_reps exercise per bucket, adjusting reps for the RPE of each
+// set. Buckets without sets are left out. Sets must be sorted by the
+// time they were performed.
+func OneRepMaxTrends(sets []store.PerformedSet, bucket Bucket, formula Formula) []OneRepMaxTrend {
+	trends := []OneRepMaxTrend{}
+	index := map[int]int{}
+	for _, set := range sets {
+		if !counts(set) || set.ExerciseType != "weight_reps" {
+			continue
+		}
+
+		e1rm := formula.OneRepMax(set.Weight, EffectiveReps(set.Reps, set.RPE))
+		if e1rm <= 0 {
+			continue
+		}
+
+		i, ok := index[set.ExerciseID]
+		if !ok {
+			i = len(trends)
+			index[set.ExerciseID] = i
+			trends = append(trends, OneRepMaxTrend{
+				ExerciseID:   set.ExerciseID,
+				ExerciseName: set.ExerciseName,
+				Points:       []OneRepMaxPoint{},
+			})
+		}
+
+		start := bucket.Start(set.PerformedAt)
+		points := trends[i].Points
+		if n := len(points); n > 0 && points[n-1].Start.Equal(start) {
+			points[n-1].OneRepMax = max(points[n-1].OneRepMax, e1rm)
+			continue
+		}
+		trends[i].Points = append(points, OneRepMaxPoint{Start: start, OneRepMax: e1rm})
+	}
+
+	sort.SliceStable(trends, func(i, j int) bool {
+		return trends[i].ExerciseName < trends[j].ExerciseName
+	})
+	return trends
+}
+
+// VolumePoint is the training volume over a bucket. Tonnage is the sum
+// of reps times weight.
+type VolumePoint struct {
+	Start   time.Time `json:"start"`
+	Sets    int       `json:"sets"`
+	Reps    int       `json:"reps"`
+	Tonnage float64   `json:"tonnage"`
+}
+
+// Volume returns the volume of weight_reps sets per bucket. Buckets
+// without sets are left out. Sets must be sorted by the time they were
+// performed.
+func Volume(sets []store.PerformedSet, bucket Bucket) []VolumePoint {
+	points := []VolumePoint{}
+	for _, set := range sets {
+		if !counts(set) || set.ExerciseType != "weight_reps" {
+			continue
+		}
+
+		start := bucket.Start(set.PerformedAt)
+		if n := len(points); n == 0 || !points[n-1].Start.Equal(start) {
+			points = append(points, VolumePoint{Start: start})
+		}
+
+		point := &points[len(points)-1]
+		point.Sets++
+		point.Reps += set.Reps
+		point.Tonnage += float64(set.Reps) * set.Weight
+	}
+	return points
+}
+
+// MuscleSets is the number of sets that worked a muscle, as a primary
+// or secondary muscle.
+type MuscleSets struct {
+	Muscle    string `json:"muscle"`
+	Primary   int    `json:"primary_sets"`
+	Secondary int    `json:"secondary_sets"`
+}
+
+// MusclePoint is the number of sets per muscle over a bucket.
+type MusclePoint struct {
+	Start   time.Time    `json:"start"`
+	Muscles []MuscleSets `json:"muscles"`
+}
+
+// Muscles returns the number of sets per muscle and bucket, whatever
+// the exercise type. Muscles are sorted by name, and buckets without
+// sets are left out. Sets must be sorted by the time they were
+// performed.
+func Muscles(sets []store.PerformedSet, bucket Bucket) []MusclePoint {
+	points := []MusclePoint{}
+	var muscles map[string]*MuscleSets
+
+	// flush turns the muscles of the last bucket into a sorted list
+	flush := func() {
+		if len(points) == 0 {
+			return
+		}
+		list := []MuscleSets{}
+		for _, muscle := range muscles {
+			list = append(list, *muscle)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Muscle < list[j].Muscle
+		})
+		points[len(points)-1].Muscles = list
+	}
+
+	muscle := func(name string) *MuscleSets {
+		if _, ok := muscles[name]; !ok {
+			muscles[name] = &MuscleSets{Muscle: name}
+		}
+		return muscles[name]
+	}
+
+	for _, set := range sets {
+		if !counts(set) {
+			continue
+		}
+
+		start := bucket.Start(set.PerformedAt)
+		if n := len(points); n == 0 || !points[n-1].Start.Equal(start) {
+			flush()
+			points = append(points, MusclePoint{Start: start})
+			muscles = map[string]*MuscleSets{}
+		}
+
+		for _, name := range set.PrimaryMuscles {
+			muscle(name).Primary++
+		}
+		for _, name := range set.SecondaryMuscles {
+			muscle(name).Secondary++
+		}
+	}
+	flush()
+	return points
+}
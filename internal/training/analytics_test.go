@@ -0,0 +1,151 @@
+package training
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+// date returns midnight of a day of January 2024, where the 1st is a
+// Monday.
+func date(day int) time.Time {
+	return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+}
+
+// performed returns a completed working set of a weight_reps exercise.
+func performed(day, exerciseID int, name string, weight float64, reps int) store.PerformedSet {
+	return store.PerformedSet{
+		WorkoutSetWithDetails: store.WorkoutSetWithDetails{
+			WorkoutSet: store.WorkoutSet{
+				ExerciseID: exerciseID,
+				SetType:    store.SetWorking,
+				Completed:  true,
+				Reps:       reps,
+				Weight:     weight,
+			},
+			ExerciseName: name,
+			ExerciseType: "weight_reps",
+		},
+		PerformedAt: date(day).Add(18 * time.Hour),
+	}
+}
+
+func TestBucketStart(t *testing.T) {
+	wednesday := time.Date(2024, 1, 3, 15, 4, 5, 0, time.UTC)
+	sunday := time.Date(2024, 1, 7, 23, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		bucket Bucket
+		t      time.Time
+		want   time.Time
+	}{
+		{"day", Day, wednesday, date(3)},
+		{"week", Week, wednesday, date(1)},
+		{"week on a sunday", Week, sunday, date(1)},
+		{"week on a monday", Week, date(8), date(8)},
+		{"month", Month, wednesday, date(1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bucket.Start(tt.t); !got.Equal(tt.want) {
+				t.Errorf("Start(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketValid(t *testing.T) {
+	for bucket, want := range map[Bucket]bool{Day: true, Week: true, Month: true, "year": false, "": false} {
+		if got := bucket.Valid(); got != want {
+			t.Errorf("Bucket(%q).Valid() = %v, want %v", bucket, got, want)
+		}
+	}
+}
+
+func TestVolume(t *testing.T) {
+	warmup := performed(3, 1, "Squat", 60, 5)
+	warmup.SetType = store.SetWarmup
+	running := performed(8, 2, "Running", 0, 0)
+	running.ExerciseType = "distance_time"
+	running.Distance = 5
+
+	sets := []store.PerformedSet{
+		performed(1, 1, "Squat", 100, 5),
+		warmup,
+		performed(3, 3, "Bench Press", 50, 10),
+		running,
+		performed(8, 1, "Squat", 80, 5),
+	}
+
+	want := []VolumePoint{
+		{Start: date(1), Sets: 2, Reps: 15, Tonnage: 1000},
+		{Start: date(8), Sets: 1, Reps: 5, Tonnage: 400},
+	}
+	if got := Volume(sets, Week); !reflect.DeepEqual(got, want) {
+		t.Errorf("Volume() = %+v, want %+v", got, want)
+	}
+}
+
+func TestMuscles(t *testing.T) {
+	squat := performed(1, 1, "Squat", 100, 5)
+	squat.PrimaryMuscles = []string{"quadriceps"}
+	squat.SecondaryMuscles = []string{"glutes"}
+	hipThrust := performed(1, 2, "Hip Thrust", 100, 10)
+	hipThrust.PrimaryMuscles = []string{"glutes"}
+	bench := performed(2, 3, "Bench Press", 80, 5)
+	bench.PrimaryMuscles = []string{"chest"}
+
+	want := []MusclePoint{
+		{Start: date(1), Muscles: []MuscleSets{
+			{Muscle: "glutes", Primary: 1, Secondary: 1},
+			{Muscle: "quadriceps", Primary: 1},
+		}},
+		{Start: date(2), Muscles: []MuscleSets{
+			{Muscle: "chest", Primary: 1},
+		}},
+	}
+	if got := Muscles([]store.PerformedSet{squat, hipThrust, bench}, Day); !reflect.DeepEqual(got, want) {
+		t.Errorf("Muscles() = %+v, want %+v", got, want)
+	}
+}
+
+func TestOneRepMaxTrends(t *testing.T) {
+	toFailure := performed(3, 1, "Squat", 100, 5)
+	toFailure.RPE = 10
+	easy := performed(4, 1, "Squat", 100, 5)
+	easy.RPE = 5
+
+	sets := []store.PerformedSet{
+		performed(1, 1, "Squat", 100, 1),
+		toFailure,
+		performed(2, 2, "Bench Press", 80, 1),
+		easy,
+		performed(8, 1, "Squat", 110, 1),
+	}
+
+	trends := OneRepMaxTrends(sets, Week, Epley)
+	if len(trends) != 2 {
+		t.Fatalf("OneRepMaxTrends() returned %d trends, want 2", len(trends))
+	}
+
+	// Trends are sorted by exercise name
+	if trends[0].ExerciseName != "Bench Press" || len(trends[0].Points) != 1 || trends[0].Points[0].OneRepMax != 80 {
+		t.Errorf("OneRepMaxTrends()[0] = %+v, want a single point of 80 for Bench Press", trends[0])
+	}
+
+	squat := trends[1]
+	if squat.ExerciseName != "Squat" || len(squat.Points) != 2 {
+		t.Fatalf("OneRepMaxTrends()[1] = %+v, want two points for Squat", squat)
+	}
+	// The easy set of the first week is worth 10 reps to failure
+	if !squat.Points[0].Start.Equal(date(1)) || !near(squat.Points[0].OneRepMax, 133.333) {
+		t.Errorf("first point = %+v, want 133.333 in the week of %v", squat.Points[0], date(1))
+	}
+	if !squat.Points[1].Start.Equal(date(8)) || squat.Points[1].OneRepMax != 110 {
+		t.Errorf("second point = %+v, want 110 in the week of %v", squat.Points[1], date(8))
+	}
+}
@@ -0,0 +1,38 @@
+package training
+
+import "github.com/soa-rs/fit/internal/store"
+
+// RecordCandidates returns the personal records a set may earn,
+// depending on the type of its exercise. They are only records if no
+// other set of the user did at least as well. Only completed sets earn
+// records.
+func RecordCandidates(set store.WorkoutSet, exerciseType string) []store.PersonalRecord {
+	if !set.Completed {
+		return nil
+	}
+
+	var candidates []store.PersonalRecord
+	switch exerciseType {
+	case "weight_reps":
+		if set.Weight > 0 && set.Reps > 0 {
+			candidates = append(candidates,
+				store.PersonalRecord{Kind: store.RecordMaxWeight, Value: set.Weight},
+				store.PersonalRecord{Kind: store.RecordEstimated1RM, Value: EstimatedOneRepMax(set.Weight, set.Reps)},
+				store.PersonalRecord{Kind: store.RecordRepsAtWeight, Value: float64(set.Reps), Weight: set.Weight},
+			)
+		}
+	case "duration_only":
+		if set.Duration > 0 {
+			candidates = append(candidates,
+				store.PersonalRecord{Kind: store.RecordLongestDuration, Value: float64(set.Duration)},
+			)
+		}
+	case "distance_time":
+		if set.Duration > 0 && set.Distance > 0 {
+			candidates = append(candidates,
+				store.PersonalRecord{Kind: store.RecordFastestPace, Value: Pace(set.Duration, set.Distance)},
+			)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,52 @@
+package training
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soa-rs/fit/internal/store"
+)
+
+func TestRecordCandidates(t *testing.T) {
+	tests := []struct {
+		name         string
+		set          store.WorkoutSet
+		exerciseType string
+		want         []store.PersonalRecord
+	}{
+		{
+			name:         "weight and reps",
+			set:          store.WorkoutSet{Completed: true, Reps: 1, Weight: 100},
+			exerciseType: "weight_reps",
+			want: []store.PersonalRecord{
+				{Kind: store.RecordMaxWeight, Value: 100},
+				{Kind: store.RecordEstimated1RM, Value: 100},
+				{Kind: store.RecordRepsAtWeight, Value: 1, Weight: 100},
+			},
+		},
+		{
+			name:         "duration",
+			set:          store.WorkoutSet{Completed: true, Duration: 60},
+			exerciseType: "duration_only",
+			want:         []store.PersonalRecord{{Kind: store.RecordLongestDuration, Value: 60}},
+		},
+		{
+			name:         "distance and time",
+			set:          store.WorkoutSet{Completed: true, Duration: 1800, Distance: 5},
+			exerciseType: "distance_time",
+			want:         []store.PersonalRecord{{Kind: store.RecordFastestPace, Value: 360}},
+		},
+		{"not completed", store.WorkoutSet{Reps: 5, Weight: 100}, "weight_reps", nil},
+		{"bodyweight", store.WorkoutSet{Completed: true, Reps: 10}, "weight_reps", nil},
+		{"no distance", store.WorkoutSet{Completed: true, Duration: 1800}, "distance_time", nil},
+		{"unknown type", store.WorkoutSet{Completed: true, Duration: 60}, "stretching", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RecordCandidates(tt.set, tt.exerciseType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RecordCandidates() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Package training implements the formulas used to compare and
+// analyse performances.
+package training
+
+// Formula is a formula estimating one-rep maxes.
+type Formula string
+
+// Supported one-rep max formulas
+const (
+	Epley   Formula = "epley"
+	Brzycki Formula = "brzycki"
+)
+
+// OneRepMax estimates the heaviest weight that could be lifted for a
+// single rep from a set of reps at the given weight. Reps may be
+// fractional once adjusted for effort; see EffectiveReps. It returns 0
+// for sets the formula cannot handle.
+func (f Formula) OneRepMax(weight, reps float64) float64 {
+	if reps <= 0 || weight <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return weight
+	}
+
+	switch f {
+	case Epley:
+		return weight * (1 + reps/30)
+	case Brzycki:
+		// The formula diverges as reps approach 37
+		if reps >= 36 {
+			return 0
+		}
+		return weight * 36 / (37 - reps)
+	}
+	return 0
+}
+
+// Weight is the inverse of OneRepMax: it returns the weight that can
+// be lifted for the given reps out of a one-rep max.
+func (f Formula) Weight(oneRepMax, reps float64) float64 {
+	if reps <= 0 || oneRepMax <= 0 {
+		return 0
+	}
+	if reps == 1 {
+		return oneRepMax
+	}
+
+	switch f {
+	case Epley:
+		return oneRepMax / (1 + reps/30)
+	case Brzycki:
+		if reps >= 36 {
+			return 0
+		}
+		return oneRepMax * (37 - reps) / 36
+	}
+	return 0
+}
+
+// EstimatedOneRepMax estimates the one-rep max of a set with the Epley
+// formula.
+func EstimatedOneRepMax(weight float64, reps int) float64 {
+	return Epley.OneRepMax(weight, float64(reps))
+}
+
+// EffectiveReps returns the reps a set would have reached if taken to
+// failure, given its RPE: an RPE of 10 leaves no reps in reserve, an
+// RPE of 8 leaves two. Sets without an RPE are taken at face value.
+func EffectiveReps(reps int, rpe float64) float64 {
+	if rpe <= 0 || rpe > 10 {
+		return float64(reps)
+	}
+	return float64(reps) + 10 - rpe
+}
+
+// Pace returns the time taken per unit of distance, in seconds. It
+// returns 0 if no distance was covered.
+func Pace(duration int, distance float64) float64 {
+	if distance <= 0 {
+		return 0
+	}
+	return float64(duration) / distance
+}
@@ -0,0 +1,103 @@
+package training
+
+import (
+	"math"
+	"testing"
+)
+
+// near reports whether two estimates agree to a thousandth.
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-3
+}
+
+func TestOneRepMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		formula Formula
+		weight  float64
+		reps    float64
+		want    float64
+	}{
+		{"epley", Epley, 100, 5, 116.667},
+		{"epley with fractional reps", Epley, 100, 7.5, 125},
+		{"brzycki", Brzycki, 100, 5, 112.5},
+		{"single rep", Epley, 100, 1, 100},
+		{"single rep brzycki", Brzycki, 100, 1, 100},
+		{"no reps", Epley, 100, 0, 0},
+		{"no weight", Epley, 0, 5, 0},
+		{"brzycki past its range", Brzycki, 100, 36, 0},
+		{"unknown formula", Formula("lombardi"), 100, 5, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.formula.OneRepMax(tt.weight, tt.reps); !near(got, tt.want) {
+				t.Errorf("OneRepMax(%g, %g) = %g, want %g", tt.weight, tt.reps, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeightInvertsOneRepMax(t *testing.T) {
+	for _, formula := range []Formula{Epley, Brzycki} {
+		for _, reps := range []float64{1, 3, 5, 8.5, 12} {
+			e1rm := formula.OneRepMax(100, reps)
+			if got := formula.Weight(e1rm, reps); !near(got, 100) {
+				t.Errorf("%s: Weight(%g, %g) = %g, want 100", formula, e1rm, reps, got)
+			}
+		}
+	}
+
+	if got := Epley.Weight(100, 0); got != 0 {
+		t.Errorf("Weight() for no reps = %g, want 0", got)
+	}
+}
+
+func TestEstimatedOneRepMax(t *testing.T) {
+	if got := EstimatedOneRepMax(100, 10); !near(got, 133.333) {
+		t.Errorf("EstimatedOneRepMax(100, 10) = %g, want 133.333", got)
+	}
+}
+
+func TestEffectiveReps(t *testing.T) {
+	tests := []struct {
+		name string
+		reps int
+		rpe  float64
+		want float64
+	}{
+		{"no RPE", 5, 0, 5},
+		{"to failure", 5, 10, 5},
+		{"two in reserve", 5, 8, 7},
+		{"half reps in reserve", 5, 6.5, 8.5},
+		{"invalid RPE", 5, 11, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EffectiveReps(tt.reps, tt.rpe); got != tt.want {
+				t.Errorf("EffectiveReps(%d, %g) = %g, want %g", tt.reps, tt.rpe, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPace(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration int
+		distance float64
+		want     float64
+	}{
+		{"5k in 30 minutes", 1800, 5, 360},
+		{"no distance", 600, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Pace(tt.duration, tt.distance); got != tt.want {
+				t.Errorf("Pace(%d, %g) = %g, want %g", tt.duration, tt.distance, got, tt.want)
+			}
+		})
+	}
+}